@@ -0,0 +1,173 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+acronym.go Structure:
+├── Types
+│   └── charClass
+└── Functions
+
+	├── acronymOf (private)
+	├── classOf (private)
+	├── startsLower (private)
+	├── appendInitials (private)
+	└── isAcronymQuery (private)
+*/
+package fuzzyvn
+
+import (
+	"math/bits"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+- User hay gõ chữ cái đầu: "bctc" cho "Báo cáo tài chính", "hcm" cho "Hồ Chí Minh", "hdld" cho "Hợp đồng lao động"
+- Fuzzy vẫn tìm được các chữ này nhưng rải rác, điểm thấp và dễ thua đường dẫn dài có các chữ đó nằm lung tung
+- Lúc tạo Searcher, mỗi item lưu sẵn chữ cái đầu của từng từ trong trường chính (tên file)
+- Từ tách theo dấu ngăn cách, CamelCase, chữ ↔ số, và âm tiết trong từ ghép tiếng Việt viết liền (compoundSplit)
+- AcronymRanker cộng Config.AcronymBonus cho item có chữ cái đầu của các từ liền nhau khớp đúng query
+*/
+
+// minAcronymQuery, maxAcronymQuery: Độ dài query được coi là viết tắt. 2 chữ cái khớp quá nhiều file nên không tính
+const (
+	minAcronymQuery = 3
+	maxAcronymQuery = 12
+)
+
+// =============================================================================
+// Types
+// =============================================================================
+
+// charClass: Loại ký tự để tìm chỗ bắt đầu từ mới
+type charClass uint8
+
+const (
+	classSep charClass = iota // Dấu ngăn cách và ký tự không phải chữ/số
+	classLower
+	classUpper
+	classDigit
+)
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+/*
+- acronymOf: Chữ cái đầu (đã chuẩn hóa) của từng từ trong name, mỗi dãy số tính là 1 từ
+- normName = Normalize(name), có sẵn lúc tạo Searcher. name cho biết chữ hoa/thường, normName cho chữ đã chuẩn hóa của từng từ
+- Từ mới bắt đầu sau dấu ngăn cách, ở chữ hoa sau chữ thường ("BaoCao"), ở chữ hoa cuối của dãy chữ hoa nếu sau nó là chữ thường ("HTTPServer" → http|server), ở chỗ chuyển chữ ↔ số
+- Từ ghép tiếng Việt viết liền được tách âm tiết ("baocao" → bao|cao)
+- Ví dụ: "Báo_cáo_tài_chính.xlsx" → "bctcx", "HopDongLaoDong.docx" → "hdldd", "hochiminh2024.pdf" → "hcm2p"
+*/
+func acronymOf(name, normName string) string {
+	// Normalize đã đưa về NFC, name cũng phải vậy để từng ký tự khớp với normName
+	if !isASCII(name) && !norm.NFC.IsNormalString(name) {
+		name = norm.NFC.String(name)
+	}
+	var buf [32]byte // Đa số tên file ít hơn 32 từ, khỏi cấp phát thêm
+	initials := buf[:0]
+	// Từ hiện tại là normName[start:pos]
+	start, pos := 0, 0
+	prev := classSep
+	for i, r := range name {
+		if pos >= len(normName) {
+			break
+		}
+		class := classOf(r)
+		if r >= utf8.RuneSelf {
+			// Ký tự bị Normalize bỏ đi (không phải chữ/số) không có trong normName, vẫn là dấu ngăn cách
+			if _, keep := foldRune(r); !keep {
+				if prev != classSep {
+					initials = appendInitials(initials, normName[start:pos])
+				}
+				prev = classSep
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(normName[pos:])
+		if class == classSep {
+			if prev != classSep {
+				initials = appendInitials(initials, normName[start:pos])
+			}
+			prev = class
+			pos += size
+			continue
+		}
+		newWord := prev == classSep ||
+			(class == classDigit) != (prev == classDigit) ||
+			class == classUpper && prev == classLower ||
+			class == classUpper && prev == classUpper && startsLower(name[i+utf8.RuneLen(r):])
+		if newWord && prev != classSep {
+			initials = appendInitials(initials, normName[start:pos])
+		}
+		if newWord {
+			start = pos
+		}
+		pos += size
+		prev = class
+	}
+	if prev != classSep {
+		initials = appendInitials(initials, normName[start:pos])
+	}
+	return string(initials)
+}
+
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return classLower
+	case r >= 'A' && r <= 'Z':
+		return classUpper
+	case r >= '0' && r <= '9':
+		return classDigit
+	case r < utf8.RuneSelf:
+		return classSep
+	case unicode.IsDigit(r):
+		return classDigit
+	case unicode.IsUpper(r):
+		return classUpper
+	case unicode.IsLetter(r):
+		return classLower
+	}
+	return classSep
+}
+
+// startsLower: s bắt đầu bằng chữ thường không
+func startsLower(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return classOf(r) == classLower
+}
+
+// appendInitials: Nối chữ cái đầu của word (đã chuẩn hóa) vào dst, kèm chữ đầu của các âm tiết sau nếu word là từ ghép tiếng Việt
+func appendInitials(dst []byte, word string) []byte {
+	r, _ := utf8.DecodeRuneInString(word)
+	dst = utf8.AppendRune(dst, r)
+	if classOf(r) == classDigit {
+		return dst
+	}
+	for split := compoundSplit(word); split != 0; split &= split - 1 {
+		dst = append(dst, word[bits.TrailingZeros32(split)])
+	}
+	return dst
+}
+
+/*
+- isAcronymQuery: Query (đã chuẩn hóa) có thể là viết tắt không
+- 1 từ, chỉ gồm chữ cái/số ASCII, dài minAcronymQuery đến maxAcronymQuery ký tự
+*/
+func isAcronymQuery(queryNorm string) bool {
+	if len(queryNorm) < minAcronymQuery || len(queryNorm) > maxAcronymQuery {
+		return false
+	}
+	for i := 0; i < len(queryNorm); i++ {
+		if c := queryNorm[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAcronymOf(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Báo_cáo_tài_chính.xlsx", "bctcx"},
+		{"Báo cáo tài chính 2024.xlsx", "bctc2x"},
+		{"HopDongLaoDong.docx", "hdldd"},
+		{"hochiminh2024.pdf", "hcm2p"},
+		{"baocaotaichinh", "bctc"},
+		{"HTTPServer.go", "hsg"},
+		{"main_test.go", "mtg"},
+		{"config", "c"},                          // Không phải từ ghép tiếng Việt
+		{"Ho\u0302\u0300 Chi\u0301 Minh", "hcm"}, // NFD (macOS)
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := acronymOf(tt.name, Normalize(tt.name)); got != tt.want {
+			t.Errorf("acronymOf(%q) = %q, muốn %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsAcronymQuery(t *testing.T) {
+	for _, q := range []string{"bctc", "hcm", "hdld2024"} {
+		if !isAcronymQuery(q) {
+			t.Errorf("isAcronymQuery(%q) = false", q)
+		}
+	}
+	for _, q := range []string{"", "hn", "bao cao", "main.go", "abcdefghijklm"} {
+		if isAcronymQuery(q) {
+			t.Errorf("isAcronymQuery(%q) = true", q)
+		}
+	}
+}
+
+func TestSearcher_Acronym(t *testing.T) {
+	files := []string{
+		"/docs/ke_toan/Báo_cáo_tài_chính_2024.xlsx",
+		"/src/backend/tests/config.ts",
+		"/maps/Hồ_Chí_Minh.pdf",
+		"/data/hochiminh_city.csv",
+		"/hr/HopDongLaoDong.docx",
+		"/src/handlers/download.go",
+	}
+	files = append(files, generateMonorepoFiles(1000, 1)...)
+	cfg := DefaultConfig()
+	cfg.AcronymBonus = DefaultAcronymBonus
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"bctc", files[0:1]},
+		{"hcm", files[2:4]},
+		{"hdld", files[4:5]},
+	} {
+		results := searcher.SearchResults(tt.query)
+		if len(results) < len(tt.want) {
+			t.Fatalf("SearchResults(%q) = %v", tt.query, results)
+		}
+		for i, r := range results[:len(tt.want)] {
+			if r.Breakdown.Acronym != DefaultAcronymBonus {
+				t.Errorf("SearchResults(%q)[%d] = %q, Acronym = %d, muốn 1 trong %v", tt.query, i, r.Str, r.Breakdown.Acronym, tt.want)
+			}
+			if r.Score != r.Breakdown.Total() {
+				t.Errorf("%s: Score = %d, Total() = %d", r.Str, r.Score, r.Breakdown.Total())
+			}
+		}
+	}
+
+	// Tên file đúng là từ đó vẫn đứng trên: viết tắt chỉ ngang 1 từ khớp (WordMatchBonus)
+	results := NewSearcherWithConfig(append([]string{"/notes/hcm.txt"}, files...), cfg).SearchResults("hcm")
+	if len(results) == 0 || results[0].Str != "/notes/hcm.txt" {
+		t.Errorf("SearchResults(hcm) phải trả về hcm.txt đầu tiên, got %v", results)
+	}
+
+	// DefaultConfig không bật: thứ hạng giữ nguyên như trước
+	for _, r := range NewSearcher(files).SearchResults("bctc") {
+		if r.Breakdown.Acronym != 0 {
+			t.Errorf("AcronymBonus mặc định tắt nhưng %s có Acronym = %d", r.Str, r.Breakdown.Acronym)
+		}
+	}
+}
+
+func TestSearcher_AcronymAfterUpdate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AcronymBonus = DefaultAcronymBonus
+	searcher := NewSearcherWithConfig([]string{"/a/main.go", "/a/readme.md"}, cfg)
+	searcher.Add("/a/HopDongLaoDong.docx")
+	searcher.Rename("/a/main.go", "/a/Báo_cáo_tài_chính.xlsx")
+	checkIndexConsistent(t, searcher.index.Load())
+
+	for _, q := range []string{"hdld", "bctc"} {
+		if results := searcher.SearchResults(q); len(results) == 0 || results[0].Breakdown.Acronym == 0 {
+			t.Errorf("Sau Add/Rename: SearchResults(%s) = %v", q, results)
+		}
+	}
+}
+
+func BenchmarkAcronymOf(b *testing.B) {
+	name := "Báo_cáo_tài_chính_HoChiMinh_2024.xlsx"
+	normName := Normalize(name)
+	for i := 0; i < b.N; i++ {
+		acronymOf(name, normName)
+	}
+}
+
+func BenchmarkSearch_Acronym(b *testing.B) {
+	files := generateMonorepoFiles(100000, 1)
+	for _, bonus := range []int{0, DefaultAcronymBonus} {
+		cfg := DefaultConfig()
+		cfg.AcronymBonus = bonus
+		searcher := NewSearcherWithConfig(files, cfg)
+		b.Run(fmt.Sprintf("bctc/bonus=%d", bonus), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				searcher.Search("bctc")
+			}
+		})
+	}
+}
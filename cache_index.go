@@ -0,0 +1,290 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+cache_index.go Structure:
+├── Types
+│   └── queryIndex
+└── queryIndex Methods
+
+	├── newQueryIndex
+	├── add
+	├── remove
+	├── compact (private)
+	├── forEachGram (private)
+	├── typoBound (private)
+	└── candidates
+*/
+package fuzzyvn
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// shortQueryMax: Độ dài (ký tự) lớn nhất mà typoBound vẫn <= 0, query ngắn hơn không lọc được bằng bigram
+const shortQueryMax = 5
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- queryIndex: Index ngược trên các query đã cache, để GetBoostScores/GetCachedFiles không phải gọi querySimilarity cho TẤT CẢ query mỗi lần user gõ phím
+- maxQueries lên hàng chục nghìn thì quét toàn bộ là chậm thấy rõ
+- candidates(q) trả về mọi query c có thể có querySimilarity(q, c) > 0, KHÔNG BỎ SÓT
+- Prefix với q 1 ký tự: posting của bigram đầu chuỗi "\x00"+q
+- c là chuỗi con của q (bao gồm c là prefix của q): tra thẳng từng chuỗi con của q trong ids
+- Trùng từ (>= 2 byte): index theo từ
+- q là chuỗi con của c, hoặc sai chính tả trong ngưỡng: đếm bigram chung (có đệm đầu/cuối)
+- Bigram, độ dài và ngưỡng đều tính theo ký tự (rune) giống BoundedDamerauLevenshtein mà querySimilarity dùng
+- Vì sao bigram mà không phải trigram: mỗi lỗi sửa phá tối đa n gram, ngưỡng typo ~30% độ dài nên cận dưới của trigram luôn <= 0, không lọc được gì
+- Với bigram cận dưới là maxLen + 1 - 3k (xem typoBound), luôn >= 1 khi maxLen >= 6
+- Riêng maxLen từ 3 tới 5 (cận <= 0): giữ sẵn danh sách query dài 3-5 ký tự
+- Kết quả sau đó vẫn được chấm bằng querySimilarity như cũ, nên điểm giống hệt cách quét toàn bộ
+- Xóa kiểu lazy: chỉ đánh dấu id chết, posting list được dọn 1 lần khi số id chết vượt số id sống (compact)
+- QueryCache giữ 2 index: trên key của entries (index) và trên key của rejections (rejectIndex)
+- Không tự lock, QueryCache.mu bảo vệ (ghi dưới Lock, đọc dưới RLock)
+*/
+type queryIndex struct {
+	ids   map[string]int32   // Query đang sống → id
+	keys  []string           // id → query
+	dead  []bool             // id → đã bị xóa, chờ compact
+	grams map[string][]int32 // Bigram → id, lặp lại đúng số lần bigram xuất hiện trong query
+	words map[string][]int32 // Từ (>= 2 byte) → id
+	short []int32            // id các query dài 3-5 ký tự
+	nDead int
+}
+
+// indexScratch: Bộ đếm bigram chung của 1 lần gọi candidates, dùng lại qua pool (có thể chạy song song dưới RLock)
+type indexScratch struct {
+	counts  []int32 // id → số bigram chung với q, emitted = đã gọi fn
+	touched []int32 // Các id có counts khác 0, để reset nhanh thay vì xóa cả mảng
+}
+
+var scratchPool = sync.Pool{
+	New: func() any { return new(indexScratch) },
+}
+
+// =============================================================================
+// queryIndex Methods
+// =============================================================================
+
+func newQueryIndex() *queryIndex {
+	return &queryIndex{
+		ids:   make(map[string]int32),
+		grams: make(map[string][]int32),
+		words: make(map[string][]int32),
+	}
+}
+
+// add: Thêm query vào index, query rỗng không bao giờ giống query nào nên bỏ qua
+func (ix *queryIndex) add(key string) {
+	if key == "" {
+		return
+	}
+	if _, exists := ix.ids[key]; exists {
+		return
+	}
+	id := int32(len(ix.keys))
+	ix.ids[key] = id
+	ix.keys = append(ix.keys, key)
+	ix.dead = append(ix.dead, false)
+
+	forEachGram(key, func(g string) {
+		ix.grams[g] = append(ix.grams[g], id)
+	})
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(key) {
+		if len(w) >= 2 && !seen[w] {
+			seen[w] = true
+			ix.words[w] = append(ix.words[w], id)
+		}
+	}
+	if n := utf8.RuneCountInString(key); n >= 3 && n <= shortQueryMax {
+		ix.short = append(ix.short, id)
+	}
+}
+
+// remove: Đánh dấu query đã bị xóa, posting list giữ nguyên tới lần compact
+func (ix *queryIndex) remove(key string) {
+	id, exists := ix.ids[key]
+	if !exists {
+		return
+	}
+	delete(ix.ids, key)
+	ix.dead[id] = true
+	ix.nDead++
+	if ix.nDead > 1024 && ix.nDead > len(ix.ids) {
+		ix.compact()
+	}
+}
+
+// compact: Dựng lại index chỉ với các query còn sống
+func (ix *queryIndex) compact() {
+	next := newQueryIndex()
+	for id, key := range ix.keys {
+		if !ix.dead[id] {
+			next.add(key)
+		}
+	}
+	*ix = *next
+}
+
+// forEachGram: Gọi fn cho từng bigram của "\x00" + s + "\x00" (theo ký tự, giống BoundedDamerauLevenshtein)
+func forEachGram(s string, fn func(g string)) {
+	padded := "\x00" + s + "\x00"
+	// prev, last: vị trí byte bắt đầu của 2 ký tự ngay trước i
+	prev, last := -1, -1
+	for i := range padded {
+		if prev >= 0 {
+			fn(padded[prev:i])
+		}
+		prev, last = last, i
+	}
+	fn(padded[prev:])
+}
+
+/*
+- typoBound: Số bigram chung tối thiểu nếu 2 chuỗi dài nhất maxLen ký tự lệch nhau <= queryTypoThreshold lỗi
+- q-gram lemma: mỗi lỗi thêm/xóa/sửa phá tối đa 2 bigram trong maxLen + 1 bigram của chuỗi có đệm
+- Đổi chỗ 2 ký tự liền nhau (xy → yx) chỉ tính 1 lỗi nhưng phá tới 3 bigram (ax, xy, yb), nên phải lấy 3 bigram mỗi lỗi
+*/
+func typoBound(maxLen int) int {
+	return maxLen + 1 - 3*queryTypoThreshold(maxLen)
+}
+
+/*
+- candidates: Gọi fn cho mỗi query có thể giống q (querySimilarity > 0), mỗi query đúng 1 lần, thứ tự không cố định
+- q đã chuẩn hóa
+- Dùng callback thay vì trả về slice để không cấp phát khi số ứng viên lớn
+- Trả về false (chưa gọi fn lần nào) nếu quá nửa số query đều là ứng viên: index không lọc được gì, quét thẳng entries còn nhanh hơn
+- Cũng trả về false nếu q là 1 ký tự nhiều byte ("中"): key chỉ chứa q ở giữa ("a中b") không có bigram chung nào với q
+- Hay gặp khi các query na ná nhau (query1, query2, ...) vì ngưỡng typo 30% khá rộng với chuỗi ngắn
+*/
+func (ix *queryIndex) candidates(q string, fn func(key string)) bool {
+	if q == "" || len(ix.ids) == 0 {
+		return true
+	}
+
+	// q 1 ký tự: chỉ có thể khớp prefix (hoặc bằng nhau)
+	if len(q) == 1 {
+		list := ix.grams["\x00"+q]
+		if len(list) > len(ix.ids)/2 {
+			return false
+		}
+		for _, id := range list {
+			if !ix.dead[id] {
+				fn(ix.keys[id])
+			}
+		}
+		return true
+	}
+	// q 1 ký tự nhiều byte: querySimilarity tính cả chuỗi con (>= 2 byte), bigram có đệm không tìm ra được
+	lq := utf8.RuneCountInString(q)
+	if lq == 1 {
+		return false
+	}
+
+	sc := scratchPool.Get().(*indexScratch)
+	if cap(sc.counts) < len(ix.keys) {
+		sc.counts = make([]int32, len(ix.keys))
+	}
+	counts := sc.counts[:len(ix.keys)]
+	touched := sc.touched[:0]
+	defer func() {
+		for _, id := range touched {
+			counts[id] = 0
+		}
+		sc.touched = touched
+		scratchPool.Put(sc)
+	}()
+
+	// Đếm bigram chung có tính số lần lặp: cộng min(số lần trong q, số lần trong c)
+	qGrams := make(map[string]int32, len(q)+1)
+	forEachGram(q, func(g string) { qGrams[g]++ })
+	for g, inQuery := range qGrams {
+		list := ix.grams[g]
+		for i := 0; i < len(list); {
+			id := list[i]
+			j := i + 1
+			for j < len(list) && list[j] == id {
+				j++
+			}
+			run := int32(j - i)
+			i = j
+			if ix.dead[id] {
+				continue
+			}
+			if counts[id] == 0 {
+				touched = append(touched, id)
+			}
+			counts[id] += min(run, inQuery)
+		}
+	}
+
+	// -1 = là ứng viên
+	const emitted = -1
+	found := 0
+	for _, id := range touched {
+		n := int(counts[id])
+		key := ix.keys[id]
+		lc := utf8.RuneCountInString(key)
+		maxLen := max(lq, lc)
+		// c chứa q thì có đủ lq-1 bigram bên trong của q, đủ số lượng mới tốn công so chuỗi
+		isCandidate := n >= lq-1 && strings.Contains(key, q)
+		if !isCandidate && lq >= 3 && lc >= 3 && abs(lq-lc) <= queryTypoThreshold(maxLen) {
+			isCandidate = n >= typoBound(maxLen)
+		}
+		if isCandidate {
+			counts[id] = emitted
+			found++
+		}
+	}
+	if found > len(ix.ids)/2 {
+		return false
+	}
+	for _, id := range touched {
+		if counts[id] == emitted {
+			fn(ix.keys[id])
+		}
+	}
+
+	// Các query không qua được bước đếm nhưng vẫn có thể giống q
+	direct := func(id int32) {
+		if ix.dead[id] || counts[id] == emitted {
+			return
+		}
+		if counts[id] == 0 {
+			touched = append(touched, id)
+		}
+		counts[id] = emitted
+		fn(ix.keys[id])
+	}
+	// c là chuỗi con của q (>= 2 byte)
+	for i := 0; i < len(q); i++ {
+		for j := i + 2; j <= len(q); j++ {
+			if id, ok := ix.ids[q[i:j]]; ok {
+				direct(id)
+			}
+		}
+	}
+	// Trùng ít nhất 1 từ
+	for _, w := range strings.Fields(q) {
+		if len(w) >= 2 {
+			for _, id := range ix.words[w] {
+				direct(id)
+			}
+		}
+	}
+	// maxLen từ 3 tới 5: cận dưới <= 0, mọi query 3-5 ký tự đều có thể sai trong ngưỡng
+	if lq >= 3 && lq <= shortQueryMax {
+		for _, id := range ix.short {
+			direct(id)
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package fuzzyvn
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// collectCandidates: candidates dưới dạng slice, nil nếu index không lọc (phải quét toàn bộ)
+func collectCandidates(ix *queryIndex, q string) ([]string, bool) {
+	var out []string
+	selective := ix.candidates(q, func(key string) { out = append(out, key) })
+	return out, selective
+}
+
+func randomQuery(rng *rand.Rand) string {
+	alphabet := []rune("abcdeghinođ ơư12中")
+	runes := make([]rune, 1+rng.Intn(9))
+	for i := range runes {
+		runes[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return string(runes)
+}
+
+func TestQueryIndex_MatchesFullScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	cache := NewQueryCache()
+	cache.SetMaxQueries(300)
+	cache.SetMaxFeedback(150)
+
+	// Mọi query có querySimilarity > 0 (quét toàn bộ như trước) phải nằm trong candidates, không trùng, không query đã xóa
+	filtered := 0
+	check := func(round int) {
+		t.Helper()
+		for range 200 {
+			query := Normalize(randomQuery(rng))
+			got, selective := collectCandidates(cache.index, query)
+			if !selective {
+				continue
+			}
+			filtered++
+			seen := make(map[string]bool)
+			for _, q := range got {
+				if _, live := cache.entries[q]; !live || seen[q] {
+					t.Fatalf("round %d, query %q: candidate %q đã xóa hoặc bị trùng", round, query, q)
+				}
+				seen[q] = true
+			}
+			for cachedQuery := range cache.entries {
+				if cache.querySimilarity(query, cachedQuery) > 0 && !seen[cachedQuery] {
+					t.Fatalf("round %d, query %q: index bỏ sót %q", round, query, cachedQuery)
+				}
+			}
+
+			// rejectIndex cũng phải khớp với rejections
+			got, selective = collectCandidates(cache.rejectIndex, query)
+			if !selective {
+				continue
+			}
+			clear(seen)
+			for _, q := range got {
+				if _, live := cache.rejections[q]; !live || seen[q] {
+					t.Fatalf("round %d, query %q: rejection %q đã xóa hoặc bị trùng", round, query, q)
+				}
+				seen[q] = true
+			}
+			for rejectedQuery := range cache.rejections {
+				if cache.querySimilarity(query, rejectedQuery) > 0 && !seen[rejectedQuery] {
+					t.Fatalf("round %d, query %q: rejectIndex bỏ sót %q", round, query, rejectedQuery)
+				}
+			}
+		}
+	}
+
+	// Vượt maxQueries nhiều lần để có evict, từ chối để có removeEntry, đủ nhiều để index compact
+	for round := range 8 {
+		for i := range 400 {
+			query := randomQuery(rng)
+			path := fmt.Sprintf("/f%d_%d", round, i)
+			cache.RecordSelection(query, path)
+			switch rng.Intn(8) {
+			case 0, 1:
+				cache.RecordRejection(query, path)
+			case 2:
+				cache.Pin(query, path) // Bỏ từ chối nếu có
+			case 3:
+				cache.RecordSelection(query, fmt.Sprintf("/f%d_%d", round, rng.Intn(i+1))) // Có thể bỏ từ chối
+			}
+		}
+		check(round)
+	}
+	if filtered < 1000 {
+		t.Errorf("Index chỉ lọc được %d/1600 query, test không còn ý nghĩa", filtered)
+	}
+	if len(cache.index.ids) != len(cache.entries) {
+		t.Errorf("index có %d query, entries có %d", len(cache.index.ids), len(cache.entries))
+	}
+	if len(cache.rejectIndex.ids) != len(cache.rejections) {
+		t.Errorf("rejectIndex có %d query, rejections có %d", len(cache.rejectIndex.ids), len(cache.rejections))
+	}
+}
+
+func TestQueryIndex_Candidates(t *testing.T) {
+	ix := newQueryIndex()
+	for _, q := range []string{"samsung s23", "ip 15", "mtp son tung", "iphone", "abc", "xyz"} {
+		ix.add(q)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"s", []string{"samsung s23"}},           // Prefix 1 ký tự
+		{"mua ip 15 gia re", []string{"ip 15"}},  // Cache là chuỗi con của query
+		{"son tung", []string{"mtp son tung"}},   // Query là chuỗi con / trùng từ
+		{"ipbone", []string{"iphone"}},           // Sai chính tả
+		{"abd", []string{"abc", "ip 15", "xyz"}}, // Query 3-5 ký tự: không lọc được bằng bigram
+		{"hoàn toàn khác", nil},
+	}
+	for _, tt := range tests {
+		got, _ := collectCandidates(ix, Normalize(tt.query))
+		for _, w := range tt.want {
+			if !slices.Contains(got, w) {
+				t.Errorf("candidates(%q) = %v, thiếu %q", tt.query, got, w)
+			}
+		}
+		if len(got) > len(tt.want)+1 {
+			t.Errorf("candidates(%q) = %v, lọc quá ít", tt.query, got)
+		}
+	}
+
+	ix.remove("iphone")
+	if got, _ := collectCandidates(ix, "iphone"); slices.Contains(got, "iphone") {
+		t.Errorf("Query đã xóa vẫn còn trong candidates: %v", got)
+	}
+}
+
+func TestQueryCache_Index_ClearAndLoad(t *testing.T) {
+	cache := newPersistTestCache()
+	want := cache.GetBoostScores("mai")
+
+	var buf bytes.Buffer
+	cache.SaveTo(&buf, FormatBinary)
+	loaded, err := LoadQueryCache(&buf)
+	if err != nil {
+		t.Fatalf("LoadQueryCache lỗi: %v", err)
+	}
+	if got := loaded.GetBoostScores("mai"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cache đọc từ file: boost = %v, muốn %v", got, want)
+	}
+
+	if len(loaded.rejectIndex.ids) != len(loaded.rejections) {
+		t.Errorf("Cache đọc từ file: rejectIndex có %d query, rejections có %d", len(loaded.rejectIndex.ids), len(loaded.rejections))
+	}
+
+	cache.Clear()
+	if len(cache.rejectIndex.ids) != 0 {
+		t.Errorf("Sau Clear rejectIndex phải rỗng, got %v", cache.rejectIndex.ids)
+	}
+	if got := cache.GetBoostScores("main"); len(got) != 0 {
+		t.Errorf("Sau Clear boost phải rỗng, got %v", got)
+	}
+}
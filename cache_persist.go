@@ -0,0 +1,655 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+cache_persist.go Structure:
+├── Types
+│   ├── CacheFormat
+│   ├── Errors
+│   ├── cacheFile (private)
+│   └── AutosaveOptions
+├── Snapshot
+│   ├── snapshot (private)
+│   ├── cloneLists (private)
+│   └── fromFile (private)
+├── Encode / Decode
+│   ├── Save
+│   ├── SaveBinary
+│   ├── SaveTo
+│   ├── LoadQueryCache
+│   ├── encodeBinary (private)
+│   └── decodeBinary (private)
+└── File + Autosave
+
+	├── SaveFile
+	├── LoadQueryCacheFile
+	├── writeFileAtomic (private)
+	├── StartAutosave
+	└── saveIfDirty (private)
+*/
+package fuzzyvn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- CacheFormat: Định dạng file lưu QueryCache
+- FormatJSON: Dễ đọc, dễ sửa tay, dễ debug
+- FormatBinary: Gọn hơn và đọc/ghi nhanh hơn, hợp với cache lớn
+- LoadQueryCache tự nhận ra định dạng, không cần truyền vào
+*/
+type CacheFormat int
+
+const (
+	FormatJSON CacheFormat = iota
+	FormatBinary
+)
+
+/*
+- cacheFormatVersion: Phiên bản định dạng hiện tại, ghi vào đầu mọi file
+- Đổi cấu trúc dữ liệu thì tăng số này và giữ code đọc được các phiên bản cũ
+  - 1: entries, queryOrder, maxQueries, maxPerQuery, boostScore
+  - 2: thêm halfLife và thời gian các lần chọn gần nhất của mỗi entry (frecency)
+  - 3: thêm rejectPenalty, rejections (RecordRejection), pins (Pin), maxFeedback và feedbackOrder (giới hạn số query có từ chối/ghim)
+*/
+const cacheFormatVersion = 3
+
+// cacheMagic: 4 byte đầu của định dạng binary
+const cacheMagic = "FZVQ"
+
+// cacheMaxString: Giới hạn độ dài 1 chuỗi trong file binary, tránh file hỏng làm cấp phát bộ nhớ khổng lồ
+const cacheMaxString = 1 << 20
+
+var (
+	ErrCacheFormat  = errors.New("fuzzyvn: unknown query cache format")
+	ErrCacheVersion = errors.New("fuzzyvn: unsupported query cache version")
+	ErrCacheCorrupt = errors.New("fuzzyvn: corrupt query cache data")
+)
+
+/*
+- cacheFile: Dạng trung gian của QueryCache khi lưu/đọc, dùng chung cho cả JSON và binary
+- Tên field JSON cố định, không phụ thuộc tên field trong struct QueryCache
+- QueryOrder: Query cũ nhất đứng đầu, giống queryOrder trong QueryCache
+*/
+type cacheFile struct {
+	Version     int                         `json:"version"`
+	MaxQueries  int                         `json:"maxQueries"`
+	MaxPerQuery int                         `json:"maxPerQuery"`
+	BoostScore  int                         `json:"boostScore"`
+	HalfLife    string                      `json:"halfLife,omitempty"`
+	QueryOrder  []string                    `json:"queryOrder"`
+	Entries     map[string][]cacheFileEntry `json:"entries"`
+
+	RejectPenalty int                 `json:"rejectPenalty"`
+	Rejections    map[string][]string `json:"rejections,omitempty"`
+	Pins          map[string][]string `json:"pins,omitempty"`
+	MaxFeedback   int                 `json:"maxFeedback"`
+	FeedbackOrder []string            `json:"feedbackOrder,omitempty"` // Query có từ chối hoặc ghim, cũ nhất đứng đầu
+}
+
+type cacheFileEntry struct {
+	Path  string      `json:"path"`
+	Count int         `json:"count"`
+	Times []time.Time `json:"times,omitempty"` // Các lần chọn gần nhất, cũ nhất trước (từ version 2)
+}
+
+/*
+- AutosaveOptions: Cấu hình cho StartAutosave
+- Path: File đích, ghi kiểu atomic (file tạm + rename) nên không bao giờ bị ghi dở
+- Format: FormatJSON hoặc FormatBinary
+- Interval: Chu kỳ kiểm tra thay đổi, mặc định 5 giây. Không có thay đổi thì không ghi
+- OnError: Gọi khi ghi file lỗi (có thể nil), lần sau vẫn thử ghi lại
+*/
+type AutosaveOptions struct {
+	Path     string
+	Format   CacheFormat
+	Interval time.Duration
+	OnError  func(error)
+}
+
+// =============================================================================
+// Snapshot
+// =============================================================================
+
+// snapshot: Copy toàn bộ dữ liệu cache ra cacheFile dưới RLock
+func (c *QueryCache) snapshot() *cacheFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	f := &cacheFile{
+		Version:     cacheFormatVersion,
+		MaxQueries:  c.maxQueries,
+		MaxPerQuery: c.maxPerQuery,
+		BoostScore:  c.boostScore,
+		HalfLife:    c.halfLife.String(),
+		QueryOrder:  append([]string(nil), c.queryOrder...),
+		Entries:     make(map[string][]cacheFileEntry, len(c.entries)),
+
+		RejectPenalty: c.rejectPenalty,
+		Rejections:    cloneLists(c.rejections),
+		Pins:          cloneLists(c.pins),
+		MaxFeedback:   c.maxFeedback,
+		FeedbackOrder: append([]string(nil), c.feedbackOrder...),
+	}
+	for query, entries := range c.entries {
+		out := make([]cacheFileEntry, len(entries))
+		for i, e := range entries {
+			out[i] = cacheFileEntry{Path: e.FilePath, Count: e.SelectCount, Times: append([]time.Time(nil), e.selectedAt...)}
+		}
+		f.Entries[query] = out
+	}
+	return f
+}
+
+// cloneLists: Copy sâu map query → danh sách file
+func cloneLists(m map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for k, v := range m {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
+
+/*
+- fromFile: Dựng QueryCache từ cacheFile, kiểm tra dữ liệu trước khi dùng
+- queryOrder và entries phải khớp nhau 1-1, không trùng query, SelectCount > 0
+- File có nhiều query hơn maxQueries thì bỏ bớt query cũ nhất như bình thường
+- File version 1 không có thời gian: entry giữ LastSelected = zero, frecency = SelectCount (không phân rã)
+- File version 1, 2 không có từ chối/ghim: dùng rejectPenalty và maxFeedback mặc định
+- Từ version 3, feedbackOrder phải khớp 1-1 với các query có từ chối hoặc ghim
+*/
+func fromFile(f *cacheFile) (*QueryCache, error) {
+	if f.Version < 1 || f.Version > cacheFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrCacheVersion, f.Version)
+	}
+	if f.MaxQueries < 0 || f.MaxPerQuery < 0 || f.MaxFeedback < 0 {
+		return nil, fmt.Errorf("%w: negative limits", ErrCacheCorrupt)
+	}
+	if len(f.QueryOrder) != len(f.Entries) {
+		return nil, fmt.Errorf("%w: queryOrder and entries differ", ErrCacheCorrupt)
+	}
+
+	c := NewQueryCache()
+	c.maxQueries = f.MaxQueries
+	c.maxPerQuery = f.MaxPerQuery
+	c.boostScore = f.BoostScore
+	if f.HalfLife != "" {
+		d, err := time.ParseDuration(f.HalfLife)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%w: invalid halfLife %q", ErrCacheCorrupt, f.HalfLife)
+		}
+		c.halfLife = d
+	}
+	if f.Version >= 3 {
+		c.rejectPenalty = f.RejectPenalty
+	}
+	for _, lists := range []struct {
+		src map[string][]string
+		dst map[string][]string
+	}{{f.Rejections, c.rejections}, {f.Pins, c.pins}} {
+		for query, paths := range lists.src {
+			for i, path := range paths {
+				if path == "" || slices.Contains(paths[:i], path) {
+					return nil, fmt.Errorf("%w: invalid rejection/pin for query %q", ErrCacheCorrupt, query)
+				}
+			}
+			setList(lists.dst, query, append([]string(nil), paths...))
+		}
+	}
+	for query := range c.rejections {
+		c.rejectIndex.add(query)
+	}
+	feedback := slices.Sorted(maps.Keys(c.rejections))
+	for query := range c.pins {
+		if _, ok := c.rejections[query]; !ok {
+			feedback = append(feedback, query)
+		}
+	}
+	if f.Version >= 3 {
+		c.maxFeedback = f.MaxFeedback
+		if len(f.FeedbackOrder) != len(feedback) {
+			return nil, fmt.Errorf("%w: feedbackOrder and rejections/pins differ", ErrCacheCorrupt)
+		}
+		seen := make(map[string]bool, len(f.FeedbackOrder))
+		for _, query := range f.FeedbackOrder {
+			if seen[query] || (len(c.rejections[query]) == 0 && len(c.pins[query]) == 0) {
+				return nil, fmt.Errorf("%w: invalid feedbackOrder query %q", ErrCacheCorrupt, query)
+			}
+			seen[query] = true
+		}
+		feedback = append([]string(nil), f.FeedbackOrder...)
+	} else {
+		slices.Sort(feedback)
+	}
+	c.feedbackOrder = append(c.feedbackOrder, feedback...)
+	c.evictFeedback()
+	c.queryOrder = make([]string, 0, len(f.QueryOrder))
+
+	for _, query := range f.QueryOrder {
+		entries, ok := f.Entries[query]
+		if !ok {
+			return nil, fmt.Errorf("%w: query %q has no entries", ErrCacheCorrupt, query)
+		}
+		if _, dup := c.entries[query]; dup {
+			return nil, fmt.Errorf("%w: duplicate query %q", ErrCacheCorrupt, query)
+		}
+		out := make([]CacheEntry, len(entries))
+		for i, e := range entries {
+			if e.Path == "" || e.Count <= 0 {
+				return nil, fmt.Errorf("%w: invalid entry for query %q", ErrCacheCorrupt, query)
+			}
+			out[i] = CacheEntry{FilePath: e.Path, SelectCount: e.Count}
+			if len(e.Times) > 0 {
+				times := make([]time.Time, len(e.Times))
+				for k, t := range e.Times {
+					times[k] = t.UTC()
+				}
+				sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+				times = times[max(0, len(times)-frecencySamples):]
+				out[i].selectedAt = times
+				out[i].LastSelected = times[len(times)-1]
+			}
+		}
+		c.entries[query] = out
+		c.index.add(query)
+		c.queryOrder = append(c.queryOrder, query)
+	}
+	c.evictIfNeeded()
+	return c, nil
+}
+
+// =============================================================================
+// Encode / Decode
+// =============================================================================
+
+/*
+- Save: Ghi toàn bộ cache ra w dưới dạng JSON
+- Lưu cả entries, thứ tự query (LRU), maxQueries, maxPerQuery và boostScore
+- Đọc lại bằng LoadQueryCache
+*/
+func (c *QueryCache) Save(w io.Writer) error {
+	return c.SaveTo(w, FormatJSON)
+}
+
+/*
+- SaveBinary: Như Save nhưng dùng định dạng binary gọn hơn
+*/
+func (c *QueryCache) SaveBinary(w io.Writer) error {
+	return c.SaveTo(w, FormatBinary)
+}
+
+/*
+- SaveTo: Ghi cache ra w theo định dạng chỉ định
+- Chỉ giữ RLock trong lúc copy dữ liệu, việc encode và ghi không chặn Search/RecordSelection
+*/
+func (c *QueryCache) SaveTo(w io.Writer, format CacheFormat) error {
+	f := c.snapshot()
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(f)
+	case FormatBinary:
+		_, err := w.Write(encodeBinary(f))
+		return err
+	default:
+		return fmt.Errorf("%w: %d", ErrCacheFormat, format)
+	}
+}
+
+/*
+- LoadQueryCache: Đọc cache đã lưu bằng Save/SaveBinary
+- Tự nhận ra định dạng qua vài byte đầu: "FZVQ" là binary, '{' là JSON
+- Trả về ErrCacheFormat, ErrCacheVersion hoặc ErrCacheCorrupt (dùng errors.Is) nếu dữ liệu không hợp lệ
+*/
+func LoadQueryCache(r io.Reader) (*QueryCache, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(cacheMagic))
+	if string(head) == cacheMagic {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		f, err := decodeBinary(data[len(cacheMagic):])
+		if err != nil {
+			return nil, err
+		}
+		return fromFile(f)
+	}
+
+	// Bỏ qua khoảng trắng đầu file JSON (file sửa tay thường có)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return nil, ErrCacheFormat
+			}
+			return nil, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\n' && b[0] != '\r' {
+			if b[0] != '{' {
+				return nil, ErrCacheFormat
+			}
+			break
+		}
+		br.ReadByte()
+	}
+
+	var f cacheFile
+	if err := json.NewDecoder(br).Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheCorrupt, err)
+	}
+	return fromFile(&f)
+}
+
+/*
+- encodeBinary: Định dạng binary
+  - "FZVQ" | version | maxQueries | maxPerQuery | boostScore | halfLife (ns) | số query
+  - Mỗi query (cũ nhất trước): query | số entry | (path | count | số lần | unix ns...)...
+  - rejectPenalty | rejections | pins, mỗi map: số query | (query | số file | path...)... theo query tăng dần
+  - maxFeedback | số query | feedbackOrder (cũ nhất trước)
+  - Số nguyên là varint, chuỗi là độ dài (uvarint) + byte UTF-8
+*/
+func encodeBinary(f *cacheFile) []byte {
+	buf := make([]byte, 0, 64+len(f.QueryOrder)*32)
+	putString := func(s string) {
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+
+	buf = append(buf, cacheMagic...)
+	buf = binary.AppendUvarint(buf, uint64(f.Version))
+	buf = binary.AppendVarint(buf, int64(f.MaxQueries))
+	buf = binary.AppendVarint(buf, int64(f.MaxPerQuery))
+	buf = binary.AppendVarint(buf, int64(f.BoostScore))
+	halfLife, _ := time.ParseDuration(f.HalfLife)
+	buf = binary.AppendVarint(buf, int64(halfLife))
+	buf = binary.AppendUvarint(buf, uint64(len(f.QueryOrder)))
+	for _, query := range f.QueryOrder {
+		entries := f.Entries[query]
+		putString(query)
+		buf = binary.AppendUvarint(buf, uint64(len(entries)))
+		for _, e := range entries {
+			putString(e.Path)
+			buf = binary.AppendVarint(buf, int64(e.Count))
+			buf = binary.AppendUvarint(buf, uint64(len(e.Times)))
+			for _, t := range e.Times {
+				buf = binary.AppendVarint(buf, t.UnixNano())
+			}
+		}
+	}
+
+	buf = binary.AppendVarint(buf, int64(f.RejectPenalty))
+	for _, m := range []map[string][]string{f.Rejections, f.Pins} {
+		buf = binary.AppendUvarint(buf, uint64(len(m)))
+		for _, query := range slices.Sorted(maps.Keys(m)) {
+			putString(query)
+			buf = binary.AppendUvarint(buf, uint64(len(m[query])))
+			for _, path := range m[query] {
+				putString(path)
+			}
+		}
+	}
+	buf = binary.AppendVarint(buf, int64(f.MaxFeedback))
+	buf = binary.AppendUvarint(buf, uint64(len(f.FeedbackOrder)))
+	for _, query := range f.FeedbackOrder {
+		putString(query)
+	}
+	return buf
+}
+
+// decodeBinary: Ngược lại với encodeBinary, data không gồm 4 byte magic
+func decodeBinary(data []byte) (*cacheFile, error) {
+	r := bytes.NewReader(data)
+	var err error
+	uvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(r)
+		return v
+	}
+	varint := func() int64 {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(r)
+		return v
+	}
+	str := func() string {
+		n := uvarint()
+		if err != nil {
+			return ""
+		}
+		if n > cacheMaxString || n > uint64(r.Len()) {
+			err = io.ErrUnexpectedEOF
+			return ""
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return string(b)
+	}
+
+	version := uvarint()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheCorrupt, err)
+	}
+	// Kiểm tra version trước khi đọc phần còn lại, vì định dạng sau này có thể khác hẳn
+	if version < 1 || version > cacheFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrCacheVersion, version)
+	}
+
+	f := &cacheFile{Version: int(version)}
+	f.MaxQueries = int(varint())
+	f.MaxPerQuery = int(varint())
+	f.BoostScore = int(varint())
+	if version >= 2 {
+		f.HalfLife = time.Duration(varint()).String()
+	}
+	n := uvarint()
+	// Mỗi query tốn ít nhất 1 byte, số query lớn hơn số byte còn lại chắc chắn là file hỏng
+	if err == nil && n > uint64(r.Len()) {
+		err = io.ErrUnexpectedEOF
+	}
+	f.QueryOrder = make([]string, 0, n)
+	f.Entries = make(map[string][]cacheFileEntry, n)
+	for i := uint64(0); i < n && err == nil; i++ {
+		query := str()
+		m := uvarint()
+		if err == nil && m > uint64(r.Len()) {
+			err = io.ErrUnexpectedEOF
+		}
+		entries := make([]cacheFileEntry, 0, m)
+		for j := uint64(0); j < m && err == nil; j++ {
+			e := cacheFileEntry{Path: str(), Count: int(varint())}
+			if version >= 2 {
+				k := uvarint()
+				if err == nil && k > uint64(r.Len()) {
+					err = io.ErrUnexpectedEOF
+				}
+				for ; k > 0 && err == nil; k-- {
+					e.Times = append(e.Times, time.Unix(0, varint()).UTC())
+				}
+			}
+			entries = append(entries, e)
+		}
+		f.QueryOrder = append(f.QueryOrder, query)
+		f.Entries[query] = entries
+	}
+
+	lists := func() map[string][]string {
+		m := make(map[string][]string)
+		n := uvarint()
+		for i := uint64(0); i < n && err == nil; i++ {
+			query := str()
+			k := uvarint()
+			if err == nil && k > uint64(r.Len()) {
+				err = io.ErrUnexpectedEOF
+			}
+			paths := make([]string, 0, k)
+			for ; k > 0 && err == nil; k-- {
+				paths = append(paths, str())
+			}
+			m[query] = paths
+		}
+		return m
+	}
+	if version >= 3 {
+		f.RejectPenalty = int(varint())
+		f.Rejections = lists()
+		f.Pins = lists()
+		f.MaxFeedback = int(varint())
+		k := uvarint()
+		if err == nil && k > uint64(r.Len()) {
+			err = io.ErrUnexpectedEOF
+		}
+		for ; k > 0 && err == nil; k-- {
+			f.FeedbackOrder = append(f.FeedbackOrder, str())
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCacheCorrupt, err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrCacheCorrupt)
+	}
+	return f, nil
+}
+
+// =============================================================================
+// File + Autosave
+// =============================================================================
+
+/*
+- SaveFile: Ghi cache xuống file theo kiểu atomic
+- Ghi ra file tạm cùng thư mục, fsync rồi rename đè lên path
+- Process chết giữa chừng thì file cũ vẫn nguyên vẹn, không bao giờ có file ghi dở
+- File tạo mới có quyền 0600 vì lịch sử tìm kiếm là dữ liệu riêng của user
+*/
+func (c *QueryCache) SaveFile(path string, format CacheFormat) error {
+	var buf bytes.Buffer
+	if err := c.SaveTo(&buf, format); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+/*
+- LoadQueryCacheFile: Đọc cache từ file đã lưu bằng SaveFile hoặc autosave
+- File chưa tồn tại thì trả lỗi bọc os.ErrNotExist, caller có thể kiểm tra bằng errors.Is và dùng NewQueryCache
+*/
+func LoadQueryCacheFile(path string) (*QueryCache, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadQueryCache(file)
+}
+
+// writeFileAtomic: file tạm + fsync + rename, dọn file tạm nếu có lỗi
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+/*
+- StartAutosave: Tự động ghi cache xuống opts.Path mỗi khi có thay đổi
+- Chạy 1 goroutine nền, mỗi opts.Interval kiểm tra cờ dirty, chỉ ghi khi cache thực sự đổi
+- Các thay đổi dồn dập trong 1 chu kỳ chỉ tốn 1 lần ghi
+- Trả về hàm stop: dừng goroutine và ghi lần cuối nếu còn thay đổi, gọi nhiều lần vẫn an toàn
+- Mỗi cache chỉ nên có 1 autosave đang chạy
+- Ví dụ:
+
+	cache, err := fuzzyvn.LoadQueryCacheFile("cache.json")
+	if err != nil {
+		cache = fuzzyvn.NewQueryCache()
+	}
+	stop := cache.StartAutosave(fuzzyvn.AutosaveOptions{Path: "cache.json"})
+	defer stop()
+*/
+func (c *QueryCache) StartAutosave(opts AutosaveOptions) (stop func() error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.saveIfDirty(opts); err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			close(done)
+			<-finished
+			err = c.saveIfDirty(opts)
+		})
+		return err
+	}
+}
+
+// saveIfDirty: Ghi file nếu có thay đổi, ghi lỗi thì bật lại cờ dirty để lần sau thử lại
+func (c *QueryCache) saveIfDirty(opts AutosaveOptions) error {
+	if !c.dirty.Swap(false) {
+		return nil
+	}
+	if err := c.SaveFile(opts.Path, opts.Format); err != nil {
+		c.dirty.Store(true)
+		return err
+	}
+	return nil
+}
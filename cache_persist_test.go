@@ -0,0 +1,270 @@
+package fuzzyvn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPersistTestCache() *QueryCache {
+	cache := NewQueryCache()
+	cache.SetMaxQueries(50)
+	cache.SetBoostScore(7000)
+	cache.SetHalfLife(72 * time.Hour)
+	cache.RecordSelection("main", "/project/main.go")
+	cache.RecordSelection("main", "/project/main.go")
+	cache.RecordSelection("main", "/project/main_test.go")
+	cache.RecordSelection("Báo cáo", "/docs/Báo_cáo_tháng.pdf")
+	cache.RecordSelection("config", "/project/config.yaml")
+	cache.RecordSelection("main", "/project/main.go")
+	cache.SetRejectPenalty(12000)
+	cache.RecordRejection("main", "/project/main_old.go")
+	cache.RecordRejection("cfg", "/project/config.bak")
+	cache.Pin("main", "/project/README.md")
+	cache.Pin("main", "/project/main.go")
+	cache.SetMaxFeedback(10)
+	return cache
+}
+
+func assertCacheEqual(t *testing.T, got, want *QueryCache) {
+	t.Helper()
+	if !reflect.DeepEqual(got.entries, want.entries) {
+		t.Errorf("entries = %v, muốn %v", got.entries, want.entries)
+	}
+	if !reflect.DeepEqual(got.queryOrder, want.queryOrder) {
+		t.Errorf("queryOrder = %v, muốn %v", got.queryOrder, want.queryOrder)
+	}
+	if got.maxQueries != want.maxQueries || got.maxPerQuery != want.maxPerQuery || got.boostScore != want.boostScore {
+		t.Errorf("limits = (%d, %d, %d), muốn (%d, %d, %d)",
+			got.maxQueries, got.maxPerQuery, got.boostScore,
+			want.maxQueries, want.maxPerQuery, want.boostScore)
+	}
+	if got.halfLife != want.halfLife || got.rejectPenalty != want.rejectPenalty {
+		t.Errorf("halfLife, rejectPenalty = %v, %d, muốn %v, %d", got.halfLife, got.rejectPenalty, want.halfLife, want.rejectPenalty)
+	}
+	if !reflect.DeepEqual(got.rejections, want.rejections) {
+		t.Errorf("rejections = %v, muốn %v", got.rejections, want.rejections)
+	}
+	if !reflect.DeepEqual(got.pins, want.pins) {
+		t.Errorf("pins = %v, muốn %v", got.pins, want.pins)
+	}
+	if got.maxFeedback != want.maxFeedback || !reflect.DeepEqual(got.feedbackOrder, want.feedbackOrder) {
+		t.Errorf("maxFeedback, feedbackOrder = %d, %v, muốn %d, %v", got.maxFeedback, got.feedbackOrder, want.maxFeedback, want.feedbackOrder)
+	}
+}
+
+func TestQueryCache_SaveLoad_RoundTrip(t *testing.T) {
+	cache := newPersistTestCache()
+
+	for _, format := range []CacheFormat{FormatJSON, FormatBinary} {
+		var buf bytes.Buffer
+		if err := cache.SaveTo(&buf, format); err != nil {
+			t.Fatalf("format %d: SaveTo lỗi: %v", format, err)
+		}
+		loaded, err := LoadQueryCache(&buf)
+		if err != nil {
+			t.Fatalf("format %d: LoadQueryCache lỗi: %v", format, err)
+		}
+		assertCacheEqual(t, loaded, cache)
+
+		// Cache đọc lên phải dùng được như bình thường, giữ đúng thứ tự LRU
+		if got := loaded.GetRecentQueries(1); len(got) != 1 || got[0] != "main" {
+			t.Errorf("format %d: query gần nhất = %v, muốn [main]", format, got)
+		}
+		if !reflect.DeepEqual(loaded.GetBoostScores("main"), cache.GetBoostScores("main")) {
+			t.Errorf("format %d: GetBoostScores khác cache gốc", format)
+		}
+	}
+}
+
+func TestQueryCache_SaveBinary_Smaller(t *testing.T) {
+	cache := NewQueryCache()
+	for i := 0; i < 100; i++ {
+		cache.RecordSelection("query "+strings.Repeat("x", i%10), "/project/file.go")
+	}
+
+	var js, bin bytes.Buffer
+	cache.Save(&js)
+	cache.SaveBinary(&bin)
+	if !bytes.HasPrefix(bin.Bytes(), []byte(cacheMagic)) {
+		t.Errorf("File binary phải bắt đầu bằng %q", cacheMagic)
+	}
+	if bin.Len() >= js.Len() {
+		t.Errorf("Binary (%d byte) phải gọn hơn JSON (%d byte)", bin.Len(), js.Len())
+	}
+}
+
+func TestLoadQueryCache_Errors(t *testing.T) {
+	var bin bytes.Buffer
+	newPersistTestCache().SaveBinary(&bin)
+	full := bin.Bytes()
+
+	futureBin := append([]byte(cacheMagic), binary.AppendUvarint(nil, cacheFormatVersion+1)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrCacheFormat},
+		{"garbage", []byte("hello"), ErrCacheFormat},
+		{"json future version", []byte(`{"version": 99}`), ErrCacheVersion},
+		{"json missing version", []byte(`{"maxQueries": 10}`), ErrCacheVersion},
+		{"json broken", []byte(`{"version": 1,`), ErrCacheCorrupt},
+		{"json order mismatch", []byte(`{"version":1,"maxQueries":10,"maxPerQuery":5,"queryOrder":["a"],"entries":{"b":[{"path":"/b","count":1}]}}`), ErrCacheCorrupt},
+		{"json feedbackOrder mismatch", []byte(`{"version":3,"queryOrder":[],"entries":{},"pins":{"a":["/a"]},"feedbackOrder":["b"]}`), ErrCacheCorrupt},
+		{"json duplicate pin", []byte(`{"version":3,"queryOrder":[],"entries":{},"pins":{"a":["/a","/a"]}}`), ErrCacheCorrupt},
+		{"json bad count", []byte(`{"version":1,"maxQueries":10,"maxPerQuery":5,"queryOrder":["a"],"entries":{"a":[{"path":"/a","count":0}]}}`), ErrCacheCorrupt},
+		{"binary future version", futureBin, ErrCacheVersion},
+		{"binary truncated", full[:len(full)-3], ErrCacheCorrupt},
+		{"binary trailing", append(append([]byte(nil), full...), 0), ErrCacheCorrupt},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadQueryCache(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, muốn %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadQueryCache_EvictsOverLimit(t *testing.T) {
+	data := `
+	{"version":1,"maxQueries":2,"maxPerQuery":5,"boostScore":5000,
+	 "queryOrder":["q1","q2","q3"],
+	 "entries":{"q1":[{"path":"/a","count":1}],"q2":[{"path":"/b","count":1}],"q3":[{"path":"/c","count":2}]}}`
+
+	cache, err := LoadQueryCache(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadQueryCache lỗi: %v", err)
+	}
+	if cache.Size() != 2 || !reflect.DeepEqual(cache.queryOrder, []string{"q2", "q3"}) {
+		t.Errorf("Phải bỏ query cũ nhất khi vượt maxQueries, got %v", cache.queryOrder)
+	}
+}
+
+func TestLoadQueryCache_Version1(t *testing.T) {
+	// File từ phiên bản đầu tiên: chưa có halfLife và thời gian chọn
+	data := `{"version":1,"maxQueries":100,"maxPerQuery":5,"boostScore":5000,
+	 "queryOrder":["main"],"entries":{"main":[{"path":"/main.go","count":3}]}}`
+
+	cache, err := LoadQueryCache(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadQueryCache lỗi: %v", err)
+	}
+	if cache.halfLife != DefaultHalfLife || cache.rejectPenalty != DefaultRejectPenalty || cache.maxFeedback != DefaultMaxFeedback {
+		t.Errorf("halfLife, rejectPenalty, maxFeedback = %v, %d, %d, muốn giá trị mặc định", cache.halfLife, cache.rejectPenalty, cache.maxFeedback)
+	}
+	// Không có thời gian thì không phân rã, dù đồng hồ đã qua rất lâu
+	cache.SetClock(func() time.Time { return time.Now().Add(10 * 365 * 24 * time.Hour) })
+	if got := cache.GetBoostScores("main")["/main.go"]; got != 5000*3 {
+		t.Errorf("Boost entry version 1 = %d, muốn %d", got, 5000*3)
+	}
+	// Chọn lại sau khi đọc thì bắt đầu có thời gian
+	cache.RecordSelection("main", "/main.go")
+	if entry := cache.entries["main"][0]; entry.SelectCount != 4 || entry.LastSelected.IsZero() {
+		t.Errorf("Entry sau khi chọn lại = %+v", entry)
+	}
+}
+
+func TestQueryCache_SaveFile_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.bin")
+	cache := newPersistTestCache()
+
+	if err := cache.SaveFile(path, FormatBinary); err != nil {
+		t.Fatalf("SaveFile lỗi: %v", err)
+	}
+	// Ghi đè lần 2 vẫn phải thành công và không để lại file tạm
+	cache.RecordSelection("readme", "/README.md")
+	if err := cache.SaveFile(path, FormatBinary); err != nil {
+		t.Fatalf("SaveFile lần 2 lỗi: %v", err)
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Thư mục chỉ được có 1 file, got %d", len(files))
+	}
+
+	loaded, err := LoadQueryCacheFile(path)
+	if err != nil {
+		t.Fatalf("LoadQueryCacheFile lỗi: %v", err)
+	}
+	assertCacheEqual(t, loaded, cache)
+
+	if _, err := LoadQueryCacheFile(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("File không tồn tại phải trả os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestQueryCache_Autosave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := NewQueryCache()
+	stop := cache.StartAutosave(AutosaveOptions{Path: path, Interval: 10 * time.Millisecond})
+
+	// Chưa có thay đổi thì không ghi file
+	time.Sleep(30 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Không có thay đổi thì không được tạo file, err = %v", err)
+	}
+
+	cache.RecordSelection("main", "/main.go")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if loaded, err := LoadQueryCacheFile(path); err == nil && loaded.Size() == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Autosave không ghi file sau khi cache thay đổi")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// stop phải ghi nốt thay đổi cuối
+	cache.RecordSelection("config", "/config.yaml")
+	if err := stop(); err != nil {
+		t.Fatalf("stop lỗi: %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Gọi stop lần 2 lỗi: %v", err)
+	}
+	loaded, err := LoadQueryCacheFile(path)
+	if err != nil {
+		t.Fatalf("LoadQueryCacheFile lỗi: %v", err)
+	}
+	assertCacheEqual(t, loaded, cache)
+}
+
+func TestQueryCache_Autosave_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing-dir", "cache.json")
+	cache := NewQueryCache()
+
+	errs := make(chan error, 16)
+	stop := cache.StartAutosave(AutosaveOptions{
+		Path:     path,
+		Interval: 5 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	cache.RecordSelection("main", "/main.go")
+
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnError phải được gọi khi ghi file lỗi")
+	}
+	// Ghi lỗi thì giữ cờ dirty, stop sẽ thử ghi lại và trả lỗi
+	if err := stop(); err == nil {
+		t.Error("stop phải trả lỗi khi không ghi được file")
+	}
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"crypto/rand"
 	_ "embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"path/filepath"
-	"sync"
+	"time"
 
 	"github.com/verse91/fuzzyvn"
 )
@@ -17,21 +20,38 @@ import (
 //go:embed index.html
 var challengeHTML string
 
+// Lịch sử chọn file được lưu ở đây, chạy lại demo vẫn còn boost cũ
+const cacheFile = "query_cache.json"
+
+// Searcher tự lo concurrency (copy-on-write), không cần lock bên ngoài
 var (
-	searcher     *fuzzyvn.Searcher
-	searcherLock sync.RWMutex
-	globalCache  *fuzzyvn.QueryCache
+	searcher    = fuzzyvn.NewSearcherWithCache(nil, loadCache())
+	globalCache = searcher.GetCache()
 )
 
+func loadCache() *fuzzyvn.QueryCache {
+	cache, err := fuzzyvn.LoadQueryCacheFile(cacheFile)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("Error loading cache, starting empty:", err)
+		}
+		return fuzzyvn.NewQueryCache()
+	}
+	return cache
+}
+
 type SearchResult struct {
-	Path    string `json:"path"`
-	Score   int    `json:"score"`
-	Boosted bool   `json:"boosted"`
+	Path      string                 `json:"path"`
+	Score     int                    `json:"score"`
+	Boosted   bool                   `json:"boosted"`
+	Breakdown fuzzyvn.ScoreBreakdown `json:"breakdown"`
 }
 
 type SearchResponse struct {
 	CachedFiles []string       `json:"cached_files"`
 	Results     []SearchResult `json:"results"`
+	Total       int            `json:"total"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
 }
 
 type SelectionRequest struct {
@@ -45,7 +65,28 @@ type CacheInfo struct {
 	RecentFiles   []string `json:"recent_files"`
 }
 
+// userID: Mỗi trình duyệt 1 ID riêng (cookie), để click của người này không đổi thứ hạng của người khác
+func userID(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie("fuzzyvn_user"); err == nil && c.Value != "" {
+		return c.Value
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{Name: "fuzzyvn_user", Value: id, Path: "/", HttpOnly: true})
+	return id
+}
+
+// userCache: Cache riêng của user nếu đã có, chưa có thì dùng cache chung
+func userCache(id string) *fuzzyvn.QueryCache {
+	if cache, ok := searcher.Users.Lookup(id); ok {
+		return cache
+	}
+	return globalCache
+}
+
 func handleHome(w http.ResponseWriter, r *http.Request) {
+	userID(w, r)
 	tmpl := challengeHTML
 	t, _ := template.New("index").Parse(tmpl)
 	t.Execute(w, nil)
@@ -69,23 +110,14 @@ func indexFiles(rootPath string) {
 		return
 	}
 
-	var newSearcher *fuzzyvn.Searcher
-	if globalCache != nil {
-		newSearcher = fuzzyvn.NewSearcherWithCache(tempFiles, globalCache)
-	} else {
-		newSearcher = fuzzyvn.NewSearcher(tempFiles)
-		globalCache = newSearcher.GetCache()
-	}
-
-	searcherLock.Lock()
-	searcher = newSearcher
-	searcherLock.Unlock()
+	searcher.Reset(tempFiles)
 
 	fmt.Printf("Indexed %d files. Cache: %d queries\n", len(tempFiles), globalCache.Size())
 }
 
 func search(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
+	uid := userID(w, r)
 	if query == "" {
 		json.NewEncoder(w).Encode(SearchResponse{
 			CachedFiles: []string{},
@@ -94,58 +126,49 @@ func search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	searcherLock.RLock()
-	defer searcherLock.RUnlock()
+	cachedFiles := userCache(uid).GetCachedFiles(query, 5)
 
-	if searcher == nil {
-		json.NewEncoder(w).Encode(SearchResponse{
-			CachedFiles: []string{},
-			Results:     []SearchResult{},
-		})
+	// cursor: lấy từ next_cursor của lần gọi trước để "load more"
+	// r.Context() bị hủy khi browser bỏ request (user gõ tiếp), search sẽ dừng sớm
+	page, err := searcher.SearchContext(r.Context(), query, fuzzyvn.SearchOptions{
+		Limit:  20,
+		Cursor: r.URL.Query().Get("cursor"),
+		UserID: uid,
+	})
+	if r.Context().Err() != nil {
 		return
 	}
-
-	var cachedFiles []string
-	if searcher.Cache != nil {
-		cachedFiles = searcher.Cache.GetCachedFiles(query, 5)
-	}
-
-	var boostedFiles map[string]int
-	if searcher.Cache != nil {
-		boostedFiles = searcher.Cache.GetBoostScores(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	matchedStrings := searcher.Search(query)
-
 	cachedSet := make(map[string]bool)
 	for _, f := range cachedFiles {
 		cachedSet[f] = true
 	}
 
 	results := []SearchResult{}
-	maxRes := 20
 
-	for _, str := range matchedStrings {
-		if cachedSet[str] {
+	for _, m := range page.Results {
+		if cachedSet[m.Str] {
 			continue
 		}
 
-		_, isBoosted := boostedFiles[str]
 		results = append(results, SearchResult{
-			Path:    str,
-			Score:   0,
-			Boosted: isBoosted,
+			Path:      m.Str,
+			Score:     m.Score,
+			Boosted:   m.Breakdown.CacheBoost > 0,
+			Breakdown: m.Breakdown,
 		})
-
-		if len(results) >= maxRes {
-			break
-		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(SearchResponse{
 		CachedFiles: cachedFiles,
 		Results:     results,
+		Total:       page.Total,
+		NextCursor:  page.NextCursor,
 	})
 }
 
@@ -161,31 +184,19 @@ func recordSelection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	searcherLock.RLock()
-	defer searcherLock.RUnlock()
-
-	if searcher != nil {
-		searcher.RecordSelection(req.Query, req.Path)
-	}
+	// Lưu vào cache riêng của user và cache chung (chỉ cache chung được autosave)
+	searcher.RecordSelectionAs(userID(w, r), req.Query, req.Path)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 func cacheInfo(w http.ResponseWriter, r *http.Request) {
-	searcherLock.RLock()
-	defer searcherLock.RUnlock()
-
+	cache := userCache(userID(w, r))
 	info := CacheInfo{
-		Size:          0,
-		RecentQueries: []string{},
-		RecentFiles:   []string{},
-	}
-
-	if globalCache != nil {
-		info.Size = globalCache.Size()
-		info.RecentQueries = globalCache.GetRecentQueries(10)
-		info.RecentFiles = globalCache.GetAllRecentFiles(5)
+		Size:          cache.Size(),
+		RecentQueries: cache.GetRecentQueries(10),
+		RecentFiles:   cache.GetAllRecentFiles(5),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -195,6 +206,12 @@ func cacheInfo(w http.ResponseWriter, r *http.Request) {
 func main() {
 	go indexFiles("./test_data")
 
+	globalCache.StartAutosave(fuzzyvn.AutosaveOptions{
+		Path:     cacheFile,
+		Interval: 2 * time.Second,
+		OnError:  func(err error) { log.Println("Error saving cache:", err) },
+	})
+
 	http.HandleFunc("/", handleHome)
 	http.HandleFunc("/search", search)
 	http.HandleFunc("/record-selection", recordSelection)
@@ -0,0 +1,227 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+diacritics.go Structure:
+├── Tables
+│   ├── tonedVowel
+│   ├── tonedVowels
+│   └── vowelTones
+└── Functions
+
+	├── foldAccents (private)
+	├── writeWord (private)
+	├── buildTonedVowels (private)
+	├── canonicalTone (private)
+	├── untoned (private)
+	├── accentDoc (private)
+	├── accentedWords (private)
+	└── countAccentMatches (private)
+*/
+package fuzzyvn
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+- Normalize gộp "bạn", "bán", "bản" và "ban" thành 1 chuỗi "ban", nên user gõ cẩn thận "bản đồ" vẫn thấy "bán đồ" đứng ngang hàng
+- Searcher giữ thêm 1 bản chỉ lowercase + NFC (giữ dấu) của mỗi item có ký tự ngoài ASCII
+- DiacriticRanker cộng điểm cho mỗi từ có dấu của query xuất hiện đúng dấu trong bản này
+- Query không dấu không có từ nào để so, điểm giữ nguyên như cũ
+*/
+
+// =============================================================================
+// Tables
+// =============================================================================
+
+// tonedVowel: 1 nguyên âm gốc (aăâeêioôơuưy) kèm dấu thanh (index trong toneMarks)
+type tonedVowel struct {
+	base rune
+	tone int
+}
+
+// tonedVowels: Nguyên âm có dấu thanh → (nguyên âm gốc, dấu thanh), vowelTones là chiều ngược lại. Dựng 1 lần bằng NFC
+var tonedVowels, vowelTones = buildTonedVowels()
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+/*
+- foldAccents: Lowercase + NFC nhưng giữ dấu, "" nếu s toàn ASCII (không có dấu để so)
+- Dấu thanh được đặt lại theo kiểu cũ (canonicalTone) nên "hoà" và "hòa", "thuỷ" và "thủy" là 1
+- Ví dụ: foldAccents("Bản_Đồ Hoà Bình") = "bản_đồ hòa bình"
+*/
+func foldAccents(s string) string {
+	if isASCII(s) {
+		return ""
+	}
+	if !norm.NFC.IsNormalString(s) {
+		s = norm.NFC.String(s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	var buf [16]rune
+	word := buf[:0]
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			word = append(word, unicode.ToLower(r))
+			continue
+		}
+		writeWord(&b, word)
+		word = word[:0]
+		b.WriteRune(r)
+	}
+	writeWord(&b, word)
+	return b.String()
+}
+
+// writeWord: Ghi 1 từ (đã lowercase) vào b sau khi đặt lại dấu thanh
+func writeWord(b *strings.Builder, word []rune) {
+	canonicalTone(word)
+	for _, r := range word {
+		b.WriteRune(r)
+	}
+}
+
+func buildTonedVowels() (map[rune]tonedVowel, map[tonedVowel]rune) {
+	split := make(map[rune]tonedVowel, 72)
+	join := make(map[tonedVowel]rune, 72)
+	for _, v := range "aăâeêioôơuưy" {
+		for t := 1; t < len(toneMarks); t++ {
+			if composed := []rune(norm.NFC.String(string([]rune{v, toneMarks[t]}))); len(composed) == 1 {
+				split[composed[0]] = tonedVowel{v, t}
+				join[tonedVowel{v, t}] = composed[0]
+			}
+		}
+	}
+	return split, join
+}
+
+/*
+- canonicalTone: Đặt lại dấu thanh của 1 âm tiết (chữ thường, NFC) theo tonePosition (telex.go), sửa ngay trên word
+- Bộ gõ kiểu mới đặt "hoà", "thuỷ", kiểu cũ đặt "hòa", "thủy": NFC không gộp được 2 cách này
+- Từ không có dấu thanh, có nhiều hơn 1 dấu thanh (nhiều âm tiết viết liền) hoặc không phải âm tiết thì giữ nguyên
+*/
+func canonicalTone(word []rune) {
+	n := len(word)
+	if n < 2 || n > 7 { // Âm tiết dài nhất 7 chữ cái ("nghiêng")
+		return
+	}
+	// 2 kiểu đặt dấu chỉ khác nhau ở vần oa, oe, uy không có phụ âm cuối, từ khác đã đúng sẵn
+	if x, y := untoned(word[n-2]), untoned(word[n-1]); !(x == 'o' && (y == 'a' || y == 'e') || x == 'u' && y == 'y') {
+		return
+	}
+	var folded [7]byte
+	tone, at := 0, -1
+	for i, r := range word {
+		if base := untoned(r); base != r {
+			if tone != 0 {
+				return
+			}
+			tone, at, r = tonedVowels[r].tone, i, base
+		}
+		f, _ := foldRune(r)
+		if f >= utf8.RuneSelf {
+			return
+		}
+		folded[i] = byte(f)
+	}
+	if tone == 0 || !isSyllable(string(folded[:len(word)])) {
+		return
+	}
+	word[at] = tonedVowels[word[at]].base
+	pos := tonePosition(word)
+	if pos < 0 {
+		pos = at
+	}
+	word[pos] = vowelTones[tonedVowel{word[pos], tone}]
+}
+
+// untoned: Bỏ dấu thanh của 1 nguyên âm, giữ mũ/móc/trăng
+func untoned(r rune) rune {
+	if r >= utf8.RuneSelf {
+		if tv, ok := tonedVowels[r]; ok {
+			return tv.base
+		}
+	}
+	return r
+}
+
+/*
+- accentDoc: Bản giữ dấu của Document, chỉ gồm các trường có ký tự ngoài ASCII (nối bằng dấu cách)
+- "" nếu mọi trường đều ASCII, phần lớn item là code nên không tốn thêm bộ nhớ
+- Trường toàn ASCII không chứa từ có dấu nào, Primary nằm sẵn trong Secondary (đường dẫn chứa tên file) thì bỏ qua
+- countAccentMatches so chuỗi con nên bỏ các trường này không làm đổi kết quả
+*/
+func accentDoc(doc Document) string {
+	var segs []string
+	keep := func(s string) {
+		if !isASCII(s) {
+			segs = append(segs, s)
+		}
+	}
+	if len(doc.Fields) > 0 {
+		for _, f := range doc.Fields {
+			keep(f.Value)
+		}
+	} else {
+		if !slices.ContainsFunc(doc.Secondary, func(s string) bool { return strings.Contains(s, doc.Primary) }) {
+			keep(doc.Primary)
+		}
+		for _, s := range doc.Secondary {
+			keep(s)
+		}
+	}
+	switch len(segs) {
+	case 0:
+		return ""
+	case 1:
+		return foldAccents(segs[0])
+	}
+	return foldAccents(strings.Join(segs, " "))
+}
+
+/*
+- accentedWords: Các từ có dấu của query (đã foldAccents), nil nếu query không dấu
+- Từ không dấu trong query ("bản do" → chỉ có "bản") không tham gia so dấu
+*/
+func accentedWords(query string) []string {
+	folded := foldAccents(query)
+	if folded == "" {
+		return nil
+	}
+	var words []string
+	for _, w := range strings.FieldsFunc(folded, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if !isASCII(w) {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+/*
+- countAccentMatches: Số từ trong words xuất hiện đúng dấu trong accented (bản giữ dấu của item)
+- So chuỗi con nên tên viết liền ("bảnđồ") vẫn khớp
+*/
+func countAccentMatches(words []string, accented string) int {
+	if accented == "" {
+		return 0
+	}
+	count := 0
+	for _, w := range words {
+		if strings.Contains(accented, w) {
+			count++
+		}
+	}
+	return count
+}
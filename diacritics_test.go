@@ -0,0 +1,129 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestFoldAccents(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"Bản_Đồ Hoà Bình", "bản_đồ hòa bình"},
+		{"THUỶ ĐIỆN", "thủy điện"},
+		{"Ba\u0309n đô\u0300", "bản đồ"}, // NFD (macOS)
+		{"quả", "quả"},
+		{"khoẻ, quý", "khỏe, quý"},
+		{"hòa", "hòa"},
+		{"bảnđồ", "bảnđồ"}, // 2 dấu thanh trong 1 từ: giữ nguyên
+		{"café", "café"},   // Không phải âm tiết tiếng Việt
+		{"main.go", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := foldAccents(tt.input); got != tt.want {
+			t.Errorf("foldAccents(%q) = %q, muốn %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAccentedWords(t *testing.T) {
+	if words := accentedWords("Bản đồ 2024"); !slices.Equal(words, []string{"bản", "đồ"}) {
+		t.Errorf("accentedWords(Bản đồ 2024) = %v", words)
+	}
+	if words := accentedWords("bản do"); !slices.Equal(words, []string{"bản"}) {
+		t.Errorf("accentedWords(bản do) = %v", words)
+	}
+	if words := accentedWords("ban do"); words != nil {
+		t.Errorf("accentedWords(ban do) = %v, muốn nil", words)
+	}
+}
+
+func TestSearcher_DiacriticBonus(t *testing.T) {
+	files := []string{
+		"/shop/Bán_đồ.pdf",
+		"/maps/Bản_đồ_Hà_Nội.png",
+		"/notes/ban_do.txt",
+	}
+	cfg := DefaultConfig()
+	cfg.DiacriticBonus = DefaultDiacriticBonus
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	results := searcher.SearchResults("bản đồ")
+	if len(results) != 3 || results[0].Str != files[1] {
+		t.Fatalf("SearchResults(bản đồ) phải trả về bản đồ đầu tiên, got %v", results)
+	}
+	want := map[string]int{files[0]: DefaultDiacriticBonus, files[1]: 2 * DefaultDiacriticBonus, files[2]: 0}
+	for _, r := range results {
+		if r.Breakdown.Diacritic != want[r.Str] {
+			t.Errorf("%s: Breakdown.Diacritic = %d, muốn %d", r.Str, r.Breakdown.Diacritic, want[r.Str])
+		}
+		if r.Score != r.Breakdown.Total() {
+			t.Errorf("%s: Score = %d, Total() = %d", r.Str, r.Score, r.Breakdown.Total())
+		}
+	}
+
+	// Kiểu đặt dấu khác ("hoà" / "hòa") vẫn tính là đúng dấu
+	if results := NewSearcherWithConfig([]string{"/a/Hòa_Bình.md"}, cfg).SearchResults("hoà bình"); len(results) != 1 || results[0].Breakdown.Diacritic != 2*DefaultDiacriticBonus {
+		t.Errorf("SearchResults(hoà bình) = %v, muốn Diacritic = %d", results, 2*DefaultDiacriticBonus)
+	}
+
+	// Query không dấu giữ nguyên như cũ: không ai được thưởng
+	for _, r := range searcher.SearchResults("ban do") {
+		if r.Breakdown.Diacritic != 0 {
+			t.Errorf("Query không dấu nhưng %s có Diacritic = %d", r.Str, r.Breakdown.Diacritic)
+		}
+	}
+
+	// Telex đã giải mã là query có dấu nên cũng được thưởng
+	decodeCfg := cfg
+	decodeCfg.DecodeKeystrokes = true
+	results = NewSearcherWithConfig(files, decodeCfg).SearchResults("banr ddoof")
+	if len(results) == 0 || results[0].Str != files[1] || results[0].Breakdown.Query != "bản đồ" {
+		t.Errorf("SearchResults(banr ddoof) phải trả về bản đồ đầu tiên qua bản giải mã, got %v", results)
+	}
+
+	// DefaultConfig không bật: thứ hạng giữ nguyên như trước
+	for _, r := range NewSearcher(files).SearchResults("bản đồ") {
+		if r.Breakdown.Diacritic != 0 {
+			t.Errorf("DiacriticBonus mặc định tắt nhưng %s có Diacritic = %d", r.Str, r.Breakdown.Diacritic)
+		}
+	}
+}
+
+func TestSearcher_DiacriticAfterUpdate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DiacriticBonus = DefaultDiacriticBonus
+	searcher := NewSearcherWithConfig([]string{"/a/Bán_đồ.pdf", "/a/main.go"}, cfg)
+	searcher.Remove("/a/Bán_đồ.pdf")
+	searcher.Add("/a/Bản_đồ.pdf")
+	checkIndexConsistent(t, searcher.index.Load())
+
+	results := searcher.SearchResults("bản đồ")
+	if len(results) == 0 || results[0].Breakdown.Diacritic != 2*DefaultDiacriticBonus {
+		t.Errorf("Sau Remove/Add: SearchResults(bản đồ) = %v", results)
+	}
+}
+
+func BenchmarkSearch_Diacritic(b *testing.B) {
+	files := generateMonorepoFiles(100000, 1)
+	for _, bonus := range []int{0, DefaultDiacriticBonus} {
+		cfg := DefaultConfig()
+		cfg.DiacriticBonus = bonus
+		searcher := NewSearcherWithConfig(files, cfg)
+		for _, q := range []string{"báo cáo", "bao cao"} {
+			b.Run(fmt.Sprintf("%s/bonus=%d", q, bonus), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					searcher.Search(q)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFoldAccents(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		foldAccents("/docs/Báo_cáo_tài_chính_Hoà_Bình_2024.xlsx")
+	}
+}
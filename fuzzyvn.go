@@ -9,53 +9,159 @@ fuzzyvn.go Structure:
 │   ├── CacheEntry
 │   ├── QueryCache
 │   ├── Searcher
+│   ├── searchIndex
+│   ├── Document
+│   ├── Field
+│   ├── ScoreBreakdown
+│   ├── ScoreBreakdown.Total
 │   ├── MatchResult
+│   ├── SearchOptions
+│   ├── SearchPage
 │   ├── FuzzyMatch
-│   └── Scoring constants
+│   ├── Scoring constants
+│   ├── Algorithm
+│   ├── Config
+│   └── DefaultConfig
 ├── Utility Functions
 │   ├── abs
+│   ├── isDone
 │	├── isSeparator
 │   ├── countWordMatches
-│	├── fastSubstring
+│   ├── typoWordMatch (private)
 │   ├── Normalize
+│   ├── foldRune
+│   ├── NormalizeWithOffsets
 │   ├── LevenshteinRatio
+│   ├── DamerauLevenshtein
+│   ├── BoundedDamerauLevenshtein
+│   ├── editDistance (private)
+│   ├── editDistanceOf (private)
 │   └── isWordBoundary
-├── Fuzzy Matcher - zero-dependency, greedy algorithm
+├── Fuzzy Matcher - zero-dependency, greedy + optimal (DP) algorithm
 │   ├── fuzzyScoreGreedy
+│   ├── fuzzyScoreGreedyPositions
+│   ├── scoreFunc
+│   ├── fuzzyScore
+│   ├── isWordStartAt
+│   ├── fuzzyScoreOptimal
 │   ├── FuzzyFind
-│   └── FuzzyFindParallel
+│   ├── FuzzyFindWithPositions
+│   ├── scoreDecoded (private)
+│   ├── FuzzyFindParallel
+│   └── FuzzyFindParallelWithPositions
 ├── QueryCache Methods
 │   ├── querySimilarity (private)
+│   ├── queryTypoThreshold (private)
+│   ├── frecency (private)
+│   ├── touch (private)
+│   ├── merge (private)
+│   ├── clock (private)
 │   ├── moveToFront (private)
+│   ├── removeEntry (private)
+│   ├── forEachCandidate (private)
+│   ├── forEachRejection (private)
+│   ├── removeString (private)
+│   ├── setList (private)
+│   ├── touchFeedback (private)
+│   ├── evictFeedback (private)
 │   ├── evictIfNeeded (private)
 │   ├── NewQueryCache
 │   ├── SetMaxQueries
+│   ├── SetHalfLife
+│   ├── SetClock
 │   ├── SetBoostScore
 │   ├── RecordSelection
+│   ├── RecordRejection
+│   ├── Pin
+│   ├── Unpin
+│   ├── GetPins
+│   ├── SetRejectPenalty
+│   ├── SetMaxFeedback
 │   ├── GetBoostScores
 │   ├── GetRecentQueries
 │   ├── GetCachedFiles
 │   ├── GetAllRecentFiles
+│   ├── RenamePath
 │   ├── Size
 │   └── Clear
 └── Searcher Methods
 
 	├── NewSearcher
+	├── NewSearcherWithConfig
+	├── buildIndex (private)
+	├── clone (private)
+	├── buildDocIndex (private)
+	├── newSearchIndex (private)
+	├── appendDoc (private)
+	├── setDoc (private)
+	├── removeAt (private)
+	├── seal (private)
+	├── doc (private)
+	├── pathDocument (private)
+	├── normalizeDoc (private)
+	├── primaryOf (private)
+	├── normalizeFields (private)
+	├── fieldEnds (private)
+	├── segments (private)
+	├── fieldWeight (private)
+	├── scoreFields (private)
+	├── fuzzyFindFields (private)
+	├── normalizeItem (private)
 	├── NewSearcherWithCache
 	├── Search
+	├── SearchResults
+	├── SearchWithOptions
+	├── SearchContext
+	├── searchPage (private)
+	├── rank (private)
+	├── rankQuery (private)
+	├── newRankState (private)
+	├── rankBefore (private)
+	├── topK (private)
+	├── encodeCursor (private)
+	├── decodeCursor (private)
+	├── matchPositions (private)
+	├── Add
+	├── addDocs (private)
+	├── Remove
+	├── Rename
+	├── replaceDoc (private)
+	├── Reset
+	├── swapIndex (private)
+	├── Len
+	├── Items
+	├── Contains
+	├── Originals (deprecated)
+	├── Normalized (deprecated)
+	├── FilenamesOnly (deprecated)
+	├── FilePathToIdx (deprecated)
 	├── RecordSelection
+	├── SearchAs
+	├── RecordSelectionAs
+	├── Config
 	├── GetCache
 	└── ClearCache
 */
 package fuzzyvn
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"hash/fnv"
+	"maps"
+	"math"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/text/unicode/norm"
 )
@@ -66,16 +172,27 @@ import (
 // =============================================================================
 
 /*
-  - Boost score tính theo SelectCount: File chọn nhiều lần → điểm boost cao hơn:
-    boost = boostScore * similarity * SelectCount / 100
-  - Khi vượt giới hạn (maxPerQuery = 5): Entry có SelectCount thấp nhất bị xóa
-  - Lần search sau: Files có SelectCount cao được ưu tiên lên đầu
+  - Boost score tính theo frecency (tần suất + độ mới): File chọn nhiều lần VÀ gần đây → điểm boost cao hơn:
+    boost = boostScore * similarity * frecency / 100
+  - frecency = SelectCount * trung bình hệ số phân rã của tối đa 10 lần chọn gần nhất (xem QueryCache.frecency)
+  - File chọn 50 lần từ năm ngoái không còn đè mãi lên file chọn 5 lần hôm nay
+  - Khi vượt giới hạn (maxPerQuery = 5): Entry có frecency thấp nhất bị xóa
+  - Lần search sau: Files có frecency cao được ưu tiên lên đầu
 */
 type CacheEntry struct {
-	FilePath    string // Đường dẫn file đã chọn
-	SelectCount int    // Số lần user chọn file này
+	FilePath     string      // Đường dẫn file đã chọn
+	SelectCount  int         // Số lần user chọn file này
+	LastSelected time.Time   // Lần chọn gần nhất (UTC), zero nếu đọc từ file cache cũ chưa có thời gian
+	selectedAt   []time.Time // Tối đa frecencySamples lần chọn gần nhất, cũ nhất đứng đầu
 }
 
+const (
+	DefaultHalfLife      = 30 * 24 * time.Hour // Half-life mặc định của frecency: 1 lần chọn cách đây 30 ngày chỉ còn nửa điểm
+	DefaultRejectPenalty = 20000               // Điểm trừ mặc định cho file bị từ chối, lớn hơn tổng các điểm khác để đẩy xuống cuối
+	DefaultMaxFeedback   = 1000                // Số query tối đa có từ chối hoặc ghim (xem SetMaxFeedback)
+	frecencySamples      = 10                  // Số lần chọn gần nhất được giữ lại để tính frecency
+)
+
 type QueryCache struct {
 	mu          sync.RWMutex            // Dùng RWMutex thay vì Mutex vì search chủ yếu là đọc (99%), tránh race codition
 	entries     map[string][]CacheEntry // Key là từ khóa (đã chuẩn hóa), Value là danh sách các CacheEntry
@@ -83,38 +200,317 @@ type QueryCache struct {
 	maxQueries  int                     // Giới hạn tổng số từ khóa được lưu
 	maxPerQuery int                     // Giới hạn số file được lưu cho mỗi từ khóa
 	boostScore  int                     // Điểm cho các file hay search
+	halfLife    time.Duration           // Sau mỗi halfLife, điểm của 1 lần chọn giảm một nửa. 0 = không phân rã
+	now         func() time.Time        // Đồng hồ, thay được qua SetClock để test
+	dirty       atomic.Bool             // Có thay đổi chưa được autosave ghi xuống file
+	index       *queryIndex             // Index ngược trên key của entries, chỉ chấm querySimilarity cho query có khả năng giống
+
+	// Phản hồi tường minh của user, không bị LRU của entries (maxQueries) xóa và không tính vào Size
+	// Có giới hạn riêng (maxFeedback) để map không phình mãi khi user từ chối/ghim trên rất nhiều query
+	rejectPenalty int                 // Điểm trừ cho file bị từ chối (RecordRejection)
+	rejections    map[string][]string // Query (đã chuẩn hóa) → các file user đã từ chối
+	pins          map[string][]string // Query (đã chuẩn hóa) → các file được ghim, đúng thứ tự vị trí
+	maxFeedback   int                 // Giới hạn số query có từ chối hoặc ghim
+	feedbackOrder []string            // Các query có từ chối hoặc ghim, cũ nhất đứng đầu (giống queryOrder)
+	rejectIndex   *queryIndex         // Index ngược trên key của rejections, giống index của entries
 }
 
+/*
+- Searcher tự quản lý concurrency theo kiểu copy-on-write
+- Dữ liệu index nằm trong 1 snapshot (searchIndex) KHÔNG BAO GIỜ bị sửa sau khi tạo
+- Search chỉ cần Load snapshot hiện tại (atomic), không lock, không bao giờ bị chặn bởi writer
+- Add/Remove/Rename/Reset copy snapshot cũ, sửa trên bản copy rồi swap atomic. Các writer xếp hàng với nhau qua writeMu
+- Bạn không cần bọc thêm sync.RWMutex bên ngoài nữa
+*/
 type Searcher struct {
-	Originals     []string       // Data gốc (có dấu, viết hoa thường lộn xộn bla bla). Dùng để trả về kết quả hiển thị
-	Normalized    []string       // Data đã chuẩn hóa cho fuzzy search
-	FilenamesOnly []string       // Chỉ chứa tên file đã chuẩn hóa (bỏ đường dẫn). Dùng cho thuật toán Levenshtein (sửa lỗi chính tả)
-	FilePathToIdx map[string]int // Nhằm mục đích không phải tạo lại mỗi lần Search
-	Cache         *QueryCache    // Để lấy dữ liệu lịch sử
+	writeMu sync.Mutex                  // Chỉ writer dùng, tránh 2 writer cùng copy từ 1 snapshot rồi ghi đè nhau
+	index   atomic.Pointer[searchIndex] // Snapshot hiện tại
+	config  Config                      // Trọng số xếp hạng, cố định sau khi tạo Searcher
+	Cache   *QueryCache                 // Để lấy dữ liệu lịch sử, dùng chung cho mọi user
+	Users   *CacheRegistry              // Cache riêng của từng user cho SearchAs / RecordSelectionAs
+}
+
+/*
+- searchIndex: Snapshot bất biến của dữ liệu đã index
+- Các slice cùng độ dài, phần tử thứ i của mỗi slice đều nói về cùng 1 item
+- Searcher theo path (typed = false) không lưu docs/values, Document suy ra lại từ originals qua pathDocument khi cần
+*/
+type searchIndex struct {
+	originals     []string       // Document.Display (với path là đường dẫn gốc, có dấu, viết hoa thường lộn xộn bla bla). Dùng để trả về kết quả hiển thị
+	normalized    []string       // Primary + Secondary đã chuẩn hóa cho fuzzy search
+	filenamesOnly []string       // Chỉ chứa trường Primary đã chuẩn hóa (với path là tên file). Dùng cho word bonus và Levenshtein (sửa lỗi chính tả)
+	accented      []string       // Như normalized nhưng giữ dấu (diacritics.go), rỗng nếu item toàn ASCII. Dùng cho DiacriticRanker
+	initials      []string       // Chữ cái đầu của từng từ/âm tiết trong trường chính (acronym.go). Dùng cho AcronymRanker
+	keyToIdx      map[string]int // Document.Key -> Index. Nhằm mục đích không phải tạo lại mỗi lần Search
+
+	typed      bool       // true nếu tạo từ ItemSearcher
+	docs       []Document // Chỉ có khi typed
+	values     []any      // Item gốc kiểu T của ItemSearcher, chỉ có khi typed
+	fieldNorms [][]string // Từng Field đã chuẩn hóa riêng, nil với item không dùng Document.Fields
+	fieldEnds  [][]uint32 // fieldEnds[i][k]: Vị trí (ký tự) kết thúc Field k trong span fields của item i
+
+	primaryField string // Config.PrimaryField lúc tạo Searcher
+
+	prefilter bool       // Config.Prefilter lúc tạo Searcher
+	masks     []charMask // Bitmask ký tự của từng item để lọc trước (prefilter.go), nil nếu không bật Prefilter
+
+	targets targetArena // normalized đã decode sẵn cho fuzzy matcher (targets.go)
+	fields  targetArena // Các Field đã chuẩn hóa của item i nối liền thành span i, chỉ có khi typed. Dùng cho scoreFields
+	typo    *typoIndex  // Trie trên filenamesOnly cho TypoRanker (typo_index.go), nil nếu không bật Config.TypoIndex
+}
+
+/*
+  - Document: Cách Searcher nhìn 1 item bất kỳ (file, sản phẩm, danh bạ, lệnh menu...)
+  - Key: Định danh duy nhất, dùng cho Remove/Update, QueryCache và chống trùng
+  - Display: Chuỗi trả về trong MatchResult.Str, Positions tính trên chuỗi này
+  - Primary: Trường chính, được ưu tiên khi fuzzy và là trường duy nhất dùng cho word bonus + Levenshtein
+  - Secondary: Các trường phụ, chỉ tham gia fuzzy (nằm sau Primary nên điểm thấp hơn)
+  - Fields: Thay cho Primary/Secondary khi cần trọng số riêng cho từng trường (xem Field)
+  - Với file path: Key = Display = path, Primary = tên file, Secondary = [path]
+    Tức là đúng chuỗi "filename + path" như trước giờ
+*/
+type Document struct {
+	Key       string
+	Display   string
+	Primary   string
+	Secondary []string
+	Fields    []Field
+}
+
+/*
+  - Field: 1 trường của Document, được chuẩn hóa và chấm fuzzy riêng
+  - Weight: Phần trăm điểm fuzzy được giữ lại khi khớp ở trường này, 0 = DefaultFieldWeight (100)
+    Ví dụ title 100, tags 60, description 30 -> khớp ở title luôn hơn khớp y hệt ở description
+  - Điểm fuzzy của item = điểm cao nhất trong các Field sau khi nhân trọng số
+  - Query khớp rải qua nhiều Field (không Field nào khớp trọn) vẫn được tính, nhưng nhân trọng số nhỏ nhất
+  - Word bonus và Levenshtein chỉ chạy trên Field có Name = Config.PrimaryField (rỗng thì Field đầu tiên)
+  - Khi Fields khác rỗng thì Primary và Secondary bị bỏ qua
+*/
+type Field struct {
+	Name   string
+	Value  string
+	Weight int
 }
 
 /*
-- Struct tạm thời dùng để gom kết quả và điểm số lại để sắp xếp trước khi trả về cho người dùng
+- ScoreBreakdown: Chi tiết điểm của 1 kết quả, để UI có thể giải thích vì sao kết quả đứng ở vị trí đó
+- Score cuối cùng = Fuzzy + WordBonus + Levenshtein + Acronym + Diacritic + CacheBoost + Custom + Synonym
+- Fuzzy và Levenshtein không cộng dồn: nhánh nào cho tổng điểm cao hơn thì giữ nhánh đó, nhánh còn lại bằng 0
+*/
+type ScoreBreakdown struct {
+	Fuzzy       int // Điểm fuzzy (fuzzyScoreGreedy hoặc fuzzyScoreOptimal)
+	WordBonus   int // Điểm thưởng cho các từ khớp trong tên file
+	Levenshtein int // Điểm sửa lỗi chính tả (so với phần đầu tên file)
+	Acronym     int // Điểm thưởng khi query là chữ cái đầu của các từ liền nhau trong tên file ("bctc" → "Báo cáo tài chính")
+	Diacritic   int // Điểm thưởng cho các từ có dấu của query khớp đúng dấu trong item
+	CacheBoost  int // Điểm boost từ lịch sử chọn file (QueryCache)
+	Custom      int // Tổng điểm từ các Scorer tùy chỉnh (Config.Scorers)
+	Synonym     int // Điểm bị trừ (<= 0) khi kết quả đến từ từ đồng nghĩa (Config.Synonyms), để khớp đúng chữ user gõ đứng trên
+
+	Field    string // Tên Field khớp fuzzy tốt nhất (chỉ với Document.Fields), rỗng nếu không có
+	Reranked bool   // Fuzzy đã được chấm lại bằng fuzzyScoreOptimal (Config.OptimalRerank)
+	Query    string // Cách hiểu khác của query đã cho ra điểm này (Telex "vieejt" → "việt", từ đồng nghĩa "hop dong" → "contract"), rỗng = query gốc
+	Pin      int    // Vị trí ghim (QueryCache.Pin): 1 = đầu tiên, 0 = không ghim. Không cộng vào Total, xếp trước mọi kết quả không ghim
+}
+
+// Total: Tổng các điểm thành phần, chính là MatchResult.Score
+func (bd ScoreBreakdown) Total() int {
+	return bd.Fuzzy + bd.WordBonus + bd.Levenshtein + bd.Acronym + bd.Diacritic + bd.CacheBoost + bd.Custom + bd.Synonym
+}
+
+/*
+  - MatchResult: Kết quả tìm kiếm kèm điểm số
+  - Index: Vị trí trong danh sách items truyền vào NewSearcher
+  - Str: Chuỗi gốc (Originals[Index])
+  - Score: Điểm cuối cùng dùng để xếp hạng
+  - Breakdown: Điểm thành phần
+  - Positions: Byte offset trong Str của các ký tự khớp với query (đã sắp xếp tăng dần)
+    Dùng utf8.DecodeRuneInString(Str[pos:]) để lấy độ dài ký tự khi cần in đậm
 */
 type MatchResult struct {
-	Str   string
-	Score int
+	Index     int
+	Str       string
+	Score     int
+	Breakdown ScoreBreakdown
+	Positions []int
 }
 
 /*
 FuzzyMatch: Kết quả của một fuzzy match
-- Index: Vị trí trong danh sách input
-- Score: Điểm match (cao = tốt hơn)
+  - Index: Vị trí trong danh sách input
+  - Score: Điểm match (cao = tốt hơn)
+  - Positions: Vị trí (rune index trong target) của từng ký tự pattern đã khớp
+    Chỉ có khi dùng FuzzyFindWithPositions/FuzzyFindParallelWithPositions, còn lại là nil
 */
 type FuzzyMatch struct {
-	Index int
-	Score int
+	Index     int
+	Score     int
+	Positions []int
+}
+
+// DefaultLimit: Số kết quả mặc định mỗi lần Search
+const DefaultLimit = 20
+
+// ctxCheckInterval: Vòng lặp nóng chỉ kiểm tra ctx mỗi N phần tử, tránh tốn select cho từng target
+const ctxCheckInterval = 256
+
+// Scoring constants: Giá trị mặc định của Config, xem giải thích ở Config
+const (
+	DefaultWordStartBonus       = 80
+	DefaultMatchBonus           = 10
+	DefaultConsecutiveBonus     = 40
+	DefaultLengthPenalty        = 1
+	DefaultWordMatchBonus       = 3000
+	DefaultMaxWordBonusCalc     = 30
+	DefaultTypoBaseScore        = 10000
+	DefaultTypoEditPenalty      = 100
+	DefaultTypoLengthPenalty    = 10
+	DefaultTypoThresholdDivisor = 3
+	DefaultMinTypoThreshold     = 3
+	DefaultAcronymBonus         = 3000 // Mức nên dùng khi bật AcronymBonus, DefaultConfig để 0 (tắt)
+	DefaultDiacriticBonus       = 1000 // Mức nên dùng khi bật DiacriticBonus, DefaultConfig để 0 (tắt)
+	DefaultParallelThreshold    = 1000
+	DefaultParallelMinTargets   = 2000
+	DefaultGapStartPenalty      = 3
+	DefaultGapExtendPenalty     = 1
+	DefaultFieldWeight          = 100
+)
+
+// Algorithm: Thuật toán fuzzy match dùng cho toàn bộ candidates
+type Algorithm int
+
+const (
+	AlgorithmGreedy  Algorithm = iota // fuzzyScoreGreedy: nhanh, duyệt target 1 lần, có thể bỏ lỡ cách khớp tốt hơn
+	AlgorithmOptimal                  // fuzzyScoreOptimal: quy hoạch động, luôn tìm cách khớp tốt nhất nhưng chậm hơn
+)
+
+/*
+- Config: Toàn bộ trọng số dùng để xếp hạng, mỗi project có thể tự chỉnh cho phù hợp với dữ liệu của mình
+- Luôn bắt đầu từ DefaultConfig() rồi sửa field cần thiết, vì giá trị 0 là hợp lệ (tắt hẳn 1 loại điểm)
+- Ví dụ:
+cfg := fuzzyvn.DefaultConfig()
+cfg.WordMatchBonus = 5000 // Ưu tiên khớp nguyên từ hơn nữa
+searcher := fuzzyvn.NewSearcherWithConfig(files, cfg)
+*/
+type Config struct {
+	// Fuzzy matcher (fuzzyScoreGreedy)
+	WordStartBonus   int // Điểm cho ký tự khớp ở đầu từ
+	MatchBonus       int // Điểm cho ký tự khớp bình thường
+	ConsecutiveBonus int // Điểm cộng thêm khi ký tự khớp liền ngay sau ký tự khớp trước
+	LengthPenalty    int // Trừ điểm mỗi ký tự target dài hơn pattern, để ưu tiên chuỗi ngắn
+
+	// Word bonus (countWordMatches)
+	WordMatchBonus   int // Điểm cho mỗi từ trong query khớp với 1 từ trong tên file
+	MaxWordBonusCalc int // Chỉ tính word bonus cho N kết quả fuzzy đầu tiên (vì countWordMatches chậm)
+
+	// Levenshtein (sửa lỗi chính tả)
+	TypoBaseScore        int  // Điểm gốc khi khớp typo: TypoBaseScore - dist * TypoEditPenalty
+	TypoEditPenalty      int  // Trừ điểm cho mỗi lỗi
+	TypoLengthPenalty    int  // Trừ điểm mỗi ký tự tên file dài hơn query
+	TypoThresholdDivisor int  // Số lỗi cho phép = queryLen / TypoThresholdDivisor + 1
+	MinTypoThreshold     int  // Số lỗi cho phép tối thiểu
+	TypoIndex            bool // Dùng trie tên file (typo_index.go) thay vì tính Levenshtein cho từng file, kết quả không đổi
+
+	// Parallel
+	ParallelThreshold  int // Search dùng FuzzyFindParallel khi số file >= ngưỡng này
+	ParallelMinTargets int // FuzzyFindParallel tự quay về bản tuần tự khi số target < ngưỡng này
+
+	// Optimal alignment (fuzzyScoreOptimal)
+	Algorithm        Algorithm // Thuật toán chấm điểm cho TẤT CẢ candidates, mặc định AlgorithmGreedy
+	OptimalRerank    int       // Khi dùng Greedy: chấm lại top N candidates bằng fuzzyScoreOptimal (không phạt khoảng trống, cùng thang điểm với greedy), 0 = tắt
+	GapStartPenalty  int       // Phạt khi mở 1 khoảng trống giữa 2 ký tự khớp
+	GapExtendPenalty int       // Phạt thêm cho mỗi ký tự tiếp theo trong khoảng trống
+
+	// Lọc trước bằng bitmask ký tự (prefilter.go)
+	Prefilter bool // Lưu thêm 32 byte mỗi item để bỏ qua item chắc chắn không khớp trước khi chấm fuzzy/Levenshtein, kết quả không đổi
+
+	// Document nhiều trường (Document.Fields)
+	PrimaryField string // Tên Field dùng cho word bonus + Levenshtein, rỗng = Field đầu tiên của mỗi Document
+
+	// Viết tắt (acronym.go)
+	AcronymBonus int // Điểm khi query là chữ cái đầu của các từ/âm tiết liền nhau trong tên file ("hdld" → "Hợp_đồng_lao_động.docx"), 0 = tắt (mặc định)
+
+	// Query có dấu (diacritics.go)
+	DiacriticBonus int // Điểm cho mỗi từ có dấu của query khớp đúng dấu trong item ("bản" khớp "bản" chứ không phải "bán"), 0 = tắt (mặc định)
+
+	// Query gõ khi tắt bộ gõ (telex.go)
+	DecodeKeystrokes bool // Chấm thêm cách hiểu Telex/VNI của query ("vieejt nam" → "việt nam") qua KeystrokeRanker, giữ điểm cao hơn cho mỗi item, mặc định tắt
+
+	// Từ đồng nghĩa (synonyms.go)
+	Synonyms *Synonyms // Chấm thêm các query thay thế ("hop dong" → "contract") qua SynonymRanker, điểm nhân trọng số của từ đồng nghĩa, nil = tắt
+
+	// Mở rộng xếp hạng (ranker.go)
+	Rankers []Ranker // Chuỗi bước xếp hạng, nil = DefaultRankers()
+	Scorers []Scorer // Tín hiệu riêng chạy sau Rankers, cộng vào Breakdown.Custom
 }
 
-var intSlicePool = sync.Pool{
+/*
+- DefaultConfig: Config mặc định, chính là các con số đã được dùng từ trước tới giờ
+- Các tín hiệu làm đổi thứ hạng đều tắt, tự bật khi cần (xem README): DecodeKeystrokes, DiacriticBonus, AcronymBonus, Synonyms
+*/
+func DefaultConfig() Config {
+	return Config{
+		WordStartBonus:       DefaultWordStartBonus,
+		MatchBonus:           DefaultMatchBonus,
+		ConsecutiveBonus:     DefaultConsecutiveBonus,
+		LengthPenalty:        DefaultLengthPenalty,
+		WordMatchBonus:       DefaultWordMatchBonus,
+		MaxWordBonusCalc:     DefaultMaxWordBonusCalc,
+		TypoBaseScore:        DefaultTypoBaseScore,
+		TypoEditPenalty:      DefaultTypoEditPenalty,
+		TypoLengthPenalty:    DefaultTypoLengthPenalty,
+		TypoThresholdDivisor: DefaultTypoThresholdDivisor,
+		MinTypoThreshold:     DefaultMinTypoThreshold,
+		TypoIndex:            true,
+		ParallelThreshold:    DefaultParallelThreshold,
+		ParallelMinTargets:   DefaultParallelMinTargets,
+		Algorithm:            AlgorithmGreedy,
+		GapStartPenalty:      DefaultGapStartPenalty,
+		GapExtendPenalty:     DefaultGapExtendPenalty,
+	}
+}
+
+// defaultConfig: Dùng cho các hàm package-level như FuzzyFind, không bao giờ bị sửa
+var defaultConfig = DefaultConfig()
+
+// ErrInvalidCursor: Cursor không decode được hoặc được tạo cho 1 query khác
+var ErrInvalidCursor = errors.New("fuzzyvn: invalid cursor")
+
+/*
+- SearchOptions: Tùy chọn phân trang cho SearchWithOptions
+- Limit: Số kết quả mỗi trang, <= 0 thì dùng DefaultLimit
+- Offset: Bỏ qua bao nhiêu kết quả đầu
+- Cursor: NextCursor của trang trước (opaque), nếu có thì Offset bị bỏ qua
+- UserID: Khác rỗng thì trộn cache riêng của user này với cache chung (xem CacheRegistry), rỗng = chỉ dùng cache chung
+*/
+type SearchOptions struct {
+	Limit  int
+	Offset int
+	Cursor string
+	UserID string
+}
+
+/*
+- SearchPage: 1 trang kết quả
+- Total: Tổng số kết quả khớp (tất cả các trang)
+- NextCursor: Truyền vào SearchOptions.Cursor để lấy trang tiếp, rỗng nếu đã hết
+*/
+type SearchPage struct {
+	Results    []MatchResult
+	Total      int
+	NextCursor string
+}
+
+// distanceBuf: Hàng DP và buffer decode của editDistance, mượn qua distancePool
+type distanceBuf struct {
+	rows           []int
+	bytes1, bytes2 []byte
+	runes1, runes2 []rune
+}
+
+var distancePool = sync.Pool{
 	New: func() interface{} {
-		s := make([]int, 0, 64)
-		return &s
+		return &distanceBuf{rows: make([]int, 0, 64)}
 	},
 }
 
@@ -136,47 +532,85 @@ func abs(x int) int {
 	return x
 }
 
+/*
+- isDone: Kiểm tra ctx.Done() mà không block
+- done == nil (context.Background) thì luôn false, không tốn select
+*/
+func isDone(done <-chan struct{}) bool {
+	if done == nil {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
 func isSeparator(r rune) bool {
 	// Liệt kê các ký tự ngăn cách phổ biến trong code/path
 	return r == '/' || r == '\\' || r == '_' || r == '-' || r == '.' || r == ' ' || r == ':'
 }
 
+/*
+- countWordMatches: Số từ của query khớp với 1 từ trong tên file (target), không phụ thuộc dấu cách
+- So trên tên file đã bỏ dấu ngăn cách (wordTarget): từ của query khớp nếu bắt đầu và kết thúc đúng ranh giới
+- Nên "bao cao" khớp 2 từ với "bao_cao.xlsx" lẫn "baocao.xlsx", "baocao" khớp 1 từ với "bao_cao.xlsx"
+- Từ ghép tiếng Việt viết liền trong query ("baocaotaichinh") tính theo từng âm tiết khớp (matchSyllables), giống như gõ có dấu cách
+- Không khớp thì cho phép 1 lỗi nếu từ >= 3 ký tự, so với từng từ của tên file
+*/
 func countWordMatches(queryWords []string, target string) int {
-	if len(target) < 2 {
-		return 0
-	}
-	targetWords := strings.Fields(target)
-	if len(targetWords) == 0 {
+	if len(target) < 2 || len(queryWords) == 0 {
 		return 0
 	}
+	wt := newWordTarget(target)
 	count := 0
 	for _, qWord := range queryWords {
 		if len(qWord) < 2 {
 			continue
 		}
-		for _, tWord := range targetWords {
-			if len(tWord) < 2 {
+		word := compactName(qWord)
+		if compoundSyllables(word) > 0 {
+			if matched := wt.matchSyllables(word); matched > 0 {
+				count += matched
 				continue
 			}
-			// Exact match
-			if qWord == tWord {
-				count++
-				break
-			}
-			// Fuzzy match: cho phép 1 lỗi nếu từ >= 3 ký tự
-			// CHỈ check Levenshtein nếu độ dài gần bằng nhau
-			if len(qWord) >= 3 && len(tWord) >= 3 && abs(len(qWord)-len(tWord)) <= 1 {
-				dist := LevenshteinRatio(qWord, tWord)
-				if dist <= 1 {
-					count++
-					break
-				}
-			}
+		}
+		if wt.contains(word) || typoWordMatch(word, target) {
+			count++
 		}
 	}
 	return count
 }
 
+/*
+- typoWordMatch: word khớp 1 từ của target (tách bằng isWordBoundary) với tối đa 1 lỗi, chỉ xét từ >= 3 ký tự
+- Đổi chỗ 2 ký tự liền nhau cũng tính 1 lỗi
+*/
+func typoWordMatch(word, target string) bool {
+	qRunes := utf8.RuneCountInString(word)
+	if qRunes < 3 {
+		return false
+	}
+	for rest := target; rest != ""; {
+		i := strings.IndexFunc(rest, isWordBoundary)
+		tWord := rest
+		if i >= 0 {
+			tWord = rest[:i]
+			_, size := utf8.DecodeRuneInString(rest[i:])
+			rest = rest[i+size:]
+		} else {
+			rest = ""
+		}
+		// Độ dài lệch nhau quá 1 ký tự thì BoundedDamerauLevenshtein trả về luôn, không phải tính
+		if utf8.RuneCountInString(tWord) >= 3 && BoundedDamerauLevenshtein(word, tWord, 1) <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
 func Normalize(s string) string {
 	// 1. FAST PATH: Nếu toàn là ASCII (Tiếng Anh, Code) -> Lowercase và trả về ngay
 	// Đây là trường hợp phổ biến nhất (90% file source code) -> Tốc độ siêu nhanh
@@ -206,65 +640,94 @@ func Normalize(s string) string {
 	// 4. MANUAL MAPPING: Duyệt từng rune và map thủ công
 	// Lưu ý: Không dùng range strings.ToLower(s) để tránh tạo string tạm
 	for _, r := range s {
-		// Lowercase từng ký tự
-		r = unicode.ToLower(r)
-
-		switch r {
-		case 'á', 'à', 'ả', 'ã', 'ạ', 'ă', 'ắ', 'ằ', 'ẳ', 'ẵ', 'ặ', 'â', 'ấ', 'ầ', 'ẩ', 'ẫ', 'ậ':
-			b.WriteRune('a')
-		case 'đ':
-			b.WriteRune('d')
-		case 'é', 'è', 'ẻ', 'ẽ', 'ẹ', 'ê', 'ế', 'ề', 'ể', 'ễ', 'ệ':
-			b.WriteRune('e')
-		case 'í', 'ì', 'ỉ', 'ĩ', 'ị':
-			b.WriteRune('i')
-		case 'ó', 'ò', 'ỏ', 'õ', 'ọ', 'ô', 'ố', 'ồ', 'ổ', 'ỗ', 'ộ', 'ơ', 'ớ', 'ờ', 'ở', 'ỡ', 'ợ':
-			b.WriteRune('o')
-		case 'ú', 'ù', 'ủ', 'ũ', 'ụ', 'ư', 'ứ', 'ừ', 'ử', 'ữ', 'ự':
-			b.WriteRune('u')
-		case 'ý', 'ỳ', 'ỷ', 'ỹ', 'ỵ':
-			b.WriteRune('y')
-		default:
-			// Giữ lại các ký tự ASCII (a-z, 0-9, symbol) và các ký tự Unicode khác không phải tiếng Việt
-			if r < 128 || unicode.IsLetter(r) || unicode.IsDigit(r) {
-				b.WriteRune(r)
-			}
+		if mapped, keep := foldRune(r); keep {
+			b.WriteRune(mapped)
 		}
 	}
 	return b.String()
 }
 
-func fastSubstring(s string, n int) string {
-	if len(s) <= n {
-		return s
+/*
+- foldRune: Lowercase + bỏ dấu tiếng Việt cho 1 rune, dùng chung cho Normalize và NormalizeWithOffsets
+- Trả về false nếu rune bị loại bỏ (ký tự đặc biệt không phải chữ/số)
+*/
+func foldRune(r rune) (rune, bool) {
+	// Lowercase từng ký tự
+	r = unicode.ToLower(r)
+
+	switch r {
+	case 'á', 'à', 'ả', 'ã', 'ạ', 'ă', 'ắ', 'ằ', 'ẳ', 'ẵ', 'ặ', 'â', 'ấ', 'ầ', 'ẩ', 'ẫ', 'ậ':
+		return 'a', true
+	case 'đ':
+		return 'd', true
+	case 'é', 'è', 'ẻ', 'ẽ', 'ẹ', 'ê', 'ế', 'ề', 'ể', 'ễ', 'ệ':
+		return 'e', true
+	case 'í', 'ì', 'ỉ', 'ĩ', 'ị':
+		return 'i', true
+	case 'ó', 'ò', 'ỏ', 'õ', 'ọ', 'ô', 'ố', 'ồ', 'ổ', 'ỗ', 'ộ', 'ơ', 'ớ', 'ờ', 'ở', 'ỡ', 'ợ':
+		return 'o', true
+	case 'ú', 'ù', 'ủ', 'ũ', 'ụ', 'ư', 'ứ', 'ừ', 'ử', 'ữ', 'ự':
+		return 'u', true
+	case 'ý', 'ỳ', 'ỷ', 'ỹ', 'ỵ':
+		return 'y', true
+	}
+	// Giữ lại các ký tự ASCII (a-z, 0-9, symbol) và các ký tự Unicode khác không phải tiếng Việt
+	if r < 128 || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return r, true
+	}
+	return r, false
+}
+
+/*
+- NormalizeWithOffsets: Giống Normalize nhưng trả thêm bảng offset
+- offsets[i] là byte offset trong chuỗi GỐC s của rune thứ i trong chuỗi đã normalize
+- Dùng để map vị trí match (tính trên chuỗi đã bỏ dấu) ngược về chuỗi gốc có dấu để highlight
+- Ví dụ: NormalizeWithOffsets("Báo") -> "bao", [0, 1, 3] (á chiếm 2 byte)
+- Với chuỗi NFD (macOS), các rune tổ hợp (a + dấu sắc) sẽ cùng trỏ về byte đầu của cụm
+*/
+func NormalizeWithOffsets(s string) (string, []int) {
+	isASCII := true
+	for i := 0; i < len(s); i++ {
+		if s[i] > 127 {
+			isASCII = false
+			break
+		}
+	}
+	if isASCII {
+		offsets := make([]int, len(s))
+		for i := range offsets {
+			offsets[i] = i
+		}
+		return strings.ToLower(s), offsets
 	}
 
-	count := 0
-	/*
-		Tại sao ta không dùng for i := 0; i < len(s); i++?
-		Hàm len(s) trả về số lượng BYTE, không phải số lượng KÝ TỰ
-		Ta có thể lợi dụng cách range hoạt động trong Go
-		range s trong Go sẽ tự động nhảy theo TỪNG KÝ TỰ (Rune), không phải từng byte
-	*/
-	for i := range s {
-		if count == n {
-			// Tại thời điểm này, 'i' đang đứng đúng ở vị trí byte kết thúc ký tự thứ n
-			// s[:i] là thao tác "Slice string"
-			// Nó KHÔNG copy dữ liệu, nó chỉ trỏ vào vùng nhớ cũ
-			return s[:i] // Cắt chuỗi tại byte index (Zero Alloc)
+	var b strings.Builder
+	b.Grow(len(s))
+	offsets := make([]int, 0, len(s))
+	emit := func(r rune, offset int) {
+		if mapped, keep := foldRune(r); keep {
+			b.WriteRune(mapped)
+			offsets = append(offsets, offset)
 		}
-		count++
 	}
-	/*
-		Ví dụ:
-		Chuỗi "âb" (giả sử â là 2 byte, b là 1 byte). Tổng 3 byte. Cần lấy 1 ký tự (n=1)
-		Vòng lặp chạy lần 1: Đọc chữ â
-		count tăng lên 1. count == n (1==1)
-		Biến i lúc này đang ở vị trí byte tiếp theo (tức là byte số 2).
-		return s[:2] -> Trả về đúng chữ â
-	*/
 
-	return s
+	if norm.NFC.IsNormalString(s) {
+		for i, r := range s {
+			emit(r, i)
+		}
+		return b.String(), offsets
+	}
+
+	// Chuỗi NFD: duyệt theo từng cụm NFC, Pos() cho biết cụm bắt đầu ở byte nào trong chuỗi gốc
+	var it norm.Iter
+	it.InitString(norm.NFC, s)
+	for !it.Done() {
+		start := it.Pos()
+		for _, r := range string(it.Next()) {
+			emit(r, start)
+		}
+	}
+	return b.String(), offsets
 }
 
 /*
@@ -279,21 +742,73 @@ func fastSubstring(s string, n int) string {
 > Nếu 2 ký tự giống nhau: Không mất phí (+0)
 > Nếu khác nhau: Thay ký tự này bằng ký tự kia (+1)
 NOTE: 1 điều lưu ý là ta không cần quan tâm chữ hoa, chữ thường vì đã chuẩn hóa rồi
+- Tính theo ký tự (rune) chứ không theo byte: sau khi chuẩn hóa vẫn còn chữ Hán, Cyrillic, Thái..., "中文" → "中国" là 1 lỗi chứ không phải 3
+- Đổi chỗ 2 ký tự liền nhau vẫn tính 2 lỗi ("main" → "mian" = 2), muốn tính 1 lỗi thì dùng DamerauLevenshtein
 */
-
 func LevenshteinRatio(s1, s2 string) int {
-	/*
-		Đây là trường hợp biến chuỗi s1 thành "chuỗi rỗng"
-		Ví dụ s1 = "ABC", s2 = ""
-		Biến "" thành "" mất 0 bước (column[0] = 0)
-		Biến "A" thành "" mất 1 bước xóa (column[1] = 1)
-		Biến "AB" thành "" mất 2 bước xóa (column[2] = 2)
-		Lúc này mảng column trông như thế này: [0, 1, 2, 3, ... len(s1)]
-		Tương ứng tăng dần từ 0 đến len(s1) là chi phí biến thành chuỗi rỗng
-	*/
+	return editDistance(s1, s2, -1, false)
+}
+
+/*
+- DamerauLevenshtein: Giống LevenshteinRatio nhưng có thêm lựa chọn thứ 4: đổi chỗ 2 ký tự liền nhau (Chi phí +1)
+- "main" → "mian" chỉ còn 1 lỗi thay vì 2, đây là lỗi gõ phím hay gặp nhất
+- Là bản Optimal String Alignment: 2 ký tự đã đổi chỗ thì không sửa thêm được nữa, nên "ca" → "abc" = 3 chứ không phải 2 (không ai gõ sai kiểu đó)
+*/
+func DamerauLevenshtein(s1, s2 string) int {
+	return editDistance(s1, s2, -1, true)
+}
+
+/*
+- BoundedDamerauLevenshtein: DamerauLevenshtein nhưng dừng sớm khi chắc chắn vượt maxDist
+- Trả về đúng khoảng cách nếu <= maxDist, ngược lại trả về maxDist + 1
+- Độ dài lệch nhau quá maxDist thì trả về luôn, không cần tính
+- Cả hàng DP đều > maxDist thì dừng: hàng sau không bao giờ nhỏ hơn giá trị nhỏ nhất của hàng trước
+- Search (TypoRanker), countWordMatches, querySimilarity chỉ cần biết khoảng cách có nằm trong ngưỡng không nên đều dùng hàm này
+*/
+func BoundedDamerauLevenshtein(s1, s2 string, maxDist int) int {
+	return editDistance(s1, s2, maxDist, true)
+}
+
+/*
+- editDistance: Phần chung của LevenshteinRatio, DamerauLevenshtein và BoundedDamerauLevenshtein
+- maxDist < 0: không giới hạn
+- transpose: có tính phép đổi chỗ 2 ký tự liền nhau không
+- Đa số chuỗi đã chuẩn hóa là ASCII: tính thẳng trên byte (1 byte = 1 ký tự), chỉ decode sang []rune khi cần
+*/
+func editDistance(s1, s2 string, maxDist int, transpose bool) int {
+	// Thay vì cấp phát mới (make) mỗi lần gọi, ta mượn buffer từ Pool
+	// Giúp giảm allocation
+	buf := distancePool.Get().(*distanceBuf)
+	var dist int
+	if isASCII(s1) && isASCII(s2) {
+		buf.bytes1 = append(buf.bytes1[:0], s1...)
+		buf.bytes2 = append(buf.bytes2[:0], s2...)
+		dist = editDistanceOf(buf, buf.bytes1, buf.bytes2, maxDist, transpose)
+	} else {
+		buf.runes1 = buf.runes1[:0]
+		for _, r := range s1 {
+			buf.runes1 = append(buf.runes1, r)
+		}
+		buf.runes2 = buf.runes2[:0]
+		for _, r := range s2 {
+			buf.runes2 = append(buf.runes2, r)
+		}
+		dist = editDistanceOf(buf, buf.runes1, buf.runes2, maxDist, transpose)
+	}
+	// Không dùng defer cho nhanh, hàm này bị gọi rất nhiều lần mỗi lần search
+	distancePool.Put(buf)
+	return dist
+}
+
+func editDistanceOf[T fuzzyChar](buf *distanceBuf, s1, s2 []T, maxDist int, transpose bool) int {
 	s1Len := len(s1)
 	s2Len := len(s2)
+	bounded := maxDist >= 0
 
+	// Mỗi ký tự dư ra phải tốn ít nhất 1 bước thêm/xóa
+	if bounded && abs(s1Len-s2Len) > maxDist {
+		return maxDist + 1
+	}
 	/*
 		Cái này hiểu đơn giản là
 		Ví dụ như: "" -> "ABC" thì ta lấy luôn độ dài s2
@@ -301,6 +816,7 @@ func LevenshteinRatio(s1, s2 string) int {
 		Vì rõ ràng số bước thay đổi từ rỗng thành text, hay text thành rỗng tốn số bước
 		đúng bằng độ dài của nó
 		Điều này giúp ta bỏ qua mấy bước bên dưới, làm tốn thêm phép toán và chậm đi chương trình
+		(bounded thì đã qua check độ dài ở trên nên chắc chắn <= maxDist)
 	*/
 	if s1Len == 0 {
 		return s2Len
@@ -308,31 +824,10 @@ func LevenshteinRatio(s1, s2 string) int {
 	if s2Len == 0 {
 		return s1Len
 	}
-	// Thay vì cấp phát mới (make) mỗi lần gọi, ta mượn slice từ Pool
-	// Giúp giảm allocation
-	ptr := intSlicePool.Get().(*[]int)
-	column := *ptr
-	// Kiểm tra sức chứa của slice mượn được
-	// Nếu slice mượn được quá bé không đủ chứa (s1Len + 1),
-	// bắt buộc phải cấp phát vùng nhớ mới to hơn.
-	if cap(column) < s1Len+1 {
-		column = make([]int, s1Len+1)
-	}
-	// Resize lại độ dài slice đúng bằng nhu cầu sử dụng
-	column = column[:s1Len+1]
-	// IMPORTAN: Trả lại slice về Pool sau khi tính toán xong.
-	// Phải gán lại *ptr = column phòng trường hợp slice bị tạo mới (re-allocated)
-	defer func() {
-		*ptr = column
-		intSlicePool.Put(ptr)
-	}()
 
-	for y := 1; y <= s1Len; y++ {
-		column[y] = y
-	}
 	/*
 			Ở đây mình sẽ giải thích sơ sơ
-			Thay vì dùng ma trận, mình dùng column như 1 stack từ trên xuống vậy, và ta sẽ ghi đè lên cái nào đã dùng
+			Thay vì dùng cả ma trận, mình chỉ giữ vài hàng cuối, hàng nào không cần nữa thì ghi đè lên
 			Chủ yếu để tiết kiệm 1 chút bộ nhớ thôi
 			Giờ nhìn ma trận trước
 		        /*
@@ -349,57 +844,76 @@ func LevenshteinRatio(s1, s2 string) int {
 			Kết quả tại ô "?" = 1
 			Vì ô ? = min(trên, trái, chéo trái) + 1 (+1 khi ta thấy được ký tự khác nhau)
 			Còn bạn nhìn vào ô (4,4) (C,C) ta thấy nó bằng 1 vì min(trên, trái, chéo trái) không + 1 vì C-C giống nhau
-			Bây giờ, hãy xem chuyện gì xảy ra khi ta ép cái bảng trên vào 1 mảng duy nhất (column)
-
+			Mỗi ô chỉ cần hàng ngay trên (prev) và chính hàng đang tính (column)
+			Riêng phép đổi chỗ cần thêm ô chéo cách 2 hàng 2 cột (prev2), nên giữ 3 hàng là đủ
 	*/
+	width := s1Len + 1
+	if cap(buf.rows) < 3*width {
+		buf.rows = make([]int, 3*width)
+	}
+	rows := buf.rows[:3*width]
+	prev2, prev, column := rows[:width], rows[width:2*width], rows[2*width:]
+	// Hàng đầu tiên: [0, 1, 2, 3, ... len(s1)], chi phí biến chuỗi rỗng thành từng đoạn đầu của s1
+	for j := range prev {
+		prev[j] = j
+	}
 
 	for i := 1; i <= s2Len; i++ {
-		column[0] = i    // Ví dụ: "" -> "A" (1 thêm), "" -> "AX" (2 thêm)
-		lastKey := i - 1 // Lưu giá trị cũ của ô chéo trên trái ta đã đề cập
+		column[0] = i // Ví dụ: "" -> "A" (1 thêm), "" -> "AX" (2 thêm)
+		rowMin := i
+		c := s2[i-1]
+		// Ký tự trước c trong s2, chỉ dùng cho phép đổi chỗ
+		swap := transpose && i > 1
+		var before T
+		if swap {
+			before = s2[i-2]
+		}
+		// Giữ ô trái và ô chéo trong biến thay vì đọc lại từ mảng
+		left := i
+		diag := prev[0]
 		for j := 1; j <= s1Len; j++ {
-			/*
-					IMPORTANT: Lưu lại giá trị cũ của column[j] trước khi bị ghi đè
-					column[j] lúc này đang chứa giá trị của hàng bên trên (i-1)
-					Sau khi vòng lặp này kết thúc, giá trị này sẽ trở thành
-				    ô chéo trên trái cho vòng lặp tiếp theo (j+1)
-			*/
-			oldKey := column[j]
 			/*
 							Tính toán chi phí biến đổi:
 
-									(lastKey)    (column[j] cũ)
+									 (diag)      (prev[j])
 				   					  CHÉO      |     TRÊN
 				    				   ↘        |      ↓
 				           					┌───────┐
 				  				   TRÁI ──→ │  ???  │  (Đang tính)
-				              (column[j-1]) └───────┘
-
-							NOTE: lastKey = column[j-1]
+				                   (left)   └───────┘
 			*/
-			var incr int
-			if s1[j-1] != s2[i-1] {
-				incr = 1 // Khác nhau: +1 bước thay thế, còn không thì thôi không cần cộng
-			}
-
-			// Và đây chính xác là cái min chúng ta đã làm ở trên: min(trên, trái, chéo trái)
-			// Xóa. Ví dụ: Name -> Nam
-			minVal := column[j] + 1
-			// Thêm. Ví dụ: Nam -> Name
-			if column[j-1]+1 < minVal {
-				minVal = column[j-1] + 1
-			}
-			// Sửa. Ví dụ: Năm -> Nấm
-			if lastKey+incr < minVal {
-				minVal = lastKey + incr
+			up := prev[j]
+			// Giống nhau thì ô chéo (+0) luôn là nhỏ nhất, không cần xét gì thêm
+			minVal := diag
+			if s1[j-1] != c {
+				// Và đây chính xác là cái min chúng ta đã làm ở trên: min(trên, trái, chéo trái) + 1
+				// Xóa (trên). Ví dụ: Name -> Nam
+				// Thêm (trái). Ví dụ: Nam -> Name
+				// Sửa (chéo). Ví dụ: Năm -> Nấm
+				minVal = min(up, left, diag) + 1
+				// Đổi chỗ. Ví dụ: mian -> main, lấy ô cách 2 hàng 2 cột rồi +1
+				if swap && j > 1 && s1[j-1] == before && s1[j-2] == c && prev2[j-2]+1 < minVal {
+					minVal = prev2[j-2] + 1
+				}
 			}
 			column[j] = minVal
-			// Giá trị Trên của ô hiện tại (oldKey) sẽ trở thành
-			// giá trị Chéo của ô bên phải
-			lastKey = oldKey
+			rowMin = min(rowMin, minVal)
+			// Giá trị Trên của ô hiện tại sẽ trở thành giá trị Chéo của ô bên phải
+			left, diag = minVal, up
 		}
+		// Mọi ô của hàng sau đều đi ra từ 1 ô của hàng này (hoặc hàng trước nó) cộng thêm >= 0, nên không thể xuống dưới rowMin
+		if bounded && rowMin > maxDist {
+			return maxDist + 1
+		}
+		// Xoay vòng 3 hàng: hàng vừa tính thành hàng trên, hàng cũ nhất bị ghi đè ở vòng sau
+		prev2, prev, column = prev, column, prev2
+	}
+	// Trả về chi phí cuối dựa trên độ dài s1 (phần tử cuối của hàng cuối). Đọc tới đây mà không hiểu thì hãy xem lại ma trận
+	dist := prev[s1Len]
+	if bounded && dist > maxDist {
+		return maxDist + 1
 	}
-	// Trả về chi phí cuối dựa trên độ dài s1 (phần tử cuối). Đọc tới đây mà không hiểu thì hãy xem lại ma trận
-	return column[s1Len]
+	return dist
 }
 
 /*
@@ -417,17 +931,29 @@ func isWordBoundary(r rune) bool {
 fuzzyScoreGreedy: Tính điểm fuzzy match sử dụng thuật toán tham lam
 - pattern: Query đã normalize
 - target: Target string đã normalize
-- Trả về: (matched bool, score int, positions []int)
+- Trả về: (score int, matched bool)
 - Cách này có 1 vấn đề nho nhỏ, là do tham lam
 - Nó có thể bỏ qua một match tốt hơn ở sau để chọn match đầu tiên tìm được
 - Nhưng bù lại cực nhanh vì chỉ duyệt target 1 lần
 */
 func fuzzyScoreGreedy(pattern []rune, target []rune) (int, bool) {
+	score, _, matched := fuzzyScoreGreedyPositions(&defaultConfig, pattern, target, nil)
+	return score, matched
+}
+
+/*
+fuzzyScoreGreedyPositions: Giống fuzzyScoreGreedy nhưng ghi lại vị trí khớp
+- cfg: Trọng số điểm (WordStartBonus, MatchBonus, ConsecutiveBonus, LengthPenalty)
+- positions: Buffer để append vị trí (rune index trong target), truyền nil nếu không cần
+- Trả về: (score int, positions []int, matched bool)
+- Generic theo fuzzyChar: target thuần ASCII chấm thẳng trên []byte (targetArena), index byte cũng là index rune
+*/
+func fuzzyScoreGreedyPositions[T fuzzyChar](cfg *Config, pattern []T, target []T, positions []int) (int, []int, bool) {
 	lenP := len(pattern)
 	lenT := len(target)
 
 	if lenP > lenT {
-		return 0, false
+		return 0, positions, false
 	}
 
 	totalScore := 0
@@ -467,23 +993,23 @@ func fuzzyScoreGreedy(pattern []rune, target []rune) (int, bool) {
 				} else {
 					prevChar := target[t-1]
 					// Check separator: dấu cách, _, -, /, .
-					if isSeparator(prevChar) {
+					if isSeparator(rune(prevChar)) {
 						isWordStart = true
-					} else if unicode.IsLower(prevChar) && unicode.IsUpper(tChar) {
+					} else if unicode.IsLower(rune(prevChar)) && unicode.IsUpper(rune(tChar)) {
 						// CamelCase (aB) -> B là đầu từ
 						isWordStart = true
 					}
 				}
 
 				if isWordStart {
-					score += 80 // Thưởng đậm cho đầu từ
+					score += cfg.WordStartBonus // Thưởng đậm cho đầu từ
 				} else {
-					score += 10 // Điểm cơ bản
+					score += cfg.MatchBonus // Điểm cơ bản
 				}
 
 				// Thưởng liền kề
 				if prevMatchIdx != -1 && t == prevMatchIdx+1 {
-					score += 40 // Thưởng cho việc gõ liền mạch
+					score += cfg.ConsecutiveBonus // Thưởng cho việc gõ liền mạch
 				}
 
 				// Phạt khoảng cách
@@ -510,12 +1036,15 @@ func fuzzyScoreGreedy(pattern []rune, target []rune) (int, bool) {
 		}
 
 		if !found {
-			return 0, false // Không tìm thấy ký tự pattern
+			return 0, positions, false // Không tìm thấy ký tự pattern
 		}
 
 		// Chốt phương án cho ký tự pattern này
 		totalScore += bestScore
 		prevMatchIdx = bestIdx
+		if positions != nil {
+			positions = append(positions, bestIdx)
+		}
 
 		// Ký tự tiếp theo của pattern phải tìm sau vị trí này
 		targetIdx = bestIdx + 1
@@ -523,9 +1052,163 @@ func fuzzyScoreGreedy(pattern []rune, target []rune) (int, bool) {
 
 	// Phạt độ dài (để ưu tiên chuỗi ngắn hơn khi cùng điểm match)
 	// Ví dụ search "app" thì "App" (3) ngon hơn "Application" (11)
-	totalScore -= (lenT - lenP)
+	totalScore -= (lenT - lenP) * cfg.LengthPenalty
+
+	return totalScore, positions, true
+}
+
+// scoreFunc: Chữ ký chung của fuzzyScoreGreedyPositions, fuzzyScoreOptimal và fuzzyScore
+type scoreFunc func(cfg *Config, pattern []rune, target []rune, positions []int) (int, []int, bool)
+
+/*
+fuzzyScore: Chọn thuật toán theo cfg.Algorithm
+*/
+func fuzzyScore[T fuzzyChar](cfg *Config, pattern []T, target []T, positions []int) (int, []int, bool) {
+	return scoreWith(cfg, cfg.Algorithm, pattern, target, positions)
+}
+
+/*
+isWordStartAt: Ký tự target[t] có phải đầu từ không (giống logic trong fuzzyScoreGreedy)
+*/
+func isWordStartAt[T fuzzyChar](target []T, t int) bool {
+	if t == 0 {
+		return true
+	}
+	prev := rune(target[t-1])
+	return isSeparator(prev) || (unicode.IsLower(prev) && unicode.IsUpper(rune(target[t])))
+}
+
+var dpPool = sync.Pool{
+	New: func() interface{} {
+		s := make([]int, 0, 1024)
+		return &s
+	},
+}
+
+// negInf: Ô không hợp lệ trong bảng DP, chia 2 để cộng trừ thêm không bị tràn số
+const negInf = math.MinInt / 2
+
+/*
+fuzzyScoreOptimal: Tính điểm fuzzy bằng quy hoạch động (kiểu Smith-Waterman / fzf v2)
+  - Khác với greedy, hàm này xét MỌI cách đặt pattern vào target và chọn cách cho điểm cao nhất
+  - Cùng hệ điểm với greedy (WordStartBonus, MatchBonus, ConsecutiveBonus, LengthPenalty)
+  - Thêm phạt khoảng trống giữa 2 ký tự khớp: GapStartPenalty + GapExtendPenalty * (độ dài gap - 1)
+  - Ví dụ pattern "abc", target "a_xbc_abc":
+    Greedy chốt a ở vị trí 0 (đầu chuỗi) rồi buộc phải lấy "bc" ở giữa -> 134 điểm
+    Optimal thấy "abc" liền mạch ở cuối (a là đầu từ sau "_") -> 174 điểm
+  - Bảng DP:
+    M[i][j] = điểm tốt nhất khi pattern[0..i] đã khớp xong và pattern[i] khớp đúng target[j]
+    M[i][j] = bonus(j) + max(M[i-1][j-1] + ConsecutiveBonus, H[j])
+    H[j] = max(M[i-1][k] - gap(j-k-1)) với k < j-1, tính cuốn chiếu: H[j] = max(H[j-1] - GapExtendPenalty, M[i-1][j-2] - GapStartPenalty)
+  - Độ phức tạp O(len(pattern) * len(target)) cả thời gian lẫn bộ nhớ (cần cả bảng để truy vết positions)
+  - Chậm hơn greedy nhiều, nên mặc định chỉ dùng để chấm lại top candidates (Config.OptimalRerank)
+*/
+func fuzzyScoreOptimal[T fuzzyChar](cfg *Config, pattern []T, target []T, positions []int) (int, []int, bool) {
+	lenP := len(pattern)
+	lenT := len(target)
+	if lenP == 0 || lenP > lenT {
+		return 0, positions, false
+	}
+
+	// Loại nhanh: pattern phải là subsequence của target, không thì khỏi dựng bảng
+	pIdx := 0
+	for t := 0; t < lenT && pIdx < lenP; t++ {
+		if target[t] == pattern[pIdx] {
+			pIdx++
+		}
+	}
+	if pIdx < lenP {
+		return 0, positions, false
+	}
+
+	ptr := dpPool.Get().(*[]int)
+	m := *ptr
+	if cap(m) < lenP*lenT {
+		m = make([]int, lenP*lenT)
+	}
+	m = m[:lenP*lenT]
+	defer func() {
+		*ptr = m
+		dpPool.Put(ptr)
+	}()
+
+	bonus := func(t int) int {
+		if isWordStartAt(target, t) {
+			return cfg.WordStartBonus
+		}
+		return cfg.MatchBonus
+	}
+
+	// Hàng đầu tiên: không phạt khoảng trống trước ký tự khớp đầu tiên (giống fzf)
+	for t := range lenT {
+		if target[t] == pattern[0] {
+			m[t] = bonus(t)
+		} else {
+			m[t] = negInf
+		}
+	}
+
+	for i := 1; i < lenP; i++ {
+		prev := m[(i-1)*lenT : i*lenT]
+		row := m[i*lenT : (i+1)*lenT]
+		gapBest := negInf
+		for t := range lenT {
+			if t >= 2 {
+				gapBest = max(gapBest-cfg.GapExtendPenalty, prev[t-2]-cfg.GapStartPenalty)
+			}
+			if t < i || target[t] != pattern[i] {
+				row[t] = negInf
+				continue
+			}
+			best := gapBest
+			if prev[t-1] > negInf {
+				best = max(best, prev[t-1]+cfg.ConsecutiveBonus)
+			}
+			if best <= negInf/2 {
+				row[t] = negInf
+				continue
+			}
+			row[t] = bonus(t) + best
+		}
+	}
+
+	last := m[(lenP-1)*lenT:]
+	bestIdx := -1
+	for t := range lenT {
+		if last[t] > negInf && (bestIdx == -1 || last[t] > last[bestIdx]) {
+			bestIdx = t
+		}
+	}
+	if bestIdx == -1 {
+		return 0, positions, false
+	}
+	score := last[bestIdx] - (lenT-lenP)*cfg.LengthPenalty
 
-	return totalScore, true
+	if positions != nil {
+		// Truy vết ngược từ ô tốt nhất của hàng cuối
+		start := len(positions)
+		positions = append(positions, make([]int, lenP)...)
+		t := bestIdx
+		for i := lenP - 1; i >= 0; i-- {
+			positions[start+i] = t
+			if i == 0 {
+				break
+			}
+			cur := m[i*lenT+t] - bonus(t)
+			prev := m[(i-1)*lenT : i*lenT]
+			if prev[t-1] > negInf && prev[t-1]+cfg.ConsecutiveBonus == cur {
+				t--
+				continue
+			}
+			for k := t - 2; k >= 0; k-- {
+				if prev[k] > negInf && prev[k]-cfg.GapStartPenalty-cfg.GapExtendPenalty*(t-k-2) == cur {
+					t = k
+					break
+				}
+			}
+		}
+	}
+	return score, positions, true
 }
 
 /*
@@ -538,39 +1221,66 @@ FuzzyFind: Tìm tất cả targets khớp với pattern
 - Xong sort theo score giảm dần
 */
 func FuzzyFind(pattern string, targets []string) []FuzzyMatch {
-	patternRunes := []rune(Normalize(pattern)) // 1 alloc
-	if len(patternRunes) == 0 {
+	return fuzzyFind(context.Background(), &defaultConfig, pattern, targets, nil, false)
+}
+
+/*
+FuzzyFindWithPositions: Giống FuzzyFind nhưng mỗi FuzzyMatch có thêm Positions
+- Positions là rune index trong target (chuỗi đã normalize), dùng để highlight
+- Chậm hơn FuzzyFind một chút vì phải cấp phát slice vị trí cho mỗi kết quả
+*/
+func FuzzyFindWithPositions(pattern string, targets []string) []FuzzyMatch {
+	return fuzzyFind(context.Background(), &defaultConfig, pattern, targets, nil, true)
+}
+
+/*
+- fuzzyFind: Bản dùng chung của FuzzyFind/FuzzyFindWithPositions
+- ctx bị hủy thì dừng giữa chừng và trả về những gì đã khớp được (vẫn sort)
+- ix: Snapshot sở hữu targets (targets = ix.normalized), nil với FuzzyFind trên []string bất kỳ
+- Có ix thì chấm trên target đã decode sẵn (targetArena) và bỏ qua target thiếu ký tự của pattern nếu bật Config.Prefilter (masks)
+- Không có ix thì decode từng target qua targetRunePool (scoreDecoded)
+*/
+func fuzzyFind(ctx context.Context, cfg *Config, pattern string, targets []string, ix *searchIndex, withPositions bool) []FuzzyMatch {
+	p := newFuzzyPattern(Normalize(pattern))
+	if len(p.runes) == 0 {
 		return nil
 	}
+	var masks []charMask
+	if ix != nil {
+		masks = ix.masks
+	}
+	patternMask := maskOf(string(p.runes), -1)
 	// Pre-allocate slice kết quả để tránh resize liên tục
 	results := make([]FuzzyMatch, 0, 1000)
+	done := ctx.Done()
 
 	for idx, targetStr := range targets {
-
-		// mượn buffer
-		ptr := targetRunePool.Get().(*[]rune)
-
-		// Ta clear buffer cũ, sau đó append từng rune của target vào
-		targetRunes := *ptr
-		targetRunes = targetRunes[:0]
-		for _, r := range targetStr {
-			targetRunes = append(targetRunes, r)
+		if idx%ctxCheckInterval == 0 && isDone(done) {
+			break
+		}
+		if masks != nil && patternMask&^masks[idx].all != 0 {
+			continue
 		}
 
-		score, matched := fuzzyScoreGreedy(patternRunes, targetRunes)
+		var positions []int
+		if withPositions {
+			positions = make([]int, 0, len(p.runes))
+		}
+		var score int
+		var matched bool
+		if ix != nil {
+			score, positions, matched = ix.targets.score(cfg, cfg.Algorithm, &p, idx, positions)
+		} else {
+			score, positions, matched = scoreDecoded(cfg, p.runes, targetStr, positions)
+		}
 
 		if matched {
 			results = append(results, FuzzyMatch{
-				Index: idx,
-				Score: score,
+				Index:     idx,
+				Score:     score,
+				Positions: positions,
 			})
 		}
-
-		// IMPORTANT: TRẢ BUFFER VỀ POOL
-		// Vì targetRunes là slice header mới trỏ vào mảng nền của ptr
-		// Nên ta put cái mảng nền (đã mở rộng capacity nếu cần) lại vào pool
-		*ptr = targetRunes
-		targetRunePool.Put(ptr)
 	}
 	// Sort by score descending
 	sort.Slice(results, func(i, j int) bool {
@@ -580,28 +1290,72 @@ func FuzzyFind(pattern string, targets []string) []FuzzyMatch {
 	return results
 }
 
+/*
+- scoreDecoded: Chấm 1 target dạng string, decode sang []rune bằng buffer mượn từ targetRunePool
+- Dùng cho FuzzyFind trên []string bất kỳ, Searcher thì đã có targetArena decode sẵn
+*/
+func scoreDecoded(cfg *Config, pattern []rune, target string, positions []int) (int, []int, bool) {
+	// mượn buffer
+	ptr := targetRunePool.Get().(*[]rune)
+
+	// Ta clear buffer cũ, sau đó append từng rune của target vào
+	targetRunes := (*ptr)[:0]
+	for _, r := range target {
+		targetRunes = append(targetRunes, r)
+	}
+	score, positions, matched := fuzzyScore(cfg, pattern, targetRunes, positions)
+
+	// IMPORTANT: TRẢ BUFFER VỀ POOL
+	// Vì targetRunes là slice header mới trỏ vào mảng nền của ptr
+	// Nên ta put cái mảng nền (đã mở rộng capacity nếu cần) lại vào pool
+	*ptr = targetRunes
+	targetRunePool.Put(ptr)
+	return score, positions, matched
+}
+
 /*
 FuzzyFindParallel: Version parallel của FuzzyFind
 - OK giờ bạn sẽ thắc mắc như này: "Tại sao lại cần FuzzyFind khi đã có parrallel version?"
 - Lý do chính là để giảm thiểu chi phí overhead khi xử lý các tập dữ liệu nhỏ
-- Bởi vậy nên đoạn ở dưới mới có if numTargets < 1000 thì dùng FuzzyFind đó
-- Dưới 1000 files dùng FuzzyFind thay vì FuzzyFindParallel để tránh overhead, vẫn đảm bảo tốc độ
+- Bởi vậy nên đoạn ở dưới mới có if numTargets < cfg.ParallelMinTargets thì dùng FuzzyFind đó
+- Dưới ngưỡng này (mặc định 2000) dùng FuzzyFind thay vì FuzzyFindParallel để tránh overhead, vẫn đảm bảo tốc độ
 Sử dụng goroutines để tăng tốc với datasets lớn
 - pattern: Query string
 - targets: Danh sách strings để search
 - Trả về: Slice of FuzzyMatch, sorted by score descending
 */
 func FuzzyFindParallel(pattern string, targets []string) []FuzzyMatch {
-	patternRunes := []rune(pattern)
-	if len(patternRunes) == 0 {
+	return fuzzyFindParallel(context.Background(), &defaultConfig, pattern, targets, nil, false)
+}
+
+/*
+FuzzyFindParallelWithPositions: Version parallel của FuzzyFindWithPositions
+*/
+func FuzzyFindParallelWithPositions(pattern string, targets []string) []FuzzyMatch {
+	return fuzzyFindParallel(context.Background(), &defaultConfig, pattern, targets, nil, true)
+}
+
+/*
+- fuzzyFindParallel: Bản dùng chung của FuzzyFindParallel/FuzzyFindParallelWithPositions
+- ctx bị hủy thì mọi worker thoát sớm, kết quả là phần các worker đã kịp chấm
+- ix: Giống fuzzyFind
+*/
+func fuzzyFindParallel(ctx context.Context, cfg *Config, pattern string, targets []string, ix *searchIndex, withPositions bool) []FuzzyMatch {
+	p := newFuzzyPattern(pattern)
+	if len(p.runes) == 0 {
 		return nil
 	}
 
 	numTargets := len(targets)
-	// Chỉ dùng parallel nếu dataset lớn
-	if numTargets < 2000 {
-		return FuzzyFind(pattern, targets)
+	// Chỉ dùng parallel nếu dataset lớn (mặc định 2000)
+	if numTargets < cfg.ParallelMinTargets {
+		return fuzzyFind(ctx, cfg, pattern, targets, ix, withPositions)
 	}
+	var masks []charMask
+	if ix != nil {
+		masks = ix.masks
+	}
+	patternMask := maskOf(pattern, -1)
 
 	/*
 		Thường đúng ra thì để tận dụng tối đa nên dùng công thức: workers = tổng số luồng
@@ -642,6 +1396,7 @@ func FuzzyFindParallel(pattern string, targets []string) []FuzzyMatch {
 	*/
 	chunkSize := (numTargets + numWorkers - 1) / numWorkers
 	resultChan := make(chan []FuzzyMatch, numWorkers)
+	done := ctx.Done()
 
 	var wg sync.WaitGroup
 	for w := range numWorkers {
@@ -660,21 +1415,31 @@ func FuzzyFindParallel(pattern string, targets []string) []FuzzyMatch {
 			localResults := make([]FuzzyMatch, 0, (end-start)/5)
 
 			for i := start; i < end; i++ {
-				ptr := targetRunePool.Get().(*[]rune)
-				targetRunes := *ptr
-				targetRunes = targetRunes[:0]
-				for _, r := range targets[i] {
-					targetRunes = append(targetRunes, r)
+				// Query đã cũ (user gõ tiếp) thì thoát luôn, không chấm nốt phần còn lại
+				if (i-start)%ctxCheckInterval == 0 && isDone(done) {
+					break
+				}
+				if masks != nil && patternMask&^masks[i].all != 0 {
+					continue
+				}
+				var positions []int
+				if withPositions {
+					positions = make([]int, 0, len(p.runes))
+				}
+				var score int
+				var matched bool
+				if ix != nil {
+					score, positions, matched = ix.targets.score(cfg, cfg.Algorithm, &p, i, positions)
+				} else {
+					score, positions, matched = scoreDecoded(cfg, p.runes, targets[i], positions)
 				}
-				score, matched := fuzzyScoreGreedy(patternRunes, targetRunes)
 				if matched {
 					localResults = append(localResults, FuzzyMatch{
-						Index: i,
-						Score: score,
+						Index:     i,
+						Score:     score,
+						Positions: positions,
 					})
 				}
-				*ptr = targetRunes
-				targetRunePool.Put(ptr)
 			}
 
 			resultChan <- localResults
@@ -762,19 +1527,10 @@ func (c *QueryCache) querySimilarity(q1, q2 string) int {
 	/*
 		Sai chính tả
 	*/
-	if len(q1) >= 3 && len(q2) >= 3 {
-		// Tính khoảng cách Levenshtein
-		dist := LevenshteinRatio(q1, q2)
-		maxLen := len(q1)
-		if len(q2) > maxLen {
-			maxLen = len(q2)
-		}
-		// Tính ngưỡng sai số cho phép (threshold): Khoảng 30% độ dài chuỗi dài nhất
-		// Ví dụ chuỗi dài 10 ký tự thì cho phép sai tối đa 3 lỗi
-		threshold := maxLen * 30 / 100
-		if threshold < 2 {
-			threshold = 2
-		}
+	if n1, n2 := utf8.RuneCountInString(q1), utf8.RuneCountInString(q2); n1 >= 3 && n2 >= 3 {
+		// Tính khoảng cách Damerau-Levenshtein theo ký tự, vượt ngưỡng thì dừng sớm
+		threshold := queryTypoThreshold(max(n1, n2))
+		dist := BoundedDamerauLevenshtein(q1, q2, threshold)
 		/*
 			Nếu số lỗi nằm trong ngưỡng cho phép: Trả về 60 trừ đi điểm phạt (mỗi lỗi trừ 10 điểm)
 			Ví dụ:
@@ -790,6 +1546,15 @@ func (c *QueryCache) querySimilarity(q1, q2 string) int {
 	return 0
 }
 
+/*
+- queryTypoThreshold: Ngưỡng sai số cho phép (threshold) của querySimilarity: Khoảng 30% độ dài chuỗi dài nhất, tối thiểu 2
+- Ví dụ chuỗi dài 10 ký tự thì cho phép sai tối đa 3 lỗi
+- queryIndex dùng chung ngưỡng này để lọc ứng viên
+*/
+func queryTypoThreshold(maxLen int) int {
+	return max(maxLen*30/100, 2)
+}
+
 //
 /*
 - moveToFront: Đẩy query lên đầu danh sách queryOrder
@@ -807,13 +1572,161 @@ func (c *QueryCache) moveToFront(query string) {
 }
 
 /*
-- evictIfNeeded: Xóa query cũ nhất nếu vượt giới hạn maxQueries
+- frecency: Điểm "phổ biến + gần đây" của 1 entry tại thời điểm now
+- Mỗi lần chọn đóng góp 2^(-tuổi/halfLife): vừa chọn = 1, chọn cách đây 1 halfLife = 0.5, 2 halfLife = 0.25...
+- Chỉ giữ frecencySamples lần chọn gần nhất (giống Firefox), lấy trung bình rồi nhân SelectCount
+- File chọn rất nhiều lần vẫn có lợi thế, nhưng lợi thế đó phai dần nếu lâu rồi không chọn lại
+- halfLife <= 0 hoặc entry không có thời gian (file cache cũ) thì frecency = SelectCount như trước
 */
-func (c *QueryCache) evictIfNeeded() {
-	for len(c.queryOrder) > c.maxQueries {
-		oldestQuery := c.queryOrder[0]
+func (c *QueryCache) frecency(e *CacheEntry, now time.Time) float64 {
+	if c.halfLife <= 0 || len(e.selectedAt) == 0 {
+		return float64(e.SelectCount)
+	}
+	var sum float64
+	for _, t := range e.selectedAt {
+		age := max(now.Sub(t), 0)
+		sum += math.Exp2(-float64(age) / float64(c.halfLife))
+	}
+	return float64(e.SelectCount) * sum / float64(len(e.selectedAt))
+}
+
+// touch: Ghi nhận 1 lần chọn tại thời điểm t
+func (e *CacheEntry) touch(t time.Time) {
+	e.SelectCount++
+	e.LastSelected = t
+	e.selectedAt = append(e.selectedAt, t)
+	if len(e.selectedAt) > frecencySamples {
+		e.selectedAt = append(e.selectedAt[:0:0], e.selectedAt[len(e.selectedAt)-frecencySamples:]...)
+	}
+}
+
+/*
+- merge: Gộp lịch sử chọn của other vào e (dùng khi RenamePath gộp 2 file)
+- Cộng SelectCount, trộn thời gian theo thứ tự rồi giữ frecencySamples lần gần nhất
+*/
+func (e *CacheEntry) merge(other CacheEntry) {
+	e.SelectCount += other.SelectCount
+	if other.LastSelected.After(e.LastSelected) {
+		e.LastSelected = other.LastSelected
+	}
+	times := append(append([]time.Time(nil), e.selectedAt...), other.selectedAt...)
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	if len(times) > frecencySamples {
+		times = times[len(times)-frecencySamples:]
+	}
+	e.selectedAt = times
+}
+
+// clock: Thời điểm hiện tại theo đồng hồ của cache, luôn ở UTC và không mang monotonic clock để lưu/so sánh ổn định
+func (c *QueryCache) clock() time.Time {
+	return c.now().UTC()
+}
+
+/*
+- removeEntry: Xóa lượt chọn filePath khỏi query (dùng khi user từ chối file đó)
+- Query không còn entry nào thì xóa luôn khỏi entries và queryOrder
+*/
+func (c *QueryCache) removeEntry(queryNorm, filePath string) {
+	entries := c.entries[queryNorm]
+	for i, e := range entries {
+		if e.FilePath != filePath {
+			continue
+		}
+		entries = append(entries[:i], entries[i+1:]...)
+		if len(entries) > 0 {
+			c.entries[queryNorm] = entries
+			return
+		}
+		delete(c.entries, queryNorm)
+		c.index.remove(queryNorm)
+		c.queryOrder = removeString(c.queryOrder, queryNorm)
+		return
+	}
+}
+
+/*
+- forEachCandidate: Gọi fn cho các query trong cache có thể giống queryNorm (lọc qua queryIndex)
+- Index không lọc được gì (quá nửa cache là ứng viên) thì quét toàn bộ entries như cũ
+*/
+func (c *QueryCache) forEachCandidate(queryNorm string, fn func(cachedQuery string, entries []CacheEntry)) {
+	selective := c.index.candidates(queryNorm, func(cachedQuery string) {
+		fn(cachedQuery, c.entries[cachedQuery])
+	})
+	if !selective {
+		for cachedQuery, entries := range c.entries {
+			fn(cachedQuery, entries)
+		}
+	}
+}
+
+// forEachRejection: Như forEachCandidate nhưng cho rejections (lọc qua rejectIndex)
+func (c *QueryCache) forEachRejection(queryNorm string, fn func(rejectedQuery string, paths []string)) {
+	selective := c.rejectIndex.candidates(queryNorm, func(rejectedQuery string) {
+		fn(rejectedQuery, c.rejections[rejectedQuery])
+	})
+	if !selective {
+		for rejectedQuery, paths := range c.rejections {
+			fn(rejectedQuery, paths)
+		}
+	}
+}
+
+// removeString: Xóa phần tử s khỏi list (giữ thứ tự), không có thì trả về list như cũ
+func removeString(list []string, s string) []string {
+	for i, v := range list {
+		if v == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// setList: Gán list cho key, list rỗng thì xóa key để map không giữ query rác
+func setList(m map[string][]string, key string, list []string) {
+	if len(list) == 0 {
+		delete(m, key)
+		return
+	}
+	m[key] = list
+}
+
+/*
+- touchFeedback: Cập nhật feedbackOrder và rejectIndex sau khi từ chối/ghim của queryNorm thay đổi
+- Query còn từ chối hoặc ghim thì đẩy xuống cuối (mới nhất), hết cả 2 thì bỏ khỏi danh sách
+*/
+func (c *QueryCache) touchFeedback(queryNorm string) {
+	if len(c.rejections[queryNorm]) > 0 {
+		c.rejectIndex.add(queryNorm)
+	} else {
+		c.rejectIndex.remove(queryNorm)
+	}
+	c.feedbackOrder = removeString(c.feedbackOrder, queryNorm)
+	if len(c.rejections[queryNorm]) > 0 || len(c.pins[queryNorm]) > 0 {
+		c.feedbackOrder = append(c.feedbackOrder, queryNorm)
+	}
+	c.evictFeedback()
+}
+
+// evictFeedback: Xóa từ chối và ghim của query cũ nhất nếu vượt giới hạn maxFeedback
+func (c *QueryCache) evictFeedback() {
+	for len(c.feedbackOrder) > c.maxFeedback {
+		oldestQuery := c.feedbackOrder[0]
+		c.feedbackOrder = c.feedbackOrder[1:]
+		delete(c.rejections, oldestQuery)
+		delete(c.pins, oldestQuery)
+		c.rejectIndex.remove(oldestQuery)
+	}
+}
+
+/*
+- evictIfNeeded: Xóa query cũ nhất nếu vượt giới hạn maxQueries
+*/
+func (c *QueryCache) evictIfNeeded() {
+	for len(c.queryOrder) > c.maxQueries {
+		oldestQuery := c.queryOrder[0]
 		c.queryOrder = c.queryOrder[1:]
 		delete(c.entries, oldestQuery)
+		c.index.remove(oldestQuery)
 	}
 }
 
@@ -829,6 +1742,16 @@ func NewQueryCache() *QueryCache {
 		maxQueries:  100,
 		maxPerQuery: 5,
 		boostScore:  5000,
+		halfLife:    DefaultHalfLife,
+		now:         time.Now,
+		index:       newQueryIndex(),
+
+		rejectPenalty: DefaultRejectPenalty,
+		rejections:    make(map[string][]string),
+		pins:          make(map[string][]string),
+		maxFeedback:   DefaultMaxFeedback,
+		feedbackOrder: make([]string, 0),
+		rejectIndex:   newQueryIndex(),
 	}
 }
 
@@ -839,6 +1762,32 @@ func (c *QueryCache) SetMaxQueries(n int) {
 	defer c.mu.Unlock()
 	c.maxQueries = n
 	c.evictIfNeeded()
+	c.dirty.Store(true)
+}
+
+/*
+- SetHalfLife: Đặt half-life cho frecency, mặc định DefaultHalfLife (30 ngày)
+- Sau mỗi halfLife không được chọn lại, điểm boost của 1 file giảm một nửa
+- d <= 0: Tắt phân rã, boost chỉ dựa vào SelectCount như phiên bản cũ
+*/
+func (c *QueryCache) SetHalfLife(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.halfLife = max(d, 0)
+	c.dirty.Store(true)
+}
+
+/*
+- SetClock: Thay đồng hồ của cache, nil = time.Now
+- Chủ yếu để test phân rã theo thời gian mà không phải chờ thật
+*/
+func (c *QueryCache) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now == nil {
+		now = time.Now
+	}
+	c.now = now
 }
 
 // SetBoostScore: Đặt điểm boost cơ bản cho kết quả từ cache
@@ -846,6 +1795,7 @@ func (c *QueryCache) SetBoostScore(score int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.boostScore = score
+	c.dirty.Store(true)
 }
 
 /*
@@ -863,6 +1813,13 @@ func (c *QueryCache) RecordSelection(query, filePath string) {
 	// Chuẩn hóa query, xóa hết dấu, xóa hết các ký tự viết hoa
 	// Ví dụ: Cộng đồng Golang Việt Nam -> cong dong golang viet nam
 	queryNorm := Normalize(query)
+	now := c.clock()
+	c.dirty.Store(true)
+	// Chọn lại file từng bị từ chối nghĩa là user đổi ý
+	if slices.Contains(c.rejections[queryNorm], filePath) {
+		setList(c.rejections, queryNorm, removeString(c.rejections[queryNorm], filePath))
+		c.touchFeedback(queryNorm)
+	}
 
 	// Phải ưu tiên kiểm tra trong cache trước rồi mới tới các bước tiếp theo
 	entries, exists := c.entries[queryNorm]
@@ -870,7 +1827,7 @@ func (c *QueryCache) RecordSelection(query, filePath string) {
 	if exists {
 		for i, entry := range entries {
 			if entry.FilePath == filePath {
-				c.entries[queryNorm][i].SelectCount++
+				c.entries[queryNorm][i].touch(now)
 				c.moveToFront(queryNorm)
 				return
 			}
@@ -878,22 +1835,24 @@ func (c *QueryCache) RecordSelection(query, filePath string) {
 	}
 
 	// Case 2: Nếu không có -> tạo mới một CacheEntry với count = 1
-	newEntry := CacheEntry{FilePath: filePath, SelectCount: 1}
+	newEntry := CacheEntry{FilePath: filePath}
+	newEntry.touch(now)
 	if !exists {
 		c.entries[queryNorm] = []CacheEntry{newEntry}
+		c.index.add(queryNorm)
 		c.queryOrder = append(c.queryOrder, queryNorm)
 	} else { // Case 3: Đã có query nhưng mà file đó ta chưa thêm vào
-		// Nếu đã đạt giới hạn số file được lưu cho mỗi từ khóa, xóa file có count thấp nhất
+		// Nếu đã đạt giới hạn số file được lưu cho mỗi từ khóa, xóa file có frecency thấp nhất
 		if len(entries) >= c.maxPerQuery {
 			minIdx := 0
-			minCount := entries[0].SelectCount
-			for i, e := range entries {
-				if e.SelectCount < minCount {
-					minCount = e.SelectCount
+			minScore := c.frecency(&entries[0], now)
+			for i := range entries {
+				if score := c.frecency(&entries[i], now); score < minScore {
+					minScore = score
 					minIdx = i
 				}
 			}
-			// Xóa file có count thấp nhất
+			// Xóa file có frecency thấp nhất
 			c.entries[queryNorm] = append(entries[:minIdx], entries[minIdx+1:]...)
 		}
 		// Thêm file mới vào
@@ -904,6 +1863,95 @@ func (c *QueryCache) RecordSelection(query, filePath string) {
 	c.evictIfNeeded()
 }
 
+/*
+- RecordRejection: User nói "đừng gợi ý file này cho query này nữa"
+- GetBoostScores trả về điểm âm (rejectPenalty * similarity / 100) cho file đó, áp cả cho các query tương tự
+- Search chỉ hạ điểm file bị từ chối, không kéo nó vào kết quả nếu nó vốn không khớp
+- Lượt chọn và ghim của đúng cặp (query, file) bị xóa, RecordSelection cặp đó sau này sẽ bỏ từ chối
+*/
+func (c *QueryCache) RecordRejection(query, filePath string) {
+	if query == "" || filePath == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queryNorm := Normalize(query)
+	c.removeEntry(queryNorm, filePath)
+	setList(c.pins, queryNorm, removeString(c.pins[queryNorm], filePath))
+	if !slices.Contains(c.rejections[queryNorm], filePath) {
+		c.rejections[queryNorm] = append(c.rejections[queryNorm], filePath)
+	}
+	c.touchFeedback(queryNorm)
+	c.dirty.Store(true)
+}
+
+/*
+- Pin: Ghim filePath lên đầu kết quả khi search đúng query (so sánh sau khi Normalize)
+- File được ghim luôn đứng trước mọi kết quả khác, kể cả khi fuzzy không khớp, miễn là còn trong index
+- Ghim nhiều file cho cùng query thì giữ đúng thứ tự ghim: file ghim trước ở vị trí 1, sau ở vị trí 2...
+- Ghim lại file đã ghim không đổi vị trí. Ghim file đang bị từ chối thì bỏ từ chối
+*/
+func (c *QueryCache) Pin(query, filePath string) {
+	if query == "" || filePath == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queryNorm := Normalize(query)
+	setList(c.rejections, queryNorm, removeString(c.rejections[queryNorm], filePath))
+	if !slices.Contains(c.pins[queryNorm], filePath) {
+		c.pins[queryNorm] = append(c.pins[queryNorm], filePath)
+	}
+	c.touchFeedback(queryNorm)
+	c.dirty.Store(true)
+}
+
+/*
+- Unpin: Bỏ ghim, các file ghim sau nó được đôn lên 1 vị trí
+*/
+func (c *QueryCache) Unpin(query, filePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	queryNorm := Normalize(query)
+	setList(c.pins, queryNorm, removeString(c.pins[queryNorm], filePath))
+	c.touchFeedback(queryNorm)
+	c.dirty.Store(true)
+}
+
+/*
+- GetPins: Danh sách file được ghim cho query, theo thứ tự vị trí
+*/
+func (c *QueryCache) GetPins(query string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.pins[Normalize(query)])
+}
+
+/*
+- SetRejectPenalty: Đặt điểm trừ cho file bị từ chối, mặc định DefaultRejectPenalty
+*/
+func (c *QueryCache) SetRejectPenalty(score int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejectPenalty = score
+	c.dirty.Store(true)
+}
+
+/*
+- SetMaxFeedback: Đặt giới hạn số query có từ chối hoặc ghim, mặc định DefaultMaxFeedback
+- Vượt giới hạn thì query lâu nhất không được RecordRejection/Pin/Unpin mất cả từ chối lẫn ghim
+*/
+func (c *QueryCache) SetMaxFeedback(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxFeedback = n
+	c.evictFeedback()
+	c.dirty.Store(true)
+}
+
 /*
 - GetBoostScores: Lấy điểm boost cho từng file dựa trên query người dùng
 - Ví dụ như ta có query "màn hình"
@@ -924,17 +1972,20 @@ func (c *QueryCache) GetBoostScores(query string) map[string]int {
 	}
 
 	queryNorm := Normalize(query)
+	now := c.clock()
 	/*
-		Kết quả nào càng giống ý định tìm kiếm VÀ càng được chọn nhiều trước đây, thì điểm cộng càng cao
+		Kết quả nào càng giống ý định tìm kiếm VÀ càng được chọn nhiều, gần đây, thì điểm cộng càng cao
 		Dựa vào config boost cơ bản của bạn
 		Độ giống nhau dựa vào querySimilarity
-		Độ phổ biến dựa vào entry.SelectCount, kiểu như 1 người bấm vào chọn nhiều lần hoặc nhiều người bấm vào chọn
+		Độ phổ biến dựa vào frecency: số lần chọn, giảm dần theo thời gian kể từ các lần chọn đó (halfLife)
 	*/
-	for cachedQuery, entries := range c.entries {
+	// Chỉ duyệt các query mà index cho là có thể giống, thay vì toàn bộ entries
+	c.forEachCandidate(queryNorm, func(cachedQuery string, entries []CacheEntry) {
 		similarity := c.querySimilarity(queryNorm, cachedQuery)
 		if similarity > 0 {
-			for _, entry := range entries {
-				boost := (c.boostScore * similarity * entry.SelectCount) / 100
+			for i := range entries {
+				entry := &entries[i]
+				boost := int(math.Round(float64(c.boostScore*similarity) * c.frecency(entry, now) / 100))
 				/*
 									Một file (entry.FilePath) có thể xuất hiện trong nhiều cached query khác nhau
 					    			Ví dụ: File "iPhone 15.html" xuất hiện khi tìm "iphone" và cả khi tìm "apple" (đại loại vậy)
@@ -946,6 +1997,22 @@ func (c *QueryCache) GetBoostScores(query string) map[string]int {
 				}
 			}
 		}
+	})
+
+	// File bị từ chối: trừ điểm phạt nặng nhất trong các query tương tự, kết quả có thể âm
+	penalties := make(map[string]int)
+	c.forEachRejection(queryNorm, func(rejectedQuery string, paths []string) {
+		similarity := c.querySimilarity(queryNorm, rejectedQuery)
+		if similarity == 0 {
+			return
+		}
+		penalty := c.rejectPenalty * similarity / 100
+		for _, path := range paths {
+			penalties[path] = max(penalties[path], penalty)
+		}
+	})
+	for path, penalty := range penalties {
+		result[path] -= penalty
 	}
 
 	return result
@@ -987,49 +2054,52 @@ func (c *QueryCache) GetCachedFiles(query string, limit int) []string {
 	}
 
 	queryNorm := Normalize(query)
+	now := c.clock()
 
 	type fileScore struct {
 		path  string
-		score int
+		score float64
 	}
 	var matches []fileScore
 	seen := make(map[string]bool)
 
 	// Ưu tiên cao nhất cho những query đã từng được gõ y hệt
 	if entries, ok := c.entries[queryNorm]; ok {
-		for _, entry := range entries {
-			// Điểm cơ bản cực cao (100) * số lần click
-			score := 100 * entry.SelectCount
+		for i := range entries {
+			entry := &entries[i]
+			// Điểm cơ bản cực cao (100) * frecency
+			score := 100 * c.frecency(entry, now)
 			matches = append(matches, fileScore{path: entry.FilePath, score: score})
 			seen[entry.FilePath] = true
 		}
 	}
 
 	// Tìm các query liên quan khác. Ví dụ: gõ "màn hình", tìm thấy cả trong lịch sử "màn hình dell"
-	for cachedQuery, entries := range c.entries {
+	c.forEachCandidate(queryNorm, func(cachedQuery string, entries []CacheEntry) {
 		// Bỏ qua nếu là chính nó (đã xử lý ở trên)
 		if cachedQuery == queryNorm {
-			continue
+			return
 		}
 
 		// Nếu độ dài chuỗi lệch nhau quá 5 ký tự, khả năng cao là không liên quan -> Bỏ qua để đỡ tốn tài nguyên
 		if abs(len(cachedQuery)-len(queryNorm)) > 5 {
-			continue
+			return
 		}
 
 		similarity := c.querySimilarity(queryNorm, cachedQuery)
 		if similarity > 0 {
-			for _, entry := range entries {
+			for i := range entries {
+				entry := &entries[i]
 				// Nếu đã có trong phần tìm khớp rồi thì không add lại
 				if seen[entry.FilePath] {
 					continue
 				}
-				// Điểm = Độ giống * Độ phổ biến
-				score := similarity * entry.SelectCount
+				// Điểm = Độ giống * Độ phổ biến (frecency)
+				score := float64(similarity) * c.frecency(entry, now)
 				matches = append(matches, fileScore{path: entry.FilePath, score: score})
 			}
 		}
-	}
+	})
 
 	sort.Slice(matches, func(i, j int) bool {
 		return matches[i].score > matches[j].score
@@ -1040,6 +2110,11 @@ func (c *QueryCache) GetCachedFiles(query string, limit int) []string {
 	// Reset map seen để dùng cho việc filter kết quả trả về
 	seenResult := make(map[string]bool)
 
+	// File đã bị từ chối cho đúng query này thì không gợi ý lại
+	for _, path := range c.rejections[queryNorm] {
+		seenResult[path] = true
+	}
+
 	for _, m := range matches {
 		if !seenResult[m.path] {
 			seenResult[m.path] = true
@@ -1056,6 +2131,9 @@ func (c *QueryCache) GetCachedFiles(query string, limit int) []string {
 /*
 - GetAllRecentFiles: Lấy lịch sử danh sách file đã lưu trong cache
 - List ra các file đã lưu trong cache như /data/products/dell/dell-ultrasharp.html,...
+- Sắp xếp theo lần chọn gần nhất thực sự (LastSelected), không phải theo thứ tự query
+- Chọn lại 1 file qua 1 query cũ vẫn đưa file đó lên đầu
+- Entry chưa có thời gian (đọc từ file cache cũ) xếp sau, theo thứ tự query như trước
 - Màn hình chính, input rỗng -> hiển thị "Recent Files"
 - Hãy xem demo để biết
 */
@@ -1069,6 +2147,7 @@ func (c *QueryCache) GetAllRecentFiles(limit int) []string {
 
 	type fileInfo struct {
 		path       string
+		last       time.Time
 		queryIndex int
 		count      int
 	}
@@ -1081,10 +2160,14 @@ func (c *QueryCache) GetAllRecentFiles(limit int) []string {
 				if i > existing.queryIndex {
 					existing.queryIndex = i
 				}
+				if entry.LastSelected.After(existing.last) {
+					existing.last = entry.LastSelected
+				}
 				existing.count += entry.SelectCount
 			} else {
 				fileMap[entry.FilePath] = &fileInfo{
 					path:       entry.FilePath,
+					last:       entry.LastSelected,
 					queryIndex: i,
 					count:      entry.SelectCount,
 				}
@@ -1098,6 +2181,9 @@ func (c *QueryCache) GetAllRecentFiles(limit int) []string {
 	}
 
 	sort.Slice(files, func(i, j int) bool {
+		if !files[i].last.Equal(files[j].last) {
+			return files[i].last.After(files[j].last)
+		}
 		if files[i].queryIndex != files[j].queryIndex {
 			return files[i].queryIndex > files[j].queryIndex
 		}
@@ -1112,6 +2198,57 @@ func (c *QueryCache) GetAllRecentFiles(limit int) []string {
 	return result
 }
 
+/*
+- RenamePath: Chuyển toàn bộ lượt chọn của oldPath sang newPath
+- Nếu cùng 1 query đã có cả 2 file thì cộng dồn SelectCount và thời gian các lần chọn vào newPath
+- Từ chối (RecordRejection) và ghim (Pin) của oldPath cũng chuyển sang newPath
+- Dùng khi file bị đổi tên/di chuyển để không mất boost đã học
+*/
+func (c *QueryCache) RenamePath(oldPath, newPath string) {
+	if oldPath == newPath {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty.Store(true)
+
+	for query, entries := range c.entries {
+		oldIdx, newIdx := -1, -1
+		for i, entry := range entries {
+			switch entry.FilePath {
+			case oldPath:
+				oldIdx = i
+			case newPath:
+				newIdx = i
+			}
+		}
+		if oldIdx == -1 {
+			continue
+		}
+		if newIdx == -1 {
+			entries[oldIdx].FilePath = newPath
+			continue
+		}
+		entries[newIdx].merge(entries[oldIdx])
+		c.entries[query] = append(entries[:oldIdx], entries[oldIdx+1:]...)
+	}
+
+	// Từ chối và ghim cũng đi theo file, nếu newPath đã có mặt thì chỉ giữ 1 bản
+	for _, m := range []map[string][]string{c.rejections, c.pins} {
+		for query, paths := range m {
+			i := slices.Index(paths, oldPath)
+			if i == -1 {
+				continue
+			}
+			if slices.Contains(paths, newPath) {
+				m[query] = slices.Delete(paths, i, i+1)
+			} else {
+				paths[i] = newPath
+			}
+		}
+	}
+}
+
 /*
 - Size: Lấy số lượng query đã lưu trong cache
 */
@@ -1122,13 +2259,19 @@ func (c *QueryCache) Size() int {
 }
 
 /*
-- Clear: Xóa tất cả query đã lưu trong cache
+- Clear: Xóa tất cả query đã lưu trong cache, kể cả từ chối và ghim
 */
 func (c *QueryCache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.entries = make(map[string][]CacheEntry)
+	c.index = newQueryIndex()
 	c.queryOrder = make([]string, 0)
+	c.rejections = make(map[string][]string)
+	c.pins = make(map[string][]string)
+	c.feedbackOrder = make([]string, 0)
+	c.rejectIndex = newQueryIndex()
+	c.dirty.Store(true)
 }
 
 // =============================================================================
@@ -1140,241 +2283,1033 @@ func (c *QueryCache) Clear() {
 - items: Danh sách đường dẫn file cần index
 */
 func NewSearcher(items []string) *Searcher {
-	originals := make([]string, len(items))
-	normPaths := make([]string, len(items))
-	normNames := make([]string, len(items))
-	pathMap := make(map[string]int, len(items))
+	return NewSearcherWithConfig(items, DefaultConfig())
+}
 
-	for i, item := range items {
-		originals[i] = item
-		filename := filepath.Base(item)
-		// Ưu tiên tên file, theo path thì điểm thấp hơn
-		priorityString := filename + " " + item
-		normPaths[i] = Normalize(priorityString)
-		normNames[i] = Normalize(filename)
+/*
+- NewSearcherWithConfig: Tạo Searcher mới với trọng số xếp hạng tùy chỉnh
+- cfg: Nên lấy từ DefaultConfig() rồi sửa, xem Config
+*/
+func NewSearcherWithConfig(items []string, cfg Config) *Searcher {
+	s := &Searcher{config: cfg, Cache: NewQueryCache(), Users: NewCacheRegistry()}
+	s.index.Store(buildIndex(items, &cfg))
+	return s
+}
 
-		// Map trong cache để sau này server tìm trong các file gốc nhanh hơn
-		pathMap[item] = i
+/*
+- buildIndex: Chuẩn hóa toàn bộ danh sách đường dẫn và tạo snapshot mới
+- Đường dẫn trùng chỉ giữ lần xuất hiện đầu tiên (giống Add)
+*/
+func buildIndex(items []string, cfg *Config) *searchIndex {
+	ix := newSearchIndex(len(items), false, cfg.Prefilter)
+	if cfg.TypoIndex {
+		ix.typo = newTypoIndex(len(items))
+	}
+	for _, item := range items {
+		if _, exists := ix.keyToIdx[item]; !exists {
+			ix.appendDoc(pathDocument(item), nil)
+		}
 	}
+	ix.seal()
+	return ix
+}
 
-	return &Searcher{
-		Originals:     items,
-		Normalized:    normPaths,
-		FilenamesOnly: normNames,
-		FilePathToIdx: pathMap,
-		Cache:         NewQueryCache(),
+/*
+- buildDocIndex: Giống buildIndex nhưng cho ItemSearcher, lưu kèm Document và item gốc
+*/
+func buildDocIndex(docs []Document, values []any, cfg *Config) *searchIndex {
+	ix := newSearchIndex(len(docs), true, cfg.Prefilter)
+	ix.primaryField = cfg.PrimaryField
+	if cfg.TypoIndex {
+		ix.typo = newTypoIndex(len(docs))
+	}
+	for i, doc := range docs {
+		if _, exists := ix.keyToIdx[doc.Key]; !exists {
+			ix.appendDoc(doc, values[i])
+		}
 	}
+	ix.seal()
+	return ix
+}
+
+func newSearchIndex(capacity int, typed, prefilter bool) *searchIndex {
+	ix := &searchIndex{
+		originals:     make([]string, 0, capacity),
+		normalized:    make([]string, 0, capacity),
+		filenamesOnly: make([]string, 0, capacity),
+		accented:      make([]string, 0, capacity),
+		initials:      make([]string, 0, capacity),
+		keyToIdx:      make(map[string]int, capacity),
+		typed:         typed,
+		prefilter:     prefilter,
+		targets:       newTargetArena(capacity),
+	}
+	if prefilter {
+		ix.masks = make([]charMask, 0, capacity)
+	}
+	if typed {
+		ix.docs = make([]Document, 0, capacity)
+		ix.values = make([]any, 0, capacity)
+		ix.fieldNorms = make([][]string, 0, capacity)
+		ix.fieldEnds = make([][]uint32, 0, capacity)
+		ix.fields = newTargetArena(capacity)
+	}
+	return ix
 }
 
 /*
-- NewSearcherWithCache: Tạo Searcher mới với cache có sẵn
-- items: Danh sáng đường dẫn file cần index
-- cache: QueryCache có sẵn để tái sử dụng
+- clone: Copy snapshot để writer sửa (copy-on-write)
+- extra: Số phần tử dự kiến thêm vào, để cấp phát đủ capacity 1 lần
+- Chi phí O(N) cho mỗi lần ghi, nên hãy gom nhiều file vào 1 lần Add/Remove nếu có thể
 */
-func NewSearcherWithCache(items []string, cache *QueryCache) *Searcher {
-	s := NewSearcher(items)
-	if cache != nil {
-		s.Cache = cache
+func (ix *searchIndex) clone(extra int) *searchIndex {
+	n := len(ix.originals)
+	c := newSearchIndex(n+extra, ix.typed, ix.prefilter)
+	c.primaryField = ix.primaryField
+	c.originals = append(c.originals, ix.originals...)
+	c.normalized = append(c.normalized, ix.normalized...)
+	c.filenamesOnly = append(c.filenamesOnly, ix.filenamesOnly...)
+	c.accented = append(c.accented, ix.accented...)
+	c.initials = append(c.initials, ix.initials...)
+	c.targets = ix.targets.clone(extra)
+	if ix.typo != nil {
+		c.typo = ix.typo.clone(extra)
+	}
+	if ix.prefilter {
+		c.masks = append(c.masks, ix.masks...)
+	}
+	if ix.typed {
+		c.docs = append(c.docs, ix.docs...)
+		c.values = append(c.values, ix.values...)
+		c.fieldNorms = append(c.fieldNorms, ix.fieldNorms...)
+		c.fieldEnds = append(c.fieldEnds, ix.fieldEnds...)
+		c.fields = ix.fields.clone(extra)
+	}
+	for k, v := range ix.keyToIdx {
+		c.keyToIdx[k] = v
+	}
+	return c
+}
+
+/*
+- appendDoc: Thêm 1 item vào cuối snapshot (chỉ dùng trên bản clone hoặc snapshot đang build)
+- Caller tự kiểm tra Key trùng
+- Caller gọi seal sau khi thêm xong cả lô
+*/
+func (ix *searchIndex) appendDoc(doc Document, value any) {
+	normStr, normPrimary := normalizeDoc(doc, ix.primaryField)
+	// Map trong cache để sau này server tìm trong các file gốc nhanh hơn
+	ix.keyToIdx[doc.Key] = len(ix.originals)
+	ix.originals = append(ix.originals, doc.Display)
+	ix.normalized = append(ix.normalized, normStr)
+	ix.filenamesOnly = append(ix.filenamesOnly, normPrimary)
+	ix.accented = append(ix.accented, accentDoc(doc))
+	ix.initials = append(ix.initials, acronymOf(primaryOf(doc, ix.primaryField), normPrimary))
+	if ix.typo != nil {
+		ix.typo.appendItem()
+	}
+	if ix.prefilter {
+		ix.masks = append(ix.masks, newCharMask(normStr, normPrimary))
+	}
+	if ix.typed {
+		ix.docs = append(ix.docs, doc)
+		ix.values = append(ix.values, value)
+		norms := normalizeFields(doc)
+		ix.fieldNorms = append(ix.fieldNorms, norms)
+		ix.fieldEnds = append(ix.fieldEnds, fieldEnds(norms))
 	}
-	return s
 }
 
 /*
-- Hàm quan trọng nhất, kết hợp Fuzzy Search + Levenshtein + Cache Boost
-- Có lẽ mình quên nói ở trên là ta phải dùng Rune
-- Ví dụ như:
-s := "Việt Nam"
-fmt.Println(len(s))  // 12 bytes -> SAI (8 mới đúng)
--> Đúng ra ta phải dùng Rune
-s := "Việt Nam"
-runes := []rune(s)
-fmt.Println(len(runes))  // 8 (đúng 8 ký tự)
-- Ta cần đếm số ký tự, chứ không tính theo byte được
+- setDoc: Ghi đè item ở vị trí idx, giữ nguyên Index
 */
-func (s *Searcher) Search(query string) []string {
-	queryNorm := Normalize(query)
-	// đếm số ký tự, không phải byte
-	queryLen := 0
-	for range queryNorm {
-		queryLen++
+func (ix *searchIndex) setDoc(idx int, doc Document, value any) {
+	delete(ix.keyToIdx, ix.doc(idx).Key)
+	ix.keyToIdx[doc.Key] = idx
+	ix.originals[idx] = doc.Display
+	ix.normalized[idx], ix.filenamesOnly[idx] = normalizeDoc(doc, ix.primaryField)
+	ix.accented[idx] = accentDoc(doc)
+	ix.initials[idx] = acronymOf(primaryOf(doc, ix.primaryField), ix.filenamesOnly[idx])
+	ix.targets.set(idx, ix.normalized[idx])
+	if ix.typo != nil {
+		ix.typo.setItem(idx)
+	}
+	if ix.prefilter {
+		ix.masks[idx] = newCharMask(ix.normalized[idx], ix.filenamesOnly[idx])
+	}
+	if ix.typed {
+		ix.docs[idx] = doc
+		ix.values[idx] = value
+		ix.fieldNorms[idx] = normalizeFields(doc)
+		ix.fieldEnds[idx] = fieldEnds(ix.fieldNorms[idx])
+		ix.fields.set(idx, strings.Join(ix.fieldNorms[idx], ""))
 	}
-	queryWords := strings.Fields(queryNorm)
+}
 
-	// Ước lượng capacity là để hạn chế resize
-	uniqueResults := make(map[int]int, 50)
+/*
+- removeAt: Xóa item ở vị trí idx bằng swap-remove
+- Đưa phần tử cuối vào chỗ trống để khỏi phải dịch cả mảng
+*/
+func (ix *searchIndex) removeAt(idx int) {
+	delete(ix.keyToIdx, ix.doc(idx).Key)
+	ix.targets.removeAt(idx)
+	if ix.typed {
+		ix.fields.removeAt(idx)
+	}
+	if ix.typo != nil {
+		ix.typo.removeAt(idx)
+	}
+	last := len(ix.originals) - 1
+	if idx != last {
+		ix.originals[idx] = ix.originals[last]
+		ix.normalized[idx] = ix.normalized[last]
+		ix.filenamesOnly[idx] = ix.filenamesOnly[last]
+		ix.accented[idx] = ix.accented[last]
+		ix.initials[idx] = ix.initials[last]
+		if ix.prefilter {
+			ix.masks[idx] = ix.masks[last]
+		}
+		if ix.typed {
+			ix.docs[idx] = ix.docs[last]
+			ix.values[idx] = ix.values[last]
+			ix.fieldNorms[idx] = ix.fieldNorms[last]
+			ix.fieldEnds[idx] = ix.fieldEnds[last]
+		}
+		ix.keyToIdx[ix.doc(idx).Key] = idx
+	}
+	ix.originals = ix.originals[:last]
+	ix.normalized = ix.normalized[:last]
+	ix.filenamesOnly = ix.filenamesOnly[:last]
+	ix.accented = ix.accented[:last]
+	ix.initials = ix.initials[:last]
+	if ix.prefilter {
+		ix.masks = ix.masks[:last]
+	}
+	if ix.typed {
+		ix.docs[last] = Document{} // Thả tham chiếu cho GC
+		ix.values[last] = nil
+		ix.fieldNorms[last] = nil
+		ix.fieldEnds[last] = nil
+		ix.docs = ix.docs[:last]
+		ix.values = ix.values[:last]
+		ix.fieldNorms = ix.fieldNorms[:last]
+		ix.fieldEnds = ix.fieldEnds[:last]
+	}
+}
 
-	// Ví dụ: User từng search "main" và chọn main.go nhiều lần:
-	// cacheBoosts = {"/a/main.go": 5000}
-	var cacheBoosts map[string]int
-	if s.Cache != nil {
-		cacheBoosts = s.Cache.GetBoostScores(query)
+/*
+- seal: Hoàn tất snapshot trước khi publish (cuối buildIndex/buildDocIndex và mỗi writer)
+- Decode các target mới vào arena (cả lô 1 lần), dựng lại typo index nếu quá nhiều item nằm ngoài trie
+- Với typed: các Field của item mới cũng được decode vào ix.fields
+*/
+func (ix *searchIndex) seal() {
+	ix.targets.extend(ix.normalized)
+	if ix.typed {
+		joined := make([]string, len(ix.fieldNorms))
+		for i := len(ix.fields.spans); i < len(joined); i++ {
+			joined[i] = strings.Join(ix.fieldNorms[i], "")
+		}
+		ix.fields.extend(joined)
 	}
+	if ix.typo != nil && ix.typo.stale() {
+		ix.typo.rebuild(ix.filenamesOnly)
+	}
+}
 
-	// Search bằng Smith-Waterman Fuzzy Matcher (tự implement, không dependency)
-	// Dùng parallel version nếu có nhiều files
-	var matches []FuzzyMatch
-	if len(s.Normalized) >= 1000 {
-		matches = FuzzyFindParallel(queryNorm, s.Normalized)
-	} else {
-		matches = FuzzyFind(queryNorm, s.Normalized)
+/*
+- doc: Document của item thứ idx
+- Searcher theo path không lưu docs nên dựng lại từ đường dẫn (rẻ, filepath.Base không cấp phát)
+*/
+func (ix *searchIndex) doc(idx int) Document {
+	if ix.typed {
+		return ix.docs[idx]
 	}
+	return pathDocument(ix.originals[idx])
+}
 
-	// OPTIMIZATION: Chỉ tính word bonus cho top 30 results
-	// countWordMatches rất chậm (gọi LevenshteinRatio), không nên chạy cho tất cả
-	maxWordBonusCalc := 30
-	if len(matches) < maxWordBonusCalc {
-		maxWordBonusCalc = len(matches)
+/*
+- pathDocument: Document mặc định cho 1 đường dẫn file
+- Ưu tiên tên file, theo path thì điểm thấp hơn
+*/
+func pathDocument(item string) Document {
+	return Document{
+		Key:       item,
+		Display:   item,
+		Primary:   filepath.Base(item),
+		Secondary: []string{item},
 	}
+}
 
-	for i, m := range matches {
-		if i < maxWordBonusCalc {
-			// Word bonus tính trên tên file (không phải full path)
-			wordMatches := countWordMatches(queryWords, s.FilenamesOnly[m.Index])
-			wordBonus := wordMatches * 3000
-			uniqueResults[m.Index] = m.Score + wordBonus
-		} else {
-			// Với results còn lại, chỉ dùng fuzzy score
-			uniqueResults[m.Index] = m.Score
-		}
+/*
+- normalizeDoc: Chuẩn hóa 1 Document thành (Normalized, FilenamesOnly)
+- Normalized = các trường nối bằng dấu cách (Primary + Secondary, hoặc các Field theo thứ tự)
+- FilenamesOnly = trường chính (Primary, hoặc Field tên primaryField / Field đầu tiên)
+*/
+func normalizeDoc(doc Document, primaryField string) (string, string) {
+	segs := doc.segments()
+	normPrimary := Normalize(primaryOf(doc, primaryField))
+	if len(segs) == 1 {
+		return normPrimary, normPrimary
 	}
+	return Normalize(strings.Join(segs, " ")), normPrimary
+}
 
-	// Ta tính điểm sai chính tả dựa trên Levenshtein
-	// Tức là nếu user gõ "maain" hay "mian" thì ta vẫn tính điểm cho "main"
-	// Threshold = (queryLen / 3) + 1: cho phép khoảng 1 lỗi mỗi 3 ký tự + 1 lỗi bonus
-	// Minimum threshold = 3: query ngắn (2-5 ký tự) vẫn cần đủ độ linh hoạt để match
-	// needLevenshtein := len(uniqueResults) < 20
-	if queryLen > 1 {
-		baseThreshold := (queryLen / 3) + 1
-		if baseThreshold < 3 {
-			baseThreshold = 3
+// primaryOf: Trường chính chưa chuẩn hóa của Document (Primary, hoặc Field tên primaryField / Field đầu tiên)
+func primaryOf(doc Document, primaryField string) string {
+	primary := doc.Primary
+	if len(doc.Fields) > 0 {
+		primary = doc.Fields[0].Value
+	}
+	for _, f := range doc.Fields {
+		if primaryField != "" && f.Name == primaryField {
+			primary = f.Value
+			break
 		}
+	}
+	return primary
+}
 
-		for i, nameNorm := range s.FilenamesOnly {
-			// Thay vì: runesName := []rune(nameNorm)
-			// Ta kiểm tra độ dài bằng len() byte trước cho nhanh (sơ loại)
-			if len(nameNorm) < queryLen {
-				continue
-			}
-
-			// So sánh với phần đầu của filename
-			targetStr1 := fastSubstring(nameNorm, queryLen)
-			// Nếu sau khi cắt mà độ dài vẫn ngắn hơn query (do ký tự utf8) thì bỏ
-			if len(targetStr1) < len(queryNorm) { // so sánh byte length ok vì đã normalized
-				continue
-			}
-
-			dist := LevenshteinRatio(queryNorm, targetStr1)
+/*
+- normalizeFields: Chuẩn hóa riêng từng Field để chấm fuzzy theo trọng số, nil nếu Document không dùng Fields
+*/
+func normalizeFields(doc Document) []string {
+	if len(doc.Fields) == 0 {
+		return nil
+	}
+	norms := make([]string, len(doc.Fields))
+	for i, f := range doc.Fields {
+		norms[i] = Normalize(f.Value)
+	}
+	return norms
+}
 
-			// So sánh thêm 1 ký tự (phòng trường hợp typo thêm ký tự)
-			if len(nameNorm) > len(targetStr1) {
-				// Lấy prefix dài hơn 1 rune
-				targetStr2 := fastSubstring(nameNorm, queryLen+1)
+/*
+- fieldEnds: Vị trí kết thúc (tính theo ký tự) của từng Field khi nối liền các Field đã chuẩn hóa
+- Field k của item i là đoạn [fieldEnds[k-1], fieldEnds[k]) trong span i của searchIndex.fields
+*/
+func fieldEnds(norms []string) []uint32 {
+	if norms == nil {
+		return nil
+	}
+	ends := make([]uint32, len(norms))
+	end := uint32(0)
+	for k, norm := range norms {
+		end += uint32(utf8.RuneCountInString(norm))
+		ends[k] = end
+	}
+	return ends
+}
 
-				d2 := LevenshteinRatio(queryNorm, targetStr2)
-				if d2 < dist {
-					dist = d2
-				}
+/*
+- segments: Các trường theo đúng thứ tự được nối vào Normalized
+- Field rỗng bị bỏ qua, tránh 2 dấu cách liền nhau (greedy coi ký tự sau dấu cách là đầu từ và có thể nhảy nhầm vào đó)
+*/
+func (doc *Document) segments() []string {
+	if len(doc.Fields) > 0 {
+		segs := make([]string, 0, len(doc.Fields))
+		for _, f := range doc.Fields {
+			if f.Value != "" {
+				segs = append(segs, f.Value)
 			}
-			/*
-				Ở phần trên ví dụ như "mian", target 1 là "main" target 2 là "maina"
-				Ta tính điểm ở target 1, dist = d1 = 2, nhưng ở target 2, dist = d2 = 3
-				if d2 < dist {
-						dist = d2
-					}
-				Tức là nếu nhỏ hơn cái d1 thì lấy, còn không thì giữ nguyên
-				Kiểu như min(d1, d2)
-			*/
+		}
+		if len(segs) == 0 {
+			segs = append(segs, "")
+		}
+		return segs
+	}
+	return append([]string{doc.Primary}, doc.Secondary...)
+}
 
-			// Nếu điểm sai chính tả nhỏ hơn ngưỡng cho phép thì tính điểm
-			// Robust solution khi sai chính tả đi quá xa (hoặc nếu không thì mong bạn có thể mở PR hỗ trợ mình)
-			if dist <= baseThreshold {
-				score := 10000 - (dist * 100)
-				runeCountName := 0
-				for range nameNorm {
-					runeCountName++
-				}
-				lenDiff := runeCountName - queryLen
-				if lenDiff > 0 {
-					score -= (lenDiff * 10)
-				}
+// fieldWeight: Trọng số của Field thứ k, 0 nghĩa là DefaultFieldWeight
+func (doc *Document) fieldWeight(k int) int {
+	if w := doc.Fields[k].Weight; w != 0 {
+		return w
+	}
+	return DefaultFieldWeight
+}
 
-				// Thêm word bonus cho Levenshtein matches
-				// Dùng tên file để tính word matches (không phải full path)
-				if dist < 2 {
-					wordMatches := countWordMatches(queryWords, s.FilenamesOnly[i])
-					score += wordMatches * 3000
-				}
+/*
+  - scoreFields: Điểm fuzzy của item dùng Document.Fields
+  - Chấm từng Field riêng rồi nhân trọng số (%), lấy Field cao nhất
+  - joined: Điểm trên chuỗi nối các Field. Nếu không Field nào khớp trọn (query rải qua nhiều Field)
+    thì dùng joined nhân trọng số nhỏ nhất
+  - Trả về (điểm, index của Field thắng hoặc -1 nếu dùng joined)
+  - Các Field đã decode sẵn trong ix.fields (seal), vòng lặp không cấp phát
+*/
+func (ix *searchIndex) scoreFields(cfg *Config, algo Algorithm, p *fuzzyPattern, idx int, joined int) (int, int) {
+	doc := &ix.docs[idx]
+	best, bestField := 0, -1
+	minWeight := math.MaxInt
+	from := uint32(0)
+	for k, end := range ix.fieldEnds[idx] {
+		w := doc.fieldWeight(k)
+		minWeight = min(minWeight, w)
+		fieldScore, _, ok := ix.fields.scoreRange(cfg, algo, p, idx, from, end, nil)
+		from = end
+		if !ok {
+			continue
+		}
+		if weighted := fieldScore * w / 100; bestField == -1 || weighted > best {
+			best, bestField = weighted, k
+		}
+	}
+	if bestField == -1 {
+		return joined * minWeight / 100, -1
+	}
+	return best, bestField
+}
 
-				if oldScore, exists := uniqueResults[i]; !exists || score > oldScore {
-					uniqueResults[i] = score
+/*
+  - fuzzyFindFields: Bản FuzzyFind cho snapshot của ItemSearcher
+  - Item dùng Document.Fields được chấm từng Field (scoreFields), item khác chấm trên normalized như thường
+  - KHÔNG lọc trước bằng chuỗi nối: greedy có thể trượt trên chuỗi nối (nhảy nhầm sang đầu từ ở Field sau)
+    trong khi từng Field riêng lại khớp, nên phải chấm đủ mọi Field. Đổi lại tốn gấp ~số Field lần so với path
+  - fieldOf[idx]: Field thắng của item idx, -1 nếu không có
+  - Chia việc cho worker giống fuzzyFindParallel khi số item >= cfg.ParallelThreshold
+*/
+func fuzzyFindFields(ctx context.Context, cfg *Config, ix *searchIndex, pattern string) ([]FuzzyMatch, []int) {
+	p := newFuzzyPattern(pattern)
+	n := len(ix.normalized)
+	fieldOf := make([]int, n)
+	if len(p.runes) == 0 {
+		return nil, fieldOf
+	}
+	patternMask := maskOf(pattern, -1)
+	done := ctx.Done()
+
+	scan := func(start, end int) []FuzzyMatch {
+		local := make([]FuzzyMatch, 0, (end-start)/5)
+		for i := start; i < end; i++ {
+			if (i-start)%ctxCheckInterval == 0 && isDone(done) {
+				break
+			}
+			fieldOf[i] = -1
+			// Mọi Field đều nằm trong normalized: thiếu ký tự ở normalized thì không Field nào khớp được
+			if ix.masks != nil && patternMask&^ix.masks[i].all != 0 {
+				continue
+			}
+			joined, _, ok := ix.targets.score(cfg, cfg.Algorithm, &p, i, nil)
+			if ix.fieldNorms[i] == nil {
+				if ok {
+					local = append(local, FuzzyMatch{Index: i, Score: joined})
 				}
+				continue
+			}
+			// Không khớp trên chuỗi nối vẫn thử từng Field (có thể là greedy trượt), khi đó joined không được dùng
+			score, k := ix.scoreFields(cfg, cfg.Algorithm, &p, i, joined)
+			if ok || k >= 0 {
+				fieldOf[i] = k
+				local = append(local, FuzzyMatch{Index: i, Score: score})
 			}
 		}
+		return local
 	}
-	/*
-		Đảm bảo file đã cache luôn xuất hiện trong kết quả, kể cả khi fuzzy/Levenshtein không match
-		Thì ví dụ như:
-		User search "tiền lương", xong họ chả chọn cái gì liên quan tới tiền lương
-		nhưng chọn "bao_cao_tai_chinh_2024.xlsx"
-		Hệ thống lưu lại: Query: "tiền lương" -> File: "bao_cao..."
-		Xong giờ search lại "tien luong" một lần nữa
-		Lúc này cả fuzzy và levenshtein đều không match
-		Đoạn code này sẽ giải quyết vấn đề trên
-		Nó vẫn in ra "bao_cao_tai_chinh_2024.xlsx", vì trước đây từng có hành vi này
-		Và có thể nó sẽ là 1 trong những file user cần
-		Đây chỉ là một cơ chế phòng bị cho trường hợp user quên tên file
-		vì nó cũng không có độ chính xác quá cao
-	*/
-	for cachedPath, boost := range cacheBoosts {
-		// Tra cứu trực tiếp từ map đã pre-compute
-		if idx, exists := s.FilePathToIdx[cachedPath]; exists {
-			if _, alreadyInResults := uniqueResults[idx]; !alreadyInResults {
-				uniqueResults[idx] = boost
+
+	var results []FuzzyMatch
+	if n < cfg.ParallelThreshold || n < cfg.ParallelMinTargets {
+		results = scan(0, n)
+	} else {
+		numWorkers := min(runtime.NumCPU(), 16)
+		chunkSize := (n + numWorkers - 1) / numWorkers
+		parts := make([][]FuzzyMatch, numWorkers)
+		var wg sync.WaitGroup
+		for w := range numWorkers {
+			start, end := w*chunkSize, min((w+1)*chunkSize, n)
+			if start >= n {
+				break
 			}
+			wg.Add(1)
+			go func(w, start, end int) {
+				defer wg.Done()
+				parts[w] = scan(start, end)
+			}(w, start, end)
+		}
+		wg.Wait()
+		for _, part := range parts {
+			results = append(results, part...)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, fieldOf
+}
+
+/*
+- normalizeItem: Chuẩn hóa 1 đường dẫn thành (Normalized, FilenamesOnly)
+*/
+func normalizeItem(item string) (string, string) {
+	return normalizeDoc(pathDocument(item), "")
+}
+
+/*
+- NewSearcherWithCache: Tạo Searcher mới với cache có sẵn
+- items: Danh sáng đường dẫn file cần index
+- cache: QueryCache có sẵn để tái sử dụng
+*/
+func NewSearcherWithCache(items []string, cache *QueryCache) *Searcher {
+	s := NewSearcher(items)
+	if cache != nil {
+		s.Cache = cache
+	}
+	return s
+}
+
+/*
+- Search: Tìm kiếm và trả về top 20 đường dẫn phù hợp nhất
+- Chỉ là wrapper của SearchResults cho trường hợp không cần điểm số
+*/
+func (s *Searcher) Search(query string) []string {
+	matches := s.SearchResults(query)
+	results := make([]string, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, m.Str)
+	}
+	return results
+}
+
+/*
+- SearchResults: Trả về top 20 kết quả kèm Index, Score và ScoreBreakdown để giải thích thứ hạng
+- Tương đương SearchWithOptions với SearchOptions rỗng
+*/
+func (s *Searcher) SearchResults(query string) []MatchResult {
+	// Không truyền Cursor thì không bao giờ lỗi
+	page, _ := s.SearchWithOptions(query, SearchOptions{})
+	return page.Results
+}
+
+/*
+- SearchWithOptions: Tìm kiếm có phân trang
+- Tương đương SearchContext với context.Background()
+*/
+func (s *Searcher) SearchWithOptions(query string, opts SearchOptions) (SearchPage, error) {
+	return s.SearchContext(context.Background(), query, opts)
+}
+
+/*
+- SearchContext: Tìm kiếm có phân trang, hủy được qua ctx
+- opts.Limit: Số kết quả mỗi trang (mặc định DefaultLimit = 20)
+- opts.Offset hoặc opts.Cursor: Vị trí bắt đầu, Cursor lấy từ NextCursor của trang trước
+- Chỉ chọn top (Offset + Limit) bằng heap thay vì sort toàn bộ candidates
+- Lỗi ErrInvalidCursor nếu cursor hỏng hoặc thuộc về 1 query khác
+- Ví dụ "load more":
+page, _ := searcher.SearchWithOptions("bao cao", SearchOptions{Limit: 20})
+next, _ := searcher.SearchWithOptions("bao cao", SearchOptions{Limit: 20, Cursor: page.NextCursor})
+- Type-ahead: mỗi phím gõ hủy ctx của lần search trước, các worker fuzzy và vòng Levenshtein sẽ thoát sớm
+- Khi ctx bị hủy giữa chừng: trả về trang dựng từ phần kết quả đã chấm được (có thể thiếu, Total nhỏ hơn thực tế) kèm ctx.Err()
+- Ai không cần kết quả dở dang thì chỉ việc bỏ qua page khi err != nil
+*/
+func (s *Searcher) SearchContext(ctx context.Context, query string, opts SearchOptions) (SearchPage, error) {
+	page, _, err := s.searchPage(ctx, query, opts)
+	return page, err
+}
+
+/*
+- searchPage: Phần chung của SearchContext và ItemSearcher.SearchContext
+- Trả về kèm snapshot đã dùng, để ItemSearcher lấy item gốc từ đúng snapshot đó
+*/
+func (s *Searcher) searchPage(ctx context.Context, query string, opts SearchOptions) (SearchPage, *searchIndex, error) {
+	// Lấy snapshot 1 lần duy nhất, mọi thứ bên dưới đọc từ snapshot này
+	// Kể cả khi có Add/Remove chạy song song thì kết quả vẫn nhất quán
+	ix := s.index.Load()
+	if err := ctx.Err(); err != nil {
+		return SearchPage{}, ix, err
+	}
+	queryNorm := Normalize(query)
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	offset := opts.Offset
+	if opts.Cursor != "" {
+		var err error
+		offset, err = decodeCursor(opts.Cursor, queryNorm)
+		if err != nil {
+			return SearchPage{}, ix, err
 		}
 	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	candidates, rankErr := s.rank(ctx, ix, query, queryNorm, opts.UserID)
+
+	page := SearchPage{Total: len(candidates)}
+	if offset >= len(candidates) {
+		page.Results = []MatchResult{}
+		return page, ix, rankErr
+	}
+	end := offset + limit
+	if end > len(candidates) {
+		end = len(candidates)
+	}
+	page.Results = topK(candidates, end)[offset:end]
+	if end < len(candidates) {
+		page.NextCursor = encodeCursor(end, queryNorm)
+	}
+
+	// Chỉ tính vị trí highlight cho những kết quả trả về, không tính cho toàn bộ candidates
+	patternRunes := []rune(queryNorm)
+	for i := range page.Results {
+		pattern := patternRunes
+		// Kết quả đến từ cách hiểu khác (Telex/VNI) thì highlight theo cách hiểu đó
+		if alt := page.Results[i].Breakdown.Query; alt != "" {
+			pattern = []rune(Normalize(alt))
+		}
+		page.Results[i].Positions = ix.matchPositions(&s.config, page.Results[i].Index, pattern, page.Results[i].Breakdown.Reranked)
+	}
+	return page, ix, rankErr
+}
+
+/*
+- rank: Hàm quan trọng nhất, chạy chuỗi Ranker (Fuzzy -> Word bonus -> Typo -> Cache boost -> Scorers)
+- Trả về TẤT CẢ candidates kèm điểm, CHƯA sắp xếp (việc chọn top để SearchContext lo)
+- ctx bị hủy thì dừng chuỗi Ranker, trả về candidates chấm được tới lúc đó kèm ctx.Err()
+- Từng bước xếp hạng nằm ở ranker.go, muốn chèn tín hiệu riêng thì dùng Config.Rankers / Config.Scorers
+- Config.DecodeKeystrokes: query có từ gõ Telex/VNI thì KeystrokeRanker chấm thêm bản đã giải mã, mỗi item giữ điểm cao hơn, Breakdown.Query ghi lại cách hiểu đã thắng
+- Config.Synonyms: SynonymRanker làm tương tự cho từng query thay thế (từ đồng nghĩa), điểm bị giảm theo trọng số (Breakdown.Synonym)
+- Có lẽ mình quên nói ở trên là ta phải dùng Rune
+- Ví dụ như:
+s := "Việt Nam"
+fmt.Println(len(s))  // 12 bytes -> SAI (8 mới đúng)
+-> Đúng ra ta phải dùng Rune
+s := "Việt Nam"
+runes := []rune(s)
+fmt.Println(len(runes))  // 8 (đúng 8 ký tự)
+- Ta cần đếm số ký tự, chứ không tính theo byte được
+*/
+func (s *Searcher) rank(ctx context.Context, ix *searchIndex, query, queryNorm, userID string) ([]MatchResult, error) {
+	candidates := s.rankQuery(ctx, ix, query, queryNorm, userID)
+
 	/*
 		File: "/a/main.go"
 		Fuzzy score: 85
 		Cache boost: 5000
 		Final score: 85 + 5000 = 5085 -> Lên top
 	*/
-	var rankedResults []MatchResult
-	for idx, score := range uniqueResults {
-		filePath := s.Originals[idx]
-		finalScore := score
-
-		if boost, exists := cacheBoosts[filePath]; exists {
-			if score != boost { // Tránh duplicate
-				finalScore += boost
-			}
+	rankedResults := make([]MatchResult, 0, len(candidates))
+	for idx, bd := range candidates {
+		rankedResults = append(rankedResults, MatchResult{
+			Index:     idx,
+			Str:       ix.originals[idx],
+			Score:     bd.Total(),
+			Breakdown: bd,
+		})
+	}
+	return rankedResults, ctx.Err()
+}
+
+/*
+- rankQuery: Chạy chuỗi Ranker cho 1 cách hiểu của query, trả về Candidates
+- ctx bị hủy thì dừng giữa chừng, trả về phần đã chấm được
+*/
+func (s *Searcher) rankQuery(ctx context.Context, ix *searchIndex, query, queryNorm, userID string) map[int]ScoreBreakdown {
+	st := s.newRankState(ctx, ix, query, queryNorm, userID)
+
+	rankers := s.config.Rankers
+	if rankers == nil {
+		rankers = DefaultRankers()
+	}
+	for _, r := range rankers {
+		r.Rank(st)
+		if ctx.Err() != nil {
+			break
 		}
+	}
+	if len(s.config.Scorers) > 0 && ctx.Err() == nil {
+		scorerRanker(s.config.Scorers).Rank(st)
+	}
+	return st.Candidates
+}
 
-		rankedResults = append(rankedResults, MatchResult{
-			Str:   filePath,
-			Score: finalScore,
+/*
+- newRankState: Chuẩn bị RankState cho 1 lần Search trên snapshot ix
+*/
+func (s *Searcher) newRankState(ctx context.Context, ix *searchIndex, query, queryNorm, userID string) *RankState {
+	// đếm số ký tự, không phải byte
+	queryLen := 0
+	for range queryNorm {
+		queryLen++
+	}
+	return &RankState{
+		Query:      query,
+		QueryNorm:  queryNorm,
+		QueryWords: strings.Fields(queryNorm),
+		QueryLen:   queryLen,
+		Config:     &s.config,
+		Cache:      s.Cache,
+		UserID:     userID,
+		users:      s.Users,
+		// Ước lượng capacity là để hạn chế resize
+		Candidates: make(map[int]ScoreBreakdown, 50),
+		ix:         ix,
+		ctx:        ctx,
+	}
+}
+
+/*
+- rankBefore: Thứ tự xếp hạng
+- File được ghim (Breakdown.Pin > 0) luôn đứng trước, theo đúng thứ tự ghim
+- Điểm cao lên trước
+- Cùng điểm, ưu tiên file path ngắn
+- Vẫn bằng nhau thì theo Index để kết quả ổn định giữa các trang
+*/
+func rankBefore(a, b *MatchResult) bool {
+	if a.Breakdown.Pin != b.Breakdown.Pin {
+		if a.Breakdown.Pin == 0 || b.Breakdown.Pin == 0 {
+			return a.Breakdown.Pin != 0
+		}
+		return a.Breakdown.Pin < b.Breakdown.Pin
+	}
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if len(a.Str) != len(b.Str) {
+		return len(a.Str) < len(b.Str)
+	}
+	return a.Index < b.Index
+}
+
+/*
+- topK: Trả về k kết quả tốt nhất đã sắp xếp theo rankBefore
+- Thường chỉ cần trang đầu (k = 20) trong khi candidates có thể lên tới hàng chục nghìn
+- Nên thay vì sort toàn bộ O(n log n), ta giữ 1 min-heap k phần tử: O(n log k)
+- Gốc heap là phần tử "tệ nhất" trong top k hiện tại, gặp phần tử tốt hơn thì thay vào gốc
+- NOTE: Sắp xếp lại trực tiếp trên slice candidates
+*/
+func topK(candidates []MatchResult, k int) []MatchResult {
+	if k >= len(candidates) {
+		sort.Slice(candidates, func(i, j int) bool {
+			return rankBefore(&candidates[i], &candidates[j])
 		})
+		return candidates
+	}
+
+	// heap[0:k] nằm ngay đầu slice candidates, không cần cấp phát thêm
+	h := candidates[:k]
+	// worse: phần tử i "tệ hơn" phần tử j -> i nằm gần gốc hơn
+	worse := func(i, j int) bool { return rankBefore(&h[j], &h[i]) }
+	down := func(i int) {
+		for {
+			l := 2*i + 1
+			if l >= k {
+				return
+			}
+			m := l
+			if r := l + 1; r < k && worse(r, l) {
+				m = r
+			}
+			if !worse(m, i) {
+				return
+			}
+			h[i], h[m] = h[m], h[i]
+			i = m
+		}
+	}
+	for i := k/2 - 1; i >= 0; i-- {
+		down(i)
 	}
-	// Logic:
-	// Điểm cao lên trước
-	// Cùng điểm, ưu tiên file path ngắn
-	sort.SliceStable(rankedResults, func(i, j int) bool {
-		if rankedResults[i].Score == rankedResults[j].Score {
-			return len(rankedResults[i].Str) < len(rankedResults[j].Str)
+	for i := k; i < len(candidates); i++ {
+		if rankBefore(&candidates[i], &h[0]) {
+			h[0], candidates[i] = candidates[i], h[0]
+			down(0)
 		}
-		return rankedResults[i].Score > rankedResults[j].Score
+	}
+
+	sort.Slice(h, func(i, j int) bool {
+		return rankBefore(&h[i], &h[j])
 	})
-	// Trả về top 20, nếu kết quả ít hơn 20 thì show bấy nhiêu thôi
-	// Hãy xem demo
-	var results []string
-	limit := 20
-	if len(rankedResults) < limit {
-		limit = len(rankedResults)
+	return h
+}
+
+/*
+- encodeCursor: Cursor = base64("offset:hash(query)")
+- Gắn hash của query vào để phát hiện cursor bị dùng nhầm cho query khác
+- Cursor chỉ là offset, nếu index/cache thay đổi giữa 2 trang thì kết quả có thể lệch 1 chút (giống mọi kiểu phân trang offset)
+*/
+func encodeCursor(offset int, queryNorm string) string {
+	h := fnv.New32a()
+	h.Write([]byte(queryNorm))
+	raw := strconv.Itoa(offset) + ":" + strconv.FormatUint(uint64(h.Sum32()), 36)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+/*
+- decodeCursor: Ngược lại với encodeCursor, trả về offset
+*/
+func decodeCursor(cursor, queryNorm string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
 	}
-	for _, res := range rankedResults[:limit] {
-		results = append(results, res.Str)
+	offsetStr, hash, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, ErrInvalidCursor
 	}
-	return results
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	h := fnv.New32a()
+	h.Write([]byte(queryNorm))
+	if hash != strconv.FormatUint(uint64(h.Sum32()), 36) {
+		return 0, ErrInvalidCursor
+	}
+	return offset, nil
+}
+
+/*
+- matchPositions: Tìm vị trí các ký tự khớp trong chuỗi gốc (có dấu) để highlight
+- normalized[idx] có dạng Normalize(Primary + " " + Secondary...), nên vị trí phải map qua 2 bước
+- Bước 1: Rune index trong chuỗi normalize -> byte offset trong chuỗi nối chưa normalize (NormalizeWithOffsets)
+- Bước 2: Byte offset đó -> byte offset trong Display, dựa vào vị trí của từng trường trong Display
+- Với path: filename là phần đuôi của path, path chính là Display
+- Trường không xuất hiện nguyên văn trong Display thì các vị trí khớp trong trường đó bị bỏ qua
+- Ví dụ: query "bao", file "/docs/Báo_cáo.pdf" -> vị trí của B, á, o trong "/docs/Báo_cáo.pdf"
+- Trả về nil nếu query không khớp fuzzy (ví dụ kết quả chỉ đến từ Levenshtein hoặc cache)
+- reranked: Item đã được FuzzyRanker chấm lại (Breakdown.Reranked), highlight theo đúng cách đã chấm nó
+*/
+func (ix *searchIndex) matchPositions(cfg *Config, idx int, pattern []rune, reranked bool) []int {
+	if len(pattern) == 0 {
+		return nil
+	}
+	doc := ix.doc(idx)
+	fields := doc.segments()
+
+	// Highlight bằng đúng thuật toán đã chấm item này: optimal, greedy, hoặc optimal không phạt khoảng trống (rerank)
+	var scorer scoreFunc = fuzzyScoreGreedyPositions
+	algo := AlgorithmGreedy
+	switch {
+	case cfg.Algorithm == AlgorithmOptimal:
+		scorer, algo = fuzzyScoreOptimal, AlgorithmOptimal
+	case reranked:
+		scorer, algo, cfg = fuzzyScoreOptimal, AlgorithmOptimal, rerankConfig(cfg)
+	}
+
+	// Document nhiều Field: chỉ highlight trong Field đã thắng khi chấm điểm
+	if ix.typed && ix.fieldNorms[idx] != nil {
+		p := newFuzzyPattern(string(pattern))
+		if _, k := ix.scoreFields(cfg, algo, &p, idx, 0); k >= 0 {
+			fields = []string{doc.Fields[k].Value}
+		}
+	}
+	normStr, offsets := NormalizeWithOffsets(strings.Join(fields, " "))
+	_, runePositions, matched := scorer(cfg, pattern, []rune(normStr), make([]int, 0, len(pattern)))
+	if !matched {
+		return nil
+	}
+
+	// fieldStart[k]: byte offset của trường k trong chuỗi nối
+	// displayStart[k]: byte offset của trường k trong Display, -1 nếu không có
+	// Dùng LastIndex vì filename là phần ĐUÔI của path
+	fieldStart := make([]int, len(fields))
+	displayStart := make([]int, len(fields))
+	start := 0
+	for k, f := range fields {
+		fieldStart[k] = start
+		displayStart[k] = strings.LastIndex(doc.Display, f)
+		start += len(f) + 1
+	}
+
+	positions := make([]int, 0, len(runePositions))
+	k := 0
+	for _, rp := range runePositions {
+		offset := offsets[rp]
+		// runePositions tăng dần nên chỉ cần tiến k
+		for k+1 < len(fields) && offset >= fieldStart[k+1] {
+			k++
+		}
+		rel := offset - fieldStart[k]
+		if rel >= len(fields[k]) || displayStart[k] < 0 {
+			continue // Dấu cách ngăn giữa các trường, hoặc trường không có trong Display
+		}
+		positions = append(positions, displayStart[k]+rel)
+	}
+
+	// Ký tự khớp ở phần filename và phần path có thể trỏ về cùng 1 vị trí -> sort + bỏ trùng
+	sort.Ints(positions)
+	n := 0
+	for i, p := range positions {
+		if i == 0 || p != positions[n-1] {
+			positions[n] = p
+			n++
+		}
+	}
+	return positions[:n]
+}
+
+/*
+- Add: Thêm file vào index mà không phải gọi lại NewSearcher cho toàn bộ danh sách
+- File đã tồn tại sẽ được bỏ qua
+- An toàn khi gọi đồng thời với Search (Search đang chạy vẫn thấy snapshot cũ)
+*/
+func (s *Searcher) Add(items ...string) {
+	docs := make([]Document, len(items))
+	for i, item := range items {
+		docs[i] = pathDocument(item)
+	}
+	s.addDocs(docs, nil)
+}
+
+/*
+- addDocs: Phần chung của Searcher.Add và ItemSearcher.Add
+- values: nil với Searcher theo path
+*/
+func (s *Searcher) addDocs(docs []Document, values []any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	next := s.index.Load().clone(len(docs))
+	for i, doc := range docs {
+		if _, exists := next.keyToIdx[doc.Key]; exists {
+			continue
+		}
+		var value any
+		if values != nil {
+			value = values[i]
+		}
+		next.appendDoc(doc, value)
+	}
+	next.seal()
+	s.index.Store(next)
+}
+
+/*
+- Remove: Xóa file khỏi index
+- Dùng cách swap-remove: đưa phần tử cuối vào chỗ trống để khỏi phải dịch cả mảng
+- Vì vậy Index của phần tử cuối sẽ thay đổi, đừng giữ Index qua các lần Add/Remove
+- File không tồn tại sẽ được bỏ qua. An toàn khi gọi đồng thời với Search
+- Cache không bị xóa, file được thêm lại sau này vẫn giữ được boost
+*/
+func (s *Searcher) Remove(items ...string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	next := s.index.Load().clone(0)
+	for _, key := range items {
+		if idx, exists := next.keyToIdx[key]; exists {
+			next.removeAt(idx)
+		}
+	}
+	next.seal()
+	s.index.Store(next)
+}
+
+/*
+- Rename: Đổi đường dẫn của 1 file, giữ nguyên Index
+- Các lượt chọn trong QueryCache (cả cache riêng của từng user trong Users) cũng được chuyển sang đường dẫn mới để không mất boost đã học
+- Trả về false nếu oldPath không tồn tại hoặc newPath đã có trong index
+*/
+func (s *Searcher) Rename(oldPath, newPath string) bool {
+	return s.replaceDoc(oldPath, pathDocument(newPath), nil)
+}
+
+/*
+- replaceDoc: Phần chung của Searcher.Rename và ItemSearcher.Update
+- Key đổi thì chuyển luôn cache sang Key mới, Key mới đã thuộc về item khác thì từ chối
+*/
+func (s *Searcher) replaceDoc(oldKey string, doc Document, value any) bool {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	cur := s.index.Load()
+	idx, exists := cur.keyToIdx[oldKey]
+	if !exists {
+		return false
+	}
+	if other, taken := cur.keyToIdx[doc.Key]; taken && other != idx {
+		return false
+	}
+
+	next := cur.clone(0)
+	next.setDoc(idx, doc, value)
+	next.seal()
+	s.index.Store(next)
+
+	if oldKey != doc.Key {
+		if s.Cache != nil {
+			s.Cache.RenamePath(oldKey, doc.Key)
+		}
+		if s.Users != nil {
+			s.Users.RenamePath(oldKey, doc.Key)
+		}
+	}
+	return true
+}
+
+/*
+- Reset: Thay toàn bộ danh sách file (ví dụ sau khi quét lại thư mục), giữ nguyên Cache
+- Snapshot mới được build xong mới swap, Search đang chạy không bị ảnh hưởng
+*/
+func (s *Searcher) Reset(items []string) {
+	s.swapIndex(buildIndex(items, &s.config))
+}
+
+// swapIndex: Thay snapshot đã build sẵn, xếp hàng sau các writer khác
+func (s *Searcher) swapIndex(next *searchIndex) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.index.Store(next)
+}
+
+/*
+- Len: Số file đang có trong index
+*/
+func (s *Searcher) Len() int {
+	return len(s.index.Load().originals)
+}
+
+/*
+- Items: Bản copy danh sách file hiện tại, theo đúng thứ tự Index
+*/
+func (s *Searcher) Items() []string {
+	return append([]string(nil), s.index.Load().originals...)
+}
+
+/*
+- Contains: Kiểm tra file có trong index không
+*/
+func (s *Searcher) Contains(item string) bool {
+	_, exists := s.index.Load().keyToIdx[item]
+	return exists
+}
+
+/*
+- Originals: Bản copy danh sách Display hiện tại, theo đúng thứ tự Index
+
+Deprecated: Trước đây là field Searcher.Originals, bị bỏ khi chuyển sang snapshot copy-on-write. Dùng Items
+*/
+func (s *Searcher) Originals() []string {
+	return s.Items()
+}
+
+/*
+- Normalized: Bản copy chuỗi đã chuẩn hóa (Primary + Secondary) của từng item, theo đúng thứ tự Index
+
+Deprecated: Trước đây là field Searcher.Normalized. Chỉ để code cũ chạy tiếp, mỗi lần gọi copy cả danh sách
+*/
+func (s *Searcher) Normalized() []string {
+	return append([]string(nil), s.index.Load().normalized...)
+}
+
+/*
+- FilenamesOnly: Bản copy trường chính (tên file) đã chuẩn hóa của từng item, theo đúng thứ tự Index
+
+Deprecated: Trước đây là field Searcher.FilenamesOnly. Chỉ để code cũ chạy tiếp, mỗi lần gọi copy cả danh sách
+*/
+func (s *Searcher) FilenamesOnly() []string {
+	return append([]string(nil), s.index.Load().filenamesOnly...)
+}
+
+/*
+- FilePathToIdx: Bản copy map Key → Index
+
+Deprecated: Trước đây là field Searcher.FilePathToIdx. Dùng Contains, hoặc Items để tự dựng map
+*/
+func (s *Searcher) FilePathToIdx() map[string]int {
+	return maps.Clone(s.index.Load().keyToIdx)
 }
 
 /*
@@ -1386,6 +3321,39 @@ func (s *Searcher) RecordSelection(query, filePath string) {
 	}
 }
 
+/*
+- SearchAs: Như Search nhưng xếp hạng theo lịch sử của riêng userID (trộn với cache chung, xem CacheRegistry)
+- Cần phân trang/ctx thì dùng SearchContext với SearchOptions.UserID
+*/
+func (s *Searcher) SearchAs(userID, query string) []string {
+	page, _ := s.SearchWithOptions(query, SearchOptions{UserID: userID})
+	results := make([]string, 0, len(page.Results))
+	for _, m := range page.Results {
+		results = append(results, m.Str)
+	}
+	return results
+}
+
+/*
+- RecordSelectionAs: Lưu lượt chọn vào cache riêng của userID, đồng thời vào cache chung
+- Cache chung vẫn học từ mọi user để làm lớp dự phòng cho user mới
+- Khi trộn cho chính user đó, phần này được trừ khỏi cache chung nên không bị tính 2 lần (xem CacheRegistry.blend)
+- userID rỗng thì giống RecordSelection
+*/
+func (s *Searcher) RecordSelectionAs(userID, query, filePath string) {
+	if userID != "" && s.Users != nil {
+		s.Users.Get(userID).RecordSelection(query, filePath)
+	}
+	s.RecordSelection(query, filePath)
+}
+
+/*
+- Config: Trả về bản copy của Config đang dùng
+*/
+func (s *Searcher) Config() Config {
+	return s.config
+}
+
 /*
 - GetCache: Lấy object cache
 - Ví dụ:
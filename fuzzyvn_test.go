@@ -1,14 +1,21 @@
 package fuzzyvn
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
 // Thay đổi: Trỏ vào thư mục thay vì file .txt
@@ -94,6 +101,34 @@ func TestNormalize_YI_NotEquivalent(t *testing.T) {
 	}
 }
 
+func TestNormalizeWithOffsets(t *testing.T) {
+	tests := []struct {
+		input   string
+		norm    string
+		offsets []int
+	}{
+		{"Main.go", "main.go", []int{0, 1, 2, 3, 4, 5, 6}},
+		{"Báo", "bao", []int{0, 1, 3}},
+		{"Đường", "duong", []int{0, 2, 4, 7, 8}},
+		// NFD (macOS): "a" + dấu sắc tổ hợp -> cùng trỏ về byte đầu của "a"
+		{"Ba\u0301o", "bao", []int{0, 1, 4}},
+		{"", "", []int{}},
+	}
+
+	for _, tt := range tests {
+		norm, offsets := NormalizeWithOffsets(tt.input)
+		if norm != tt.norm {
+			t.Errorf("NormalizeWithOffsets(%q) = %q, muốn %q", tt.input, norm, tt.norm)
+		}
+		if norm != Normalize(tt.input) {
+			t.Errorf("NormalizeWithOffsets(%q) = %q khác Normalize = %q", tt.input, norm, Normalize(tt.input))
+		}
+		if !slices.Equal(offsets, tt.offsets) {
+			t.Errorf("NormalizeWithOffsets(%q) offsets = %v, muốn %v", tt.input, offsets, tt.offsets)
+		}
+	}
+}
+
 func TestLevenshteinRatio(t *testing.T) {
 	tests := []struct {
 		s1, s2   string
@@ -108,6 +143,12 @@ func TestLevenshteinRatio(t *testing.T) {
 		{"main", "mian", 2},
 		{"kitten", "sitting", 3},
 		{"hello", "hallo", 1},
+		// Tính theo ký tự, không theo byte
+		{"中文", "中国", 1},
+		{"привет", "превет", 1},
+		{"สวัสดี", "สวัสดิ", 1},
+		{"năm", "nấm", 1},
+		{"中", "", 1},
 	}
 
 	for _, tt := range tests {
@@ -118,6 +159,83 @@ func TestLevenshteinRatio(t *testing.T) {
 	}
 }
 
+func TestDamerauLevenshtein(t *testing.T) {
+	tests := []struct {
+		s1, s2   string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"main", "mian", 1},
+		{"config", "conifg", 1},
+		{"ab", "ba", 1},
+		{"abcd", "badc", 2},
+		{"ca", "abc", 3}, // Optimal String Alignment: đã đổi chỗ thì không chèn thêm vào giữa
+		{"kitten", "sitting", 3},
+		{"中文", "文中", 1},
+		{"привет", "пирвет", 1},
+	}
+
+	for _, tt := range tests {
+		if result := DamerauLevenshtein(tt.s1, tt.s2); result != tt.expected {
+			t.Errorf("DamerauLevenshtein(%q, %q) = %d, muốn %d", tt.s1, tt.s2, result, tt.expected)
+		}
+		if result := DamerauLevenshtein(tt.s2, tt.s1); result != tt.expected {
+			t.Errorf("DamerauLevenshtein(%q, %q) = %d, muốn %d", tt.s2, tt.s1, result, tt.expected)
+		}
+	}
+}
+
+// damerauMatrix: Bản tính cả ma trận, không tối ưu gì, để so với editDistance
+func damerauMatrix(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func TestBoundedDamerauLevenshtein(t *testing.T) {
+	rng := rand.New(rand.NewSource(20))
+	// Có cả ký tự nhiều byte để đi qua nhánh []rune
+	alphabet := []rune("abcd中ж")
+	random := func() string {
+		runes := make([]rune, rng.Intn(9))
+		for i := range runes {
+			runes[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(runes)
+	}
+	for range 5000 {
+		s1, s2 := random(), random()
+		want := damerauMatrix([]rune(s1), []rune(s2))
+		if got := DamerauLevenshtein(s1, s2); got != want {
+			t.Fatalf("DamerauLevenshtein(%q, %q) = %d, muốn %d", s1, s2, got, want)
+		}
+		for maxDist := 0; maxDist <= 4; maxDist++ {
+			if got, capped := BoundedDamerauLevenshtein(s1, s2, maxDist), min(want, maxDist+1); got != capped {
+				t.Fatalf("BoundedDamerauLevenshtein(%q, %q, %d) = %d, muốn %d", s1, s2, maxDist, got, capped)
+			}
+		}
+	}
+}
+
 func TestNewSearcher(t *testing.T) {
 	files := []string{
 		"/home/user/main.go",
@@ -126,17 +244,24 @@ func TestNewSearcher(t *testing.T) {
 	}
 
 	searcher := NewSearcher(files)
+	ix := searcher.index.Load()
 
-	if len(searcher.Originals) != 3 {
-		t.Errorf("Originals có %d phần tử, muốn 3", len(searcher.Originals))
+	if searcher.Len() != 3 || len(ix.originals) != 3 {
+		t.Errorf("originals có %d phần tử, muốn 3", len(ix.originals))
 	}
 
-	if len(searcher.Normalized) != 3 {
-		t.Errorf("Normalized có %d phần tử, muốn 3", len(searcher.Normalized))
+	if len(ix.normalized) != 3 {
+		t.Errorf("normalized có %d phần tử, muốn 3", len(ix.normalized))
 	}
 
-	if len(searcher.FilenamesOnly) != 3 {
-		t.Errorf("FilenamesOnly có %d phần tử, muốn 3", len(searcher.FilenamesOnly))
+	if len(ix.filenamesOnly) != 3 {
+		t.Errorf("filenamesOnly có %d phần tử, muốn 3", len(ix.filenamesOnly))
+	}
+
+	// Sửa slice đầu vào không được ảnh hưởng tới index
+	files[0] = "/changed.go"
+	if searcher.Contains("/changed.go") || !searcher.Contains("/home/user/main.go") {
+		t.Error("Searcher không được dùng chung mảng với slice đầu vào")
 	}
 
 	if searcher.Cache == nil {
@@ -235,6 +360,792 @@ func TestSearcher_Search_Typo(t *testing.T) {
 	}
 }
 
+func TestSearcher_SearchResults_Breakdown(t *testing.T) {
+	files := []string{
+		"/project/main.go",
+		"/project/main_test.go",
+		"/project/config.yaml",
+	}
+
+	searcher := NewSearcher(files)
+	searcher.RecordSelection("main", "/project/main_test.go")
+
+	results := searcher.SearchResults("main")
+	if len(results) == 0 {
+		t.Fatal("SearchResults('main') không trả về kết quả")
+	}
+
+	for _, r := range results {
+		if files[r.Index] != r.Str {
+			t.Errorf("Index %d không khớp với %q", r.Index, r.Str)
+		}
+		bd := r.Breakdown
+		if sum := bd.Fuzzy + bd.WordBonus + bd.Levenshtein + bd.CacheBoost; sum != r.Score {
+			t.Errorf("%q: tổng breakdown = %d, Score = %d", r.Str, sum, r.Score)
+		}
+	}
+
+	if results[0].Str != "/project/main_test.go" {
+		t.Fatalf("File đã cache phải ở đầu, got %q", results[0].Str)
+	}
+	if results[0].Breakdown.CacheBoost == 0 {
+		t.Error("File đã cache phải có CacheBoost > 0")
+	}
+
+	for _, r := range results[1:] {
+		if r.Breakdown.CacheBoost != 0 {
+			t.Errorf("%q không được cache nhưng có CacheBoost = %d", r.Str, r.Breakdown.CacheBoost)
+		}
+	}
+}
+
+func TestSearcher_SearchResults_TypoBreakdown(t *testing.T) {
+	searcher := NewSearcher([]string{"/project/main.go", "/project/config.yaml"})
+
+	results := searcher.SearchResults("mian")
+	if len(results) == 0 || results[0].Str != "/project/main.go" {
+		t.Fatalf("SearchResults('mian') phải trả về main.go đầu tiên, got %v", results)
+	}
+	if results[0].Breakdown.Levenshtein == 0 {
+		t.Error("Kết quả từ sửa lỗi chính tả phải có điểm Levenshtein")
+	}
+	// "mian" → "main" là 1 lần đổi chỗ, chỉ tính 1 lỗi; "main.go" bỏ dấu chấm ("maingo") dài hơn query 2 ký tự
+	cfg := DefaultConfig()
+	if want := cfg.TypoBaseScore - cfg.TypoEditPenalty - 2*cfg.TypoLengthPenalty; results[0].Breakdown.Levenshtein != want {
+		t.Errorf("Levenshtein = %d, muốn %d (đổi chỗ tính 1 lỗi)", results[0].Breakdown.Levenshtein, want)
+	}
+}
+
+func TestFuzzyFindWithPositions(t *testing.T) {
+	targets := []string{"config.yaml", "main_test.go", "readme.md"}
+
+	matches := FuzzyFindWithPositions("mt", targets)
+	if len(matches) != 1 || matches[0].Index != 1 {
+		t.Fatalf("FuzzyFindWithPositions('mt') = %v, muốn chỉ khớp main_test.go", matches)
+	}
+	// m ở đầu từ, t ở đầu từ "test" (sau dấu _)
+	if !slices.Equal(matches[0].Positions, []int{0, 5}) {
+		t.Errorf("Positions = %v, muốn [0 5]", matches[0].Positions)
+	}
+
+	if plain := FuzzyFind("mt", targets); plain[0].Positions != nil {
+		t.Error("FuzzyFind không được trả về Positions")
+	}
+}
+
+func TestFuzzyFindParallelWithPositions(t *testing.T) {
+	targets := generateTestFiles(5000)
+
+	matches := FuzzyFindParallelWithPositions("main", targets)
+	if len(matches) == 0 {
+		t.Fatal("FuzzyFindParallelWithPositions('main') không trả về kết quả")
+	}
+	for _, m := range matches {
+		runes := []rune(targets[m.Index])
+		if len(m.Positions) != 4 {
+			t.Fatalf("%q: Positions = %v, muốn 4 vị trí", targets[m.Index], m.Positions)
+		}
+		for i, p := range m.Positions {
+			if runes[p] != rune("main"[i]) {
+				t.Fatalf("%q: vị trí %d là %q, muốn %q", targets[m.Index], p, runes[p], "main"[i])
+			}
+		}
+	}
+}
+
+func TestSearcher_SearchResults_Positions(t *testing.T) {
+	files := []string{
+		"/docs/Báo_cáo_tháng_1.pdf",
+		"/docs/Hợp_đồng_thuê_nhà.docx",
+	}
+	searcher := NewSearcher(files)
+
+	results := searcher.SearchResults("bao")
+	if len(results) == 0 || results[0].Str != files[0] {
+		t.Fatalf("SearchResults('bao') phải trả về %q đầu tiên", files[0])
+	}
+
+	var highlighted strings.Builder
+	for _, p := range results[0].Positions {
+		r, _ := utf8.DecodeRuneInString(results[0].Str[p:])
+		highlighted.WriteRune(r)
+	}
+	if highlighted.String() != "Báo" {
+		t.Errorf("Highlight = %q, muốn \"Báo\" (positions %v)", highlighted.String(), results[0].Positions)
+	}
+}
+
+// checkIndexConsistent: Kiểm tra các slice và map trong snapshot khớp nhau
+func checkIndexConsistent(t *testing.T, ix *searchIndex) {
+	t.Helper()
+	if len(ix.normalized) != len(ix.originals) || len(ix.filenamesOnly) != len(ix.originals) || len(ix.accented) != len(ix.originals) || len(ix.initials) != len(ix.originals) {
+		t.Fatalf("Độ dài không khớp: originals=%d normalized=%d filenamesOnly=%d accented=%d initials=%d",
+			len(ix.originals), len(ix.normalized), len(ix.filenamesOnly), len(ix.accented), len(ix.initials))
+	}
+	if ix.typed && (len(ix.docs) != len(ix.originals) || len(ix.values) != len(ix.originals) || len(ix.fieldNorms) != len(ix.originals) ||
+		len(ix.fieldEnds) != len(ix.originals) || len(ix.fields.spans) != len(ix.originals)) {
+		t.Fatalf("docs=%d values=%d fieldNorms=%d fieldEnds=%d fields=%d, originals=%d",
+			len(ix.docs), len(ix.values), len(ix.fieldNorms), len(ix.fieldEnds), len(ix.fields.spans), len(ix.originals))
+	}
+	if len(ix.keyToIdx) != len(ix.originals) {
+		t.Fatalf("keyToIdx có %d phần tử, originals có %d", len(ix.keyToIdx), len(ix.originals))
+	}
+	if ix.prefilter != (ix.masks != nil) || (ix.prefilter && len(ix.masks) != len(ix.originals)) {
+		t.Fatalf("prefilter = %v nhưng masks có %d phần tử, originals có %d", ix.prefilter, len(ix.masks), len(ix.originals))
+	}
+	if len(ix.targets.spans) != len(ix.originals) {
+		t.Fatalf("targets có %d span, originals có %d", len(ix.targets.spans), len(ix.originals))
+	}
+	if ix.typo != nil {
+		if len(ix.typo.ref) != len(ix.originals) {
+			t.Fatalf("typo index có %d item, originals có %d", len(ix.typo.ref), len(ix.originals))
+		}
+		for idx, r := range ix.typo.ref {
+			if (r >= 0 && ix.typo.slots[r] != int32(idx)) || (r < 0 && ix.typo.pending[^r] != int32(idx)) {
+				t.Fatalf("typo index: ref[%d] = %d không trỏ ngược về item", idx, r)
+			}
+		}
+	}
+	for key, idx := range ix.keyToIdx {
+		doc := ix.doc(idx)
+		if doc.Key != key || ix.originals[idx] != doc.Display {
+			t.Errorf("keyToIdx[%q] = %d nhưng doc = %+v, originals[%d] = %q", key, idx, doc, idx, ix.originals[idx])
+		}
+		normStr, normPrimary := normalizeDoc(doc, ix.primaryField)
+		if ix.normalized[idx] != normStr || ix.filenamesOnly[idx] != normPrimary {
+			t.Errorf("normalized/filenamesOnly của %q không khớp", key)
+		}
+		if ix.accented[idx] != accentDoc(doc) {
+			t.Errorf("accented của %q không khớp", key)
+		}
+		if ix.initials[idx] != acronymOf(primaryOf(doc, ix.primaryField), normPrimary) {
+			t.Errorf("initials của %q không khớp", key)
+		}
+		if ix.typed && !slices.Equal(ix.fieldNorms[idx], normalizeFields(doc)) {
+			t.Errorf("fieldNorms của %q không khớp", key)
+		}
+		if ix.typed {
+			norms := normalizeFields(doc)
+			if !slices.Equal(ix.fieldEnds[idx], fieldEnds(norms)) {
+				t.Errorf("fieldEnds của %q không khớp", key)
+			}
+			if got := arenaString(&ix.fields, idx); got != strings.Join(norms, "") {
+				t.Errorf("fields của %q = %q, muốn %q", key, got, strings.Join(norms, ""))
+			}
+		}
+		if ix.prefilter && ix.masks[idx] != newCharMask(normStr, normPrimary) {
+			t.Errorf("masks của %q không khớp", key)
+		}
+		if got := arenaString(&ix.targets, idx); got != normStr {
+			t.Errorf("targets của %q = %q, muốn %q", key, got, normStr)
+		}
+	}
+}
+
+func TestSearcher_Add(t *testing.T) {
+	searcher := NewSearcher([]string{"/project/main.go"})
+
+	searcher.Add("/project/config.yaml", "/project/main.go")
+
+	if searcher.Len() != 2 {
+		t.Fatalf("Len() = %d, muốn 2 (file trùng phải bị bỏ qua)", searcher.Len())
+	}
+	checkIndexConsistent(t, searcher.index.Load())
+
+	if !slices.Contains(searcher.Search("config"), "/project/config.yaml") {
+		t.Error("Search('config') phải tìm thấy file vừa Add")
+	}
+}
+
+func TestSearcher_DeprecatedAccessors(t *testing.T) {
+	searcher := NewSearcher([]string{"/a/Main.go", "/a/Báo_cáo.pdf"})
+
+	if got := searcher.Originals(); !slices.Equal(got, []string{"/a/Main.go", "/a/Báo_cáo.pdf"}) {
+		t.Errorf("Originals() = %v", got)
+	}
+	if got := searcher.FilenamesOnly(); !slices.Equal(got, []string{"main.go", "bao_cao.pdf"}) {
+		t.Errorf("FilenamesOnly() = %v", got)
+	}
+	if got := searcher.Normalized(); len(got) != 2 || !strings.HasPrefix(got[1], "bao_cao.pdf") {
+		t.Errorf("Normalized() = %v", got)
+	}
+	idx := searcher.FilePathToIdx()
+	if len(idx) != 2 || idx["/a/Báo_cáo.pdf"] != 1 {
+		t.Errorf("FilePathToIdx() = %v", idx)
+	}
+
+	// Là bản copy: sửa không ảnh hưởng Searcher
+	searcher.Originals()[0] = "/x"
+	delete(idx, "/a/Main.go")
+	if !searcher.Contains("/a/Main.go") || searcher.Items()[0] != "/a/Main.go" {
+		t.Error("Sửa bản copy làm đổi Searcher")
+	}
+}
+
+func TestSearcher_Remove(t *testing.T) {
+	files := []string{"/a/main.go", "/a/config.yaml", "/a/readme.md"}
+	searcher := NewSearcher(files)
+
+	searcher.Remove("/a/main.go", "/not/exist.go")
+
+	if searcher.Len() != 2 {
+		t.Fatalf("Len() = %d, muốn 2", searcher.Len())
+	}
+	checkIndexConsistent(t, searcher.index.Load())
+
+	if slices.Contains(searcher.Search("main"), "/a/main.go") {
+		t.Error("File đã Remove không được xuất hiện trong kết quả")
+	}
+}
+
+func TestSearcher_Rename(t *testing.T) {
+	searcher := NewSearcher([]string{"/a/main.go", "/a/config.yaml"})
+	searcher.RecordSelection("main", "/a/main.go")
+	searcher.RecordSelection("main", "/a/main.go")
+
+	if !searcher.Rename("/a/main.go", "/b/app_main.go") {
+		t.Fatal("Rename phải thành công")
+	}
+	if searcher.Rename("/a/main.go", "/c/x.go") {
+		t.Error("Rename file không tồn tại phải trả về false")
+	}
+	if searcher.Rename("/a/config.yaml", "/b/app_main.go") {
+		t.Error("Rename sang đường dẫn đã tồn tại phải trả về false")
+	}
+
+	if searcher.Contains("/a/main.go") {
+		t.Error("Đường dẫn cũ phải bị xóa khỏi index")
+	}
+	checkIndexConsistent(t, searcher.index.Load())
+
+	results := searcher.SearchResults("main")
+	if len(results) == 0 || results[0].Str != "/b/app_main.go" {
+		t.Fatalf("Search('main') phải trả về file đã đổi tên, got %v", results)
+	}
+	if results[0].Breakdown.CacheBoost == 0 {
+		t.Error("Boost đã học phải được giữ lại sau Rename")
+	}
+}
+
+func TestSearcher_Snapshot_Immutable(t *testing.T) {
+	searcher := NewSearcher([]string{"/a/main.go", "/a/config.yaml"})
+	before := searcher.index.Load()
+
+	searcher.Add("/a/new.go")
+	searcher.Remove("/a/main.go")
+	searcher.Rename("/a/config.yaml", "/a/settings.yaml")
+
+	// Snapshot cũ (mà 1 Search đang chạy có thể đang giữ) không được thay đổi
+	if !slices.Equal(before.originals, []string{"/a/main.go", "/a/config.yaml"}) {
+		t.Errorf("Snapshot cũ bị sửa: %v", before.originals)
+	}
+	checkIndexConsistent(t, before)
+	checkIndexConsistent(t, searcher.index.Load())
+}
+
+func TestSearcher_Reset(t *testing.T) {
+	searcher := NewSearcher([]string{"/a/main.go"})
+	searcher.RecordSelection("config", "/b/config.yaml")
+
+	searcher.Reset([]string{"/b/config.yaml", "/b/readme.md"})
+
+	if !slices.Equal(searcher.Items(), []string{"/b/config.yaml", "/b/readme.md"}) {
+		t.Errorf("Items() sau Reset = %v", searcher.Items())
+	}
+	if searcher.Cache.Size() != 1 {
+		t.Error("Reset phải giữ nguyên Cache")
+	}
+}
+
+func TestQueryCache_RenamePath_Merge(t *testing.T) {
+	cache := NewQueryCache()
+	cache.RecordSelection("main", "/old.go")
+	cache.RecordSelection("main", "/new.go")
+	cache.RecordSelection("main", "/new.go")
+
+	cache.RenamePath("/old.go", "/new.go")
+
+	files := cache.GetCachedFiles("main", 5)
+	if len(files) != 1 || files[0] != "/new.go" {
+		t.Fatalf("GetCachedFiles sau RenamePath = %v, muốn [/new.go]", files)
+	}
+	if got := cache.GetBoostScores("main")["/new.go"]; got != 5000*3 {
+		t.Errorf("Boost sau khi gộp = %d, muốn %d", got, 5000*3)
+	}
+}
+
+func TestSearcher_ConcurrentUpdates(t *testing.T) {
+	searcher := NewSearcher(generateTestFiles(500))
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				path := fmt.Sprintf("/new/%d/main_%d.go", w, i)
+				searcher.Add(path)
+				searcher.Rename(path, path+".bak")
+				searcher.Remove(path + ".bak")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				searcher.Search("main")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if searcher.Len() != 500 {
+		t.Errorf("Len() = %d, muốn 500", searcher.Len())
+	}
+	checkIndexConsistent(t, searcher.index.Load())
+}
+
+func TestSearcher_SearchWithOptions_Pagination(t *testing.T) {
+	searcher := NewSearcher(generateTestFiles(300))
+
+	all, err := searcher.SearchWithOptions("main", SearchOptions{Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Total != len(all.Results) || all.NextCursor != "" {
+		t.Fatalf("Trang duy nhất phải chứa toàn bộ %d kết quả, không có NextCursor", all.Total)
+	}
+	if all.Total <= 25 {
+		t.Fatalf("Cần hơn 25 kết quả để test phân trang, got %d", all.Total)
+	}
+
+	var paged []MatchResult
+	opts := SearchOptions{Limit: 7}
+	for {
+		page, err := searcher.SearchWithOptions("main", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != all.Total {
+			t.Errorf("Total = %d, muốn %d", page.Total, all.Total)
+		}
+		paged = append(paged, page.Results...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	if len(paged) != len(all.Results) {
+		t.Fatalf("Gộp các trang được %d kết quả, muốn %d", len(paged), len(all.Results))
+	}
+	for i := range paged {
+		if paged[i].Index != all.Results[i].Index || paged[i].Score != all.Results[i].Score {
+			t.Fatalf("Kết quả thứ %d khác nhau: %q vs %q", i, paged[i].Str, all.Results[i].Str)
+		}
+	}
+
+	byOffset, _ := searcher.SearchWithOptions("main", SearchOptions{Limit: 5, Offset: 10})
+	for i, r := range byOffset.Results {
+		if r.Index != all.Results[10+i].Index {
+			t.Errorf("Offset 10: kết quả thứ %d = %q, muốn %q", i, r.Str, all.Results[10+i].Str)
+		}
+	}
+
+	beyond, err := searcher.SearchWithOptions("main", SearchOptions{Offset: all.Total + 5})
+	if err != nil || len(beyond.Results) != 0 || beyond.NextCursor != "" {
+		t.Errorf("Offset vượt quá Total phải trả về trang rỗng, got %d kết quả", len(beyond.Results))
+	}
+
+	if got := searcher.SearchResults("main"); len(got) != DefaultLimit {
+		t.Errorf("SearchResults trả về %d kết quả, muốn %d", len(got), DefaultLimit)
+	}
+}
+
+func TestSearcher_SearchWithOptions_InvalidCursor(t *testing.T) {
+	searcher := NewSearcher(generateTestFiles(100))
+
+	page, err := searcher.SearchWithOptions("main", SearchOptions{Limit: 2})
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("Trang đầu phải có NextCursor, err = %v", err)
+	}
+
+	if _, err := searcher.SearchWithOptions("config", SearchOptions{Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Cursor của query khác phải lỗi ErrInvalidCursor, got %v", err)
+	}
+	if _, err := searcher.SearchWithOptions("main", SearchOptions{Cursor: "!!!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Cursor hỏng phải lỗi ErrInvalidCursor, got %v", err)
+	}
+	// Cursor gắn với query đã normalize, nên gõ có dấu/hoa thường vẫn dùng được
+	if _, err := searcher.SearchWithOptions("MAIN", SearchOptions{Cursor: page.NextCursor}); err != nil {
+		t.Errorf("Cursor phải dùng được với query tương đương, got %v", err)
+	}
+}
+
+func TestSearcher_SearchContext(t *testing.T) {
+	files := generateTestFiles(500)
+	searcher := NewSearcher(files)
+
+	page, err := searcher.SearchContext(context.Background(), "main", SearchOptions{Limit: 5})
+	if err != nil || len(page.Results) != 5 {
+		t.Fatalf("Background ctx: err = %v, %d kết quả", err, len(page.Results))
+	}
+	want, _ := searcher.SearchWithOptions("main", SearchOptions{Limit: 5})
+	if !reflect.DeepEqual(page, want) {
+		t.Error("SearchContext(Background) phải giống SearchWithOptions")
+	}
+
+	// Đã hủy từ trước: không chạy gì cả
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	page, err = searcher.SearchContext(ctx, "main", SearchOptions{})
+	if !errors.Is(err, context.Canceled) || len(page.Results) != 0 {
+		t.Errorf("ctx đã hủy: err = %v, %d kết quả", err, len(page.Results))
+	}
+
+	// Deadline đã qua
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if _, err := searcher.SearchContext(ctx, "main", SearchOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Hết hạn: err = %v, muốn DeadlineExceeded", err)
+	}
+}
+
+func TestSearcher_SearchContext_CancelMidway(t *testing.T) {
+	files := []string{"/src/main.go", "/docs/readme.md", "/src/other.go"}
+
+	// Hủy ngay sau bước fuzzy: các bước sau (word bonus, typo, cache) không được chạy
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := DefaultConfig()
+	cfg.Rankers = []Ranker{FuzzyRanker{}, RankerFunc(func(st *RankState) { cancel() }), WordBonusRanker{}, TypoRanker{}, CacheBoostRanker{}}
+	searcher := NewSearcherWithConfig(files, cfg)
+	searcher.RecordSelection("main", "/src/other.go")
+
+	page, err := searcher.SearchContext(ctx, "main", SearchOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, muốn context.Canceled", err)
+	}
+	// Vẫn trả về kết quả dở dang từ bước fuzzy
+	if len(page.Results) != 1 || page.Results[0].Str != "/src/main.go" {
+		t.Fatalf("Kết quả dở dang = %v", page.Results)
+	}
+	if bd := page.Results[0].Breakdown; bd.Fuzzy == 0 || bd.WordBonus != 0 || bd.CacheBoost != 0 {
+		t.Errorf("Chỉ được có điểm fuzzy, got %+v", bd)
+	}
+	if len(page.Results[0].Positions) == 0 {
+		t.Error("Kết quả dở dang vẫn phải có Positions")
+	}
+}
+
+func TestFuzzyFind_ContextCancelled(t *testing.T) {
+	targets := make([]string, 5000)
+	for i := range targets {
+		targets[i] = "main.go /src/main.go"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := fuzzyFind(ctx, &defaultConfig, "main", targets, nil, false); len(got) != 0 {
+		t.Errorf("fuzzyFind: ctx đã hủy nhưng vẫn chấm %d targets", len(got))
+	}
+	if got := fuzzyFindParallel(ctx, &defaultConfig, "main", targets, nil, false); len(got) != 0 {
+		t.Errorf("fuzzyFindParallel: ctx đã hủy nhưng vẫn chấm %d targets", len(got))
+	}
+	if got := fuzzyFindParallel(context.Background(), &defaultConfig, "main", targets, nil, false); len(got) != len(targets) {
+		t.Errorf("fuzzyFindParallel: %d kết quả, muốn %d", len(got), len(targets))
+	}
+
+	// TypoRanker cũng phải dừng
+	searcher := NewSearcher([]string{"/a/mian.go"})
+	st := searcher.newRankState(ctx, searcher.index.Load(), "main", "main", "")
+	TypoRanker{}.Rank(st)
+	if len(st.Candidates) != 0 {
+		t.Errorf("TypoRanker: ctx đã hủy nhưng vẫn có %d candidates", len(st.Candidates))
+	}
+}
+
+func TestTopK(t *testing.T) {
+	newResults := func() []MatchResult {
+		results := make([]MatchResult, 500)
+		for i := range results {
+			results[i] = MatchResult{Index: i, Str: strings.Repeat("x", i%7), Score: (i * 7919) % 97}
+		}
+		return results
+	}
+
+	full := newResults()
+	sort.Slice(full, func(i, j int) bool { return rankBefore(&full[i], &full[j]) })
+
+	for _, k := range []int{1, 5, 20, 499, 500} {
+		top := topK(newResults(), k)
+		if len(top) != k {
+			t.Fatalf("topK(%d) trả về %d phần tử", k, len(top))
+		}
+		for i := range top {
+			if top[i].Index != full[i].Index {
+				t.Fatalf("topK(%d)[%d] = %d, muốn %d", k, i, top[i].Index, full[i].Index)
+			}
+		}
+	}
+}
+
+func TestNewSearcherWithConfig(t *testing.T) {
+	files := []string{"/project/main server.go", "/project/config.yaml"}
+
+	def := NewSearcher(files).SearchResults("main")
+	if len(def) == 0 || def[0].Breakdown.WordBonus != DefaultWordMatchBonus {
+		t.Fatalf("Mặc định: WordBonus phải bằng %d, got %v", DefaultWordMatchBonus, def)
+	}
+
+	cfg := DefaultConfig()
+	cfg.WordMatchBonus = 100
+	cfg.TypoBaseScore = 20000
+	custom := NewSearcherWithConfig(files, cfg)
+	if !reflect.DeepEqual(custom.Config(), cfg) {
+		t.Error("Config() phải trả về đúng Config đã truyền vào")
+	}
+
+	results := custom.SearchResults("main")
+	if len(results) == 0 || results[0].Breakdown.WordBonus != 100 {
+		t.Fatalf("WordMatchBonus = 100: WordBonus phải bằng 100, got %v", results)
+	}
+	if results[0].Breakdown.Levenshtein != def[0].Breakdown.Levenshtein+10000 {
+		t.Errorf("TypoBaseScore = 20000: Levenshtein = %d, muốn %d",
+			results[0].Breakdown.Levenshtein, def[0].Breakdown.Levenshtein+10000)
+	}
+}
+
+func TestFuzzyScoreGreedy_ConfigWeights(t *testing.T) {
+	pattern := []rune("ms")
+	target := []rune("main_server")
+
+	def, _, _ := fuzzyScoreGreedyPositions(&defaultConfig, pattern, target, nil)
+	// 2 ký tự đều ở đầu từ, target dài hơn pattern 9 ký tự
+	if want := 2*DefaultWordStartBonus - 9*DefaultLengthPenalty; def != want {
+		t.Errorf("Điểm mặc định = %d, muốn %d", def, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.WordStartBonus = 5
+	cfg.LengthPenalty = 0
+	custom, _, _ := fuzzyScoreGreedyPositions(&cfg, pattern, target, nil)
+	if custom != 10 {
+		t.Errorf("Điểm với WordStartBonus = 5, LengthPenalty = 0 là %d, muốn 10", custom)
+	}
+}
+
+func TestNewSearcherWithConfig_DisableTypo(t *testing.T) {
+	files := []string{"/project/main.go", "/project/config.yaml"}
+
+	cfg := DefaultConfig()
+	cfg.TypoThresholdDivisor = 0
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	for _, r := range searcher.SearchResults("mian") {
+		if r.Breakdown.Levenshtein != 0 {
+			t.Errorf("TypoThresholdDivisor = 0 phải tắt Levenshtein, got %+v", r)
+		}
+	}
+}
+
+func TestNewSearcherWithConfig_ParallelThreshold(t *testing.T) {
+	files := generateTestFiles(3000)
+
+	sequential := DefaultConfig()
+	sequential.ParallelThreshold = len(files) + 1
+	parallel := DefaultConfig()
+	parallel.ParallelThreshold = 1
+	parallel.ParallelMinTargets = 1
+
+	a, _ := NewSearcherWithConfig(files, sequential).SearchWithOptions("utils", SearchOptions{Limit: 100})
+	b, _ := NewSearcherWithConfig(files, parallel).SearchWithOptions("utils", SearchOptions{Limit: 100})
+	if a.Total != b.Total {
+		t.Fatalf("Tuần tự có %d kết quả, parallel có %d", a.Total, b.Total)
+	}
+	for i := range a.Results {
+		if a.Results[i].Index != b.Results[i].Index {
+			t.Fatalf("Kết quả thứ %d khác nhau: %q vs %q", i, a.Results[i].Str, b.Results[i].Str)
+		}
+	}
+}
+
+func TestFuzzyScoreOptimal_DisagreesWithGreedy(t *testing.T) {
+	cases := []struct {
+		pattern, target string
+		greedyPos       []int
+		optimalPos      []int
+	}{
+		// Greedy chốt "a" ở đầu chuỗi rồi phải lấy "bc" rời rạc ở giữa
+		{"abc", "a_xbc_abc", []int{0, 3, 4}, []int{6, 7, 8}},
+	}
+
+	for _, c := range cases {
+		pattern, target := []rune(c.pattern), []rune(c.target)
+
+		greedy, greedyPos, ok1 := fuzzyScoreGreedyPositions(&defaultConfig, pattern, target, []int{})
+		optimal, optimalPos, ok2 := fuzzyScoreOptimal(&defaultConfig, pattern, target, []int{})
+		if !ok1 || !ok2 {
+			t.Fatalf("%q / %q: cả 2 thuật toán phải khớp", c.pattern, c.target)
+		}
+		if !slices.Equal(greedyPos, c.greedyPos) {
+			t.Errorf("%q / %q: greedy positions = %v, muốn %v", c.pattern, c.target, greedyPos, c.greedyPos)
+		}
+		if !slices.Equal(optimalPos, c.optimalPos) {
+			t.Errorf("%q / %q: optimal positions = %v, muốn %v", c.pattern, c.target, optimalPos, c.optimalPos)
+		}
+		if optimal <= greedy {
+			t.Errorf("%q / %q: optimal = %d phải cao hơn greedy = %d", c.pattern, c.target, optimal, greedy)
+		}
+	}
+
+	// Greedy nhảy tới "t" đầu từ của ".txt" nên không còn "1" phía sau -> trượt hẳn
+	if _, _, ok := fuzzyScoreGreedyPositions(&defaultConfig, []rune("pt1"), []rune("project_1.txt"), nil); ok {
+		t.Error("Greedy không được khớp 'pt1' với 'project_1.txt'")
+	}
+	_, pos, ok := fuzzyScoreOptimal(&defaultConfig, []rune("pt1"), []rune("project_1.txt"), []int{})
+	if !ok || !slices.Equal(pos, []int{0, 6, 8}) {
+		t.Errorf("Optimal 'pt1' / 'project_1.txt' = %v, %v, muốn [0 6 8]", pos, ok)
+	}
+
+	// Ví dụ trong comment của fuzzyScoreOptimal
+	greedy, _ := fuzzyScoreGreedy([]rune("abc"), []rune("a_xbc_abc"))
+	optimal, _, _ := fuzzyScoreOptimal(&defaultConfig, []rune("abc"), []rune("a_xbc_abc"), nil)
+	if greedy != 134 || optimal != 174 {
+		t.Errorf("greedy = %d, optimal = %d, muốn 134 và 174", greedy, optimal)
+	}
+}
+
+func TestFuzzyScoreOptimal_NeverWorseWithoutGapPenalty(t *testing.T) {
+	// Không phạt khoảng trống thì optimal tối ưu đúng hàm điểm của greedy -> không bao giờ thấp hơn
+	cfg := DefaultConfig()
+	cfg.GapStartPenalty = 0
+	cfg.GapExtendPenalty = 0
+
+	targets := append(generateTestFiles(200), generateVietnameseTestFiles(50)...)
+	for _, pattern := range []string{"main", "mn", "cfg", "sj", "bao", "pt1", "src_1"} {
+		p := []rune(Normalize(pattern))
+		for _, target := range targets {
+			tr := []rune(Normalize(target))
+			greedy, _, ok1 := fuzzyScoreGreedyPositions(&cfg, p, tr, nil)
+			optimal, pos, ok2 := fuzzyScoreOptimal(&cfg, p, tr, []int{})
+			// Greedy có thể trượt dù pattern là subsequence, optimal thì không
+			if ok1 && !ok2 {
+				t.Fatalf("%q / %q: greedy khớp nhưng optimal không", pattern, target)
+			}
+			if !ok1 {
+				continue
+			}
+			if optimal < greedy {
+				t.Fatalf("%q / %q: optimal = %d < greedy = %d", pattern, target, optimal, greedy)
+			}
+			for i, idx := range pos {
+				if tr[idx] != p[i] || (i > 0 && idx <= pos[i-1]) {
+					t.Fatalf("%q / %q: positions %v không hợp lệ", pattern, target, pos)
+				}
+			}
+		}
+	}
+}
+
+func TestSearcher_AlgorithmOptimal(t *testing.T) {
+	files := []string{"/p/a_xbc_abc", "/p/zzz"}
+
+	// Tắt typo pass để Breakdown.Fuzzy phản ánh đúng điểm fuzzy
+	cfg := DefaultConfig()
+	cfg.TypoThresholdDivisor = 0
+	greedyCfg := cfg
+	cfg.Algorithm = AlgorithmOptimal
+	results := NewSearcherWithConfig(files, cfg).SearchResults("abc")
+	if len(results) == 0 || results[0].Str != files[0] {
+		t.Fatalf("SearchResults('abc') = %v", results)
+	}
+	// Highlight phải là "abc" liền mạch ở cuối, không phải "a", "b", "c" rời rạc
+	if !slices.Equal(results[0].Positions, []int{9, 10, 11}) {
+		t.Errorf("Positions = %v, muốn [9 10 11]", results[0].Positions)
+	}
+
+	greedy := NewSearcherWithConfig(files, greedyCfg).SearchResults("abc")
+	if !slices.Equal(greedy[0].Positions, []int{3, 6, 7}) {
+		t.Errorf("Greedy positions = %v, muốn [3 6 7]", greedy[0].Positions)
+	}
+	if results[0].Breakdown.Fuzzy <= greedy[0].Breakdown.Fuzzy {
+		t.Errorf("Optimal fuzzy = %d phải cao hơn greedy = %d", results[0].Breakdown.Fuzzy, greedy[0].Breakdown.Fuzzy)
+	}
+}
+
+func TestSearcher_OptimalRerank(t *testing.T) {
+	files := []string{
+		"/p/q_a_b_c_abc",
+		"/p/q_abcxxxxxx",
+	}
+
+	cfg := DefaultConfig()
+	cfg.TypoThresholdDivisor = 0
+	plain := NewSearcherWithConfig(files, cfg).SearchResults("abc")
+
+	cfg.OptimalRerank = 10
+	reranked := NewSearcherWithConfig(files, cfg).SearchResults("abc")
+
+	if len(plain) != 2 || len(reranked) != 2 {
+		t.Fatalf("Cả 2 file phải khớp, got %d và %d", len(plain), len(reranked))
+	}
+	for _, r := range reranked {
+		normStr := []rune(Normalize(filepath.Base(r.Str) + " " + r.Str))
+		want, _, _ := fuzzyScoreOptimal(rerankConfig(&cfg), []rune("abc"), normStr, nil)
+		if r.Breakdown.Fuzzy != want || !r.Breakdown.Reranked {
+			t.Errorf("%q: Fuzzy sau rerank = %d (Reranked = %v), muốn điểm optimal không phạt khoảng trống %d", r.Str, r.Breakdown.Fuzzy, r.Breakdown.Reranked, want)
+		}
+	}
+}
+
+func TestSearcher_OptimalRerankConsistent(t *testing.T) {
+	files := []string{
+		"/p/q_a_b_c_abc",
+		"/p/q_abcxxxxxx",
+		"/p/xaxbxcx_abc",
+		"/p/a_b_c",
+		"/p/a_b_cx",
+	}
+	cfg := DefaultConfig()
+	cfg.TypoThresholdDivisor = 0
+	cfg.WordMatchBonus = 0 // Chỉ xét điểm fuzzy
+	plain := NewSearcherWithConfig(files, cfg).SearchResults("abc")
+	greedyPositions := make(map[string][]int)
+	greedyFuzzy := make(map[string]int)
+	for _, r := range plain {
+		greedyPositions[r.Str] = r.Positions
+		greedyFuzzy[r.Str] = r.Breakdown.Fuzzy
+	}
+
+	cfg.OptimalRerank = 3
+	results := NewSearcherWithConfig(files, cfg).SearchResults("abc")
+	if len(results) != len(files) {
+		t.Fatalf("Mọi file phải khớp, got %v", results)
+	}
+	reranked := 0
+	for i, r := range results {
+		// Điểm rerank cùng thang với greedy: thứ hạng luôn theo đúng điểm
+		if i > 0 && r.Breakdown.Fuzzy > results[i-1].Breakdown.Fuzzy {
+			t.Errorf("%q (Fuzzy %d) đứng sau %q (Fuzzy %d)", r.Str, r.Breakdown.Fuzzy, results[i-1].Str, results[i-1].Breakdown.Fuzzy)
+		}
+		// Chấm lại chỉ tìm cách đặt tốt hơn, không bao giờ làm điểm thấp hơn greedy ("a_b_c" có khoảng trống nhưng không bị phạt)
+		if r.Breakdown.Fuzzy < greedyFuzzy[r.Str] {
+			t.Errorf("%q: Fuzzy sau rerank = %d < greedy %d", r.Str, r.Breakdown.Fuzzy, greedyFuzzy[r.Str])
+		}
+		if r.Breakdown.Reranked {
+			reranked++
+			continue
+		}
+		// Item không được chấm lại thì highlight theo greedy
+		if !slices.Equal(r.Positions, greedyPositions[r.Str]) {
+			t.Errorf("%q: Positions = %v, muốn vị trí greedy %v", r.Str, r.Positions, greedyPositions[r.Str])
+		}
+	}
+	if reranked != cfg.OptimalRerank {
+		t.Errorf("Có %d item Reranked, muốn %d", reranked, cfg.OptimalRerank)
+	}
+}
+
 func TestQueryCache_RecordSelection(t *testing.T) {
 	cache := NewQueryCache()
 
@@ -328,6 +1239,261 @@ func TestQueryCache_GetAllRecentFiles(t *testing.T) {
 	}
 }
 
+// fakeClock: Đồng hồ giả cho test frecency
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestQueryCache_Frecency_HalfLife(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewQueryCache()
+	cache.SetClock(clock.now)
+	cache.SetHalfLife(24 * time.Hour)
+	cache.RecordSelection("main", "/main.go")
+
+	start := clock.t
+	tests := []struct {
+		age  time.Duration
+		want int
+	}{
+		{0, 5000},
+		{24 * time.Hour, 2500},
+		{48 * time.Hour, 1250},
+		{96 * time.Hour, 313},
+	}
+	for _, tt := range tests {
+		clock.t = start.Add(tt.age)
+		if got := cache.GetBoostScores("main")["/main.go"]; got != tt.want {
+			t.Errorf("Boost sau %v = %d, muốn %d", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestQueryCache_Frecency_RecentBeatsStale(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewQueryCache()
+	cache.SetClock(clock.now)
+
+	for range 50 {
+		cache.RecordSelection("report", "/old_report.pdf")
+	}
+	clock.advance(365 * 24 * time.Hour)
+	for range 5 {
+		cache.RecordSelection("report", "/new_report.pdf")
+	}
+
+	scores := cache.GetBoostScores("report")
+	if scores["/new_report.pdf"] <= scores["/old_report.pdf"] {
+		t.Errorf("File chọn 5 lần hôm nay phải thắng file chọn 50 lần năm ngoái, got %v", scores)
+	}
+	if files := cache.GetCachedFiles("report", 2); files[0] != "/new_report.pdf" {
+		t.Errorf("GetCachedFiles cũng phải theo frecency, got %v", files)
+	}
+
+	// Tắt phân rã thì quay lại chỉ đếm số lần chọn
+	cache.SetHalfLife(0)
+	scores = cache.GetBoostScores("report")
+	if scores["/old_report.pdf"] != 5000*50 || scores["/new_report.pdf"] != 5000*5 {
+		t.Errorf("SetHalfLife(0) phải tắt phân rã, got %v", scores)
+	}
+}
+
+func TestQueryCache_Frecency_EvictsStale(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewQueryCache()
+	cache.SetClock(clock.now)
+
+	// /stale.go chọn nhiều lần nhưng từ lâu, các file còn lại vừa chọn
+	for range 3 {
+		cache.RecordSelection("main", "/stale.go")
+	}
+	clock.advance(365 * 24 * time.Hour)
+	for _, f := range []string{"/a.go", "/b.go", "/c.go", "/d.go", "/e.go"} {
+		cache.RecordSelection("main", f)
+	}
+
+	files := cache.GetCachedFiles("main", 10)
+	if slices.Contains(files, "/stale.go") {
+		t.Errorf("Entry có frecency thấp nhất (/stale.go) phải bị xóa khi đầy, got %v", files)
+	}
+}
+
+func TestQueryCache_GetAllRecentFiles_ByTime(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewQueryCache()
+	cache.SetClock(clock.now)
+
+	cache.RecordSelection("q1", "/a.go")
+	clock.advance(time.Minute)
+	cache.RecordSelection("q1", "/b.go")
+	clock.advance(time.Minute)
+	cache.RecordSelection("q2", "/c.go")
+	clock.advance(time.Minute)
+	cache.RecordSelection("q1", "/a.go")
+
+	// q1 là query gần nhất, nhưng /b.go được chọn trước /c.go
+	want := []string{"/a.go", "/c.go", "/b.go"}
+	if got := cache.GetAllRecentFiles(5); !slices.Equal(got, want) {
+		t.Errorf("GetAllRecentFiles = %v, muốn %v", got, want)
+	}
+}
+
+func TestQueryCache_RecordRejection(t *testing.T) {
+	cache := NewQueryCache()
+	cache.RecordSelection("main", "/main.go")
+	cache.RecordSelection("main", "/main_old.go")
+
+	cache.RecordRejection("main", "/main_old.go")
+
+	scores := cache.GetBoostScores("main")
+	if scores["/main_old.go"] != -DefaultRejectPenalty {
+		t.Errorf("Boost file bị từ chối = %d, muốn %d", scores["/main_old.go"], -DefaultRejectPenalty)
+	}
+	if scores["/main.go"] != 5000 {
+		t.Errorf("File khác không bị ảnh hưởng, got %d", scores["/main.go"])
+	}
+	if got := cache.GetBoostScores("main ser")["/main_old.go"]; got >= 0 {
+		t.Errorf("Query tương tự cũng phải bị trừ điểm, got %d", got)
+	}
+	if files := cache.GetCachedFiles("main", 5); slices.Contains(files, "/main_old.go") {
+		t.Errorf("GetCachedFiles không được gợi ý file bị từ chối, got %v", files)
+	}
+
+	// Chọn lại nghĩa là đổi ý
+	cache.RecordSelection("main", "/main_old.go")
+	if got := cache.GetBoostScores("main")["/main_old.go"]; got != 5000 {
+		t.Errorf("Boost sau khi chọn lại = %d, muốn 5000", got)
+	}
+}
+
+func TestSearcher_Rejection_Demotes(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_old.go", "/src/config.go"}
+	searcher := NewSearcher(files)
+
+	searcher.Cache.RecordRejection("main", "/src/main.go")
+	searcher.Cache.RecordRejection("main", "/src/config.go")
+
+	results := searcher.SearchResults("main")
+	if len(results) != 2 {
+		t.Fatalf("File bị từ chối nhưng không khớp không được kéo vào kết quả, got %v", results)
+	}
+	if results[0].Str != "/src/main_old.go" || results[1].Str != "/src/main.go" {
+		t.Errorf("File bị từ chối phải xuống cuối, got %v, %v", results[0].Str, results[1].Str)
+	}
+	if results[1].Breakdown.CacheBoost >= 0 {
+		t.Errorf("CacheBoost của file bị từ chối phải âm, got %d", results[1].Breakdown.CacheBoost)
+	}
+}
+
+func TestSearcher_Pin(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_test.go", "/docs/README.md", "/docs/guide.md"}
+	searcher := NewSearcher(files)
+	searcher.RecordSelection("main", "/src/main_test.go")
+
+	searcher.Cache.Pin("Main", "/docs/guide.md")
+	searcher.Cache.Pin("main", "/docs/README.md")
+	searcher.Cache.Pin("main", "/docs/guide.md") // ghim lại không đổi vị trí
+	searcher.Cache.Pin("main", "/not/indexed.go")
+
+	if got := searcher.Cache.GetPins("MAIN"); !slices.Equal(got, []string{"/docs/guide.md", "/docs/README.md", "/not/indexed.go"}) {
+		t.Errorf("GetPins = %v", got)
+	}
+
+	results := searcher.SearchResults("main")
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Str)
+	}
+	want := []string{"/docs/guide.md", "/docs/README.md", "/src/main_test.go", "/src/main.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Search = %v, muốn %v", got, want)
+	}
+	if results[0].Breakdown.Pin != 1 || results[1].Breakdown.Pin != 2 || results[2].Breakdown.Pin != 0 {
+		t.Errorf("Breakdown.Pin = %d, %d, %d", results[0].Breakdown.Pin, results[1].Breakdown.Pin, results[2].Breakdown.Pin)
+	}
+
+	// Phân trang vẫn giữ file ghim ở trang đầu
+	page, _ := searcher.SearchWithOptions("main", SearchOptions{Limit: 1})
+	if page.Results[0].Str != "/docs/guide.md" {
+		t.Errorf("Trang đầu phải là file ghim, got %v", page.Results[0].Str)
+	}
+
+	// Ghim chỉ áp cho đúng query
+	if r := searcher.SearchResults("guide"); r[0].Breakdown.Pin != 0 {
+		t.Error("Query khác không được dùng ghim của main")
+	}
+
+	searcher.Cache.Unpin("main", "/docs/guide.md")
+	results = searcher.SearchResults("main")
+	if results[0].Str != "/docs/README.md" || results[0].Breakdown.Pin != 1 {
+		t.Errorf("Sau Unpin, README.md phải lên vị trí 1, got %v", results[0])
+	}
+
+	// Từ chối file đang ghim thì bỏ ghim
+	searcher.Cache.RecordRejection("main", "/docs/README.md")
+	if pins := searcher.Cache.GetPins("main"); slices.Contains(pins, "/docs/README.md") {
+		t.Errorf("RecordRejection phải bỏ ghim, got %v", pins)
+	}
+}
+
+func TestQueryCache_RenamePath_PinsAndRejections(t *testing.T) {
+	cache := NewQueryCache()
+	cache.Pin("main", "/old.go")
+	cache.RecordRejection("test", "/old.go")
+
+	cache.RenamePath("/old.go", "/new.go")
+
+	if pins := cache.GetPins("main"); !slices.Equal(pins, []string{"/new.go"}) {
+		t.Errorf("Pin phải đi theo file, got %v", pins)
+	}
+	if got := cache.GetBoostScores("test")["/new.go"]; got >= 0 {
+		t.Errorf("Từ chối phải đi theo file, got %d", got)
+	}
+}
+
+func TestQueryCache_MaxFeedback(t *testing.T) {
+	cache := NewQueryCache()
+	cache.SetMaxFeedback(2)
+
+	cache.RecordRejection("q1", "/a.go")
+	cache.Pin("q2", "/b.go")
+	cache.RecordRejection("q1", "/c.go") // q1 thành mới nhất
+	cache.Pin("q3", "/d.go")
+
+	if _, ok := cache.rejections["q1"]; !ok {
+		t.Errorf("q1 vừa được từ chối thêm, không được bị xóa")
+	}
+	if got := cache.GetPins("q2"); len(got) != 0 {
+		t.Errorf("q2 cũ nhất phải bị xóa khi vượt maxFeedback, got %v", got)
+	}
+	if got := cache.GetPins("q3"); len(got) != 1 {
+		t.Errorf("GetPins(q3) = %v", got)
+	}
+	if !reflect.DeepEqual(cache.feedbackOrder, []string{"q1", "q3"}) {
+		t.Errorf("feedbackOrder = %v, muốn [q1 q3]", cache.feedbackOrder)
+	}
+
+	// Bỏ hết từ chối và ghim thì query không còn chiếm chỗ
+	cache.RecordSelection("q1", "/a.go")
+	cache.RecordSelection("q1", "/c.go")
+	cache.Unpin("q3", "/d.go")
+	if len(cache.feedbackOrder) != 0 || len(cache.rejections) != 0 || len(cache.pins) != 0 {
+		t.Errorf("Sau khi bỏ hết: feedbackOrder = %v, rejections = %v, pins = %v", cache.feedbackOrder, cache.rejections, cache.pins)
+	}
+
+	cache.Pin("q4", "/e.go")
+	cache.Pin("q5", "/f.go")
+	cache.SetMaxFeedback(1)
+	if len(cache.pins) != 1 || len(cache.GetPins("q5")) != 1 {
+		t.Errorf("Giảm maxFeedback phải xóa ngay query cũ, pins = %v", cache.pins)
+	}
+}
+
 func TestQueryCache_LRU_Eviction(t *testing.T) {
 	cache := NewQueryCache()
 	cache.SetMaxQueries(3)
@@ -725,6 +1891,26 @@ func BenchmarkLevenshteinRatio(b *testing.B) {
 	}
 }
 
+func BenchmarkBoundedDamerauLevenshtein(b *testing.B) {
+	pairs := []struct{ a, b string }{
+		{"main", "mian"},
+		{"config", "conifg"},
+		{"moduleNameResolver", "mnr"},
+		{"hello", "hallo"},
+		{"báo cáo tài chính", "bao cao tai chinh"},
+	}
+
+	for _, maxDist := range []int{-1, 2} {
+		b.Run(fmt.Sprintf("max=%d", maxDist), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, p := range pairs {
+					editDistance(p.a, p.b, maxDist, true)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkRecordSelection(b *testing.B) {
 	cache := NewQueryCache()
 
@@ -735,14 +1921,40 @@ func BenchmarkRecordSelection(b *testing.B) {
 }
 
 func BenchmarkGetBoostScores(b *testing.B) {
-	cache := NewQueryCache()
-	for i := 0; i < 100; i++ {
-		cache.RecordSelection(fmt.Sprintf("query%d", i), fmt.Sprintf("/file%d.go", i))
+	// Query đa dạng như người dùng thật gõ: ghép 1-3 từ ngẫu nhiên
+	words := []string{"man", "hinh", "dell", "laptop", "iphone", "samsung", "tai", "nghe", "bluetooth", "ban", "phim",
+		"chuot", "gaming", "sac", "du", "phong", "loa", "op", "lung", "cap", "usb", "type", "pro", "max", "mini"}
+	rng := rand.New(rand.NewSource(1))
+	mixed := func(i int) string {
+		parts := make([]string, 1+rng.Intn(3))
+		for k := range parts {
+			parts[k] = words[rng.Intn(len(words))]
+		}
+		return fmt.Sprintf("%s %d", strings.Join(parts, " "), i)
 	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		cache.GetBoostScores("query50")
+	corpora := []struct {
+		name  string
+		query string
+		key   func(i int) string
+	}{
+		{"sequential", "query50", func(i int) string { return fmt.Sprintf("query%d", i) }},
+		{"mixed", "man hinh dell", mixed},
+	}
+	for _, corpus := range corpora {
+		for _, n := range []int{100, 50000} {
+			cache := NewQueryCache()
+			cache.SetMaxQueries(n)
+			for i := 0; i < n; i++ {
+				cache.RecordSelection(corpus.key(i), fmt.Sprintf("/file%d.go", i))
+			}
+
+			b.Run(fmt.Sprintf("%s/queries=%d", corpus.name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					cache.GetBoostScores(corpus.query)
+				}
+			})
+		}
 	}
 }
 
@@ -0,0 +1,296 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+item.go Structure:
+├── Types
+│   ├── ItemSearcher
+│   ├── ItemResult
+│   └── ItemPage
+└── ItemSearcher Methods
+
+	├── NewItemSearcher
+	├── NewItemSearcherWithConfig
+	├── docs (private)
+	├── Search
+	├── SearchResults
+	├── SearchWithOptions
+	├── SearchContext
+	├── wrapPage (private)
+	├── Add
+	├── Remove
+	├── Update
+	├── Reset
+	├── Len
+	├── Items
+	├── Get
+	├── Contains
+	├── RecordSelection
+	├── SearchAs
+	├── RecordSelectionAs
+	├── Users
+	├── Config
+	├── GetCache
+	└── ClearCache
+*/
+package fuzzyvn
+
+import "context"
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- ItemSearcher: Searcher cho item bất kỳ (sản phẩm, danh bạ, lệnh menu...), không chỉ file path
+- Caller cung cấp hàm toDoc để biết Key, chuỗi hiển thị, trường chính và các trường phụ của mỗi item
+- Cần trọng số riêng cho từng trường thì dùng Document.Fields (xem Field)
+- Kết quả trả về đúng kiểu T, không cần tra ngược từ string
+- Bên trong vẫn là Searcher (copy-on-write, Ranker chain, QueryCache...), item gốc nằm cùng snapshot nên luôn nhất quán với kết quả
+- Ví dụ:
+
+	type Product struct {
+		SKU   string
+		Name  string
+		Brand string
+	}
+
+	products := fuzzyvn.NewItemSearcher(list, func(p Product) fuzzyvn.Document {
+		return fuzzyvn.Document{Key: p.SKU, Display: p.Name, Primary: p.Name, Secondary: []string{p.Brand}}
+	})
+	for _, p := range products.Search("iphone") {
+		fmt.Println(p.SKU, p.Name)
+	}
+*/
+type ItemSearcher[T any] struct {
+	s     *Searcher
+	toDoc func(T) Document
+}
+
+/*
+- ItemResult: MatchResult kèm item gốc
+- Str là Document.Display, Positions tính trên Display
+*/
+type ItemResult[T any] struct {
+	MatchResult
+	Item T
+}
+
+// ItemPage: Giống SearchPage nhưng Results mang item gốc
+type ItemPage[T any] struct {
+	Results    []ItemResult[T]
+	Total      int
+	NextCursor string
+}
+
+// =============================================================================
+// ItemSearcher Methods
+// =============================================================================
+
+/*
+- NewItemSearcher: Tạo ItemSearcher mới với Config mặc định
+- items: Danh sách item cần index, item có Key trùng chỉ giữ cái đầu tiên
+- toDoc: Cách lấy Document từ 1 item, phải trả về cùng kết quả mỗi lần gọi cho cùng 1 item
+*/
+func NewItemSearcher[T any](items []T, toDoc func(T) Document) *ItemSearcher[T] {
+	return NewItemSearcherWithConfig(items, toDoc, DefaultConfig())
+}
+
+/*
+- NewItemSearcherWithConfig: Như NewItemSearcher nhưng với trọng số xếp hạng tùy chỉnh
+*/
+func NewItemSearcherWithConfig[T any](items []T, toDoc func(T) Document, cfg Config) *ItemSearcher[T] {
+	is := &ItemSearcher[T]{
+		s:     &Searcher{config: cfg, Cache: NewQueryCache(), Users: NewCacheRegistry()},
+		toDoc: toDoc,
+	}
+	docs, values := is.docs(items)
+	is.s.index.Store(buildDocIndex(docs, values, &cfg))
+	return is
+}
+
+// docs: Gọi toDoc cho từng item, trả về kèm item dưới dạng any để lưu vào snapshot
+func (is *ItemSearcher[T]) docs(items []T) ([]Document, []any) {
+	docs := make([]Document, len(items))
+	values := make([]any, len(items))
+	for i, item := range items {
+		docs[i] = is.toDoc(item)
+		values[i] = item
+	}
+	return docs, values
+}
+
+/*
+- Search: Trả về top 20 item phù hợp nhất
+*/
+func (is *ItemSearcher[T]) Search(query string) []T {
+	page, _ := is.SearchWithOptions(query, SearchOptions{})
+	items := make([]T, len(page.Results))
+	for i, r := range page.Results {
+		items[i] = r.Item
+	}
+	return items
+}
+
+/*
+- SearchResults: Top 20 kết quả kèm điểm số và item gốc
+*/
+func (is *ItemSearcher[T]) SearchResults(query string) []ItemResult[T] {
+	page, _ := is.SearchWithOptions(query, SearchOptions{})
+	return page.Results
+}
+
+/*
+- SearchWithOptions: Tìm kiếm có phân trang, xem Searcher.SearchWithOptions
+*/
+func (is *ItemSearcher[T]) SearchWithOptions(query string, opts SearchOptions) (ItemPage[T], error) {
+	return is.SearchContext(context.Background(), query, opts)
+}
+
+/*
+- SearchContext: Tìm kiếm có phân trang, hủy được qua ctx, xem Searcher.SearchContext
+*/
+func (is *ItemSearcher[T]) SearchContext(ctx context.Context, query string, opts SearchOptions) (ItemPage[T], error) {
+	page, ix, err := is.s.searchPage(ctx, query, opts)
+	return wrapPage[T](page, ix), err
+}
+
+// wrapPage: Gắn item gốc (lấy từ đúng snapshot đã dùng để search) vào từng kết quả
+func wrapPage[T any](page SearchPage, ix *searchIndex) ItemPage[T] {
+	out := ItemPage[T]{
+		Results:    make([]ItemResult[T], len(page.Results)),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+	for i, r := range page.Results {
+		out.Results[i] = ItemResult[T]{MatchResult: r, Item: ix.values[r.Index].(T)}
+	}
+	return out
+}
+
+/*
+- Add: Thêm item vào index, item có Key đã tồn tại sẽ được bỏ qua
+- An toàn khi gọi đồng thời với Search
+*/
+func (is *ItemSearcher[T]) Add(items ...T) {
+	is.s.addDocs(is.docs(items))
+}
+
+/*
+- Remove: Xóa item theo Key, Key không tồn tại sẽ được bỏ qua
+*/
+func (is *ItemSearcher[T]) Remove(keys ...string) {
+	is.s.Remove(keys...)
+}
+
+/*
+- Update: Thay item có Key = oldKey bằng item mới, giữ nguyên Index
+- Key mới khác Key cũ thì lịch sử chọn trong QueryCache cũng được chuyển sang Key mới (giống Searcher.Rename)
+- Trả về false nếu oldKey không tồn tại hoặc Key mới đã thuộc về item khác
+*/
+func (is *ItemSearcher[T]) Update(oldKey string, item T) bool {
+	return is.s.replaceDoc(oldKey, is.toDoc(item), item)
+}
+
+/*
+- Reset: Thay toàn bộ danh sách item, giữ nguyên Cache
+*/
+func (is *ItemSearcher[T]) Reset(items []T) {
+	docs, values := is.docs(items)
+	is.s.swapIndex(buildDocIndex(docs, values, &is.s.config))
+}
+
+/*
+- Len: Số item đang có trong index
+*/
+func (is *ItemSearcher[T]) Len() int {
+	return is.s.Len()
+}
+
+/*
+- Items: Bản copy danh sách item hiện tại, theo đúng thứ tự Index
+*/
+func (is *ItemSearcher[T]) Items() []T {
+	ix := is.s.index.Load()
+	items := make([]T, len(ix.values))
+	for i, v := range ix.values {
+		items[i] = v.(T)
+	}
+	return items
+}
+
+/*
+- Get: Lấy item theo Key
+*/
+func (is *ItemSearcher[T]) Get(key string) (T, bool) {
+	ix := is.s.index.Load()
+	idx, exists := ix.keyToIdx[key]
+	if !exists {
+		var zero T
+		return zero, false
+	}
+	return ix.values[idx].(T), true
+}
+
+/*
+- Contains: Kiểm tra Key có trong index không
+*/
+func (is *ItemSearcher[T]) Contains(key string) bool {
+	return is.s.Contains(key)
+}
+
+/*
+- RecordSelection: Lưu lại item user đã chọn (theo Key) để boost lần search sau
+*/
+func (is *ItemSearcher[T]) RecordSelection(query, key string) {
+	is.s.RecordSelection(query, key)
+}
+
+/*
+- SearchAs: Như Search nhưng theo lịch sử riêng của userID, xem Searcher.SearchAs
+*/
+func (is *ItemSearcher[T]) SearchAs(userID, query string) []T {
+	page, _ := is.SearchWithOptions(query, SearchOptions{UserID: userID})
+	items := make([]T, len(page.Results))
+	for i, r := range page.Results {
+		items[i] = r.Item
+	}
+	return items
+}
+
+/*
+- RecordSelectionAs: Lưu item userID đã chọn, xem Searcher.RecordSelectionAs
+*/
+func (is *ItemSearcher[T]) RecordSelectionAs(userID, query, key string) {
+	is.s.RecordSelectionAs(userID, query, key)
+}
+
+/*
+- Users: Registry cache riêng của từng user
+*/
+func (is *ItemSearcher[T]) Users() *CacheRegistry {
+	return is.s.Users
+}
+
+/*
+- Config: Trả về bản copy của Config đang dùng
+*/
+func (is *ItemSearcher[T]) Config() Config {
+	return is.s.Config()
+}
+
+/*
+- GetCache: QueryCache của ItemSearcher, key trong cache là Document.Key
+*/
+func (is *ItemSearcher[T]) GetCache() *QueryCache {
+	return is.s.GetCache()
+}
+
+/*
+- ClearCache: Xóa toàn bộ lịch sử chọn
+*/
+func (is *ItemSearcher[T]) ClearCache() {
+	is.s.ClearCache()
+}
@@ -0,0 +1,364 @@
+package fuzzyvn
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+type testProduct struct {
+	SKU   string
+	Name  string
+	Brand string
+	Tags  []string
+}
+
+func productDoc(p testProduct) Document {
+	return Document{
+		Key:       p.SKU,
+		Display:   p.Name,
+		Primary:   p.Name,
+		Secondary: append([]string{p.Brand}, p.Tags...),
+	}
+}
+
+func testProducts() []testProduct {
+	return []testProduct{
+		{SKU: "P1", Name: "Điện thoại iPhone 15", Brand: "Apple", Tags: []string{"smartphone"}},
+		{SKU: "P2", Name: "Máy tính xách tay", Brand: "Dell", Tags: []string{"laptop"}},
+		{SKU: "P3", Name: "Tai nghe không dây", Brand: "Sony"},
+		{SKU: "P1", Name: "Trùng SKU", Brand: "X"},
+	}
+}
+
+func TestItemSearcher_Search(t *testing.T) {
+	products := NewItemSearcher(testProducts(), productDoc)
+
+	if products.Len() != 3 {
+		t.Fatalf("Len() = %d, muốn 3 (SKU trùng phải bị bỏ qua)", products.Len())
+	}
+	checkIndexConsistent(t, products.s.index.Load())
+
+	got := products.Search("dien thoai")
+	if len(got) == 0 || got[0].SKU != "P1" {
+		t.Fatalf("Search('dien thoai') = %v", got)
+	}
+
+	// Trường phụ (Brand, Tags) vẫn tìm được
+	if got := products.Search("laptop"); len(got) == 0 || got[0].SKU != "P2" {
+		t.Errorf("Search('laptop') = %v, muốn P2", got)
+	}
+
+	results := products.SearchResults("tai nghe")
+	if len(results) == 0 || results[0].Item.SKU != "P3" {
+		t.Fatalf("SearchResults('tai nghe') = %v", results)
+	}
+	r := results[0]
+	if r.Str != "Tai nghe không dây" || r.Score != r.Breakdown.Total() {
+		t.Errorf("Str = %q, Score = %d", r.Str, r.Score)
+	}
+	// Positions tính trên Display
+	var highlighted strings.Builder
+	for _, p := range r.Positions {
+		highlighted.WriteByte(r.Str[p])
+	}
+	if highlighted.String() != "Tai nghe" {
+		t.Errorf("Highlight = %q, muốn \"Tai nghe\"", highlighted.String())
+	}
+}
+
+func TestItemSearcher_PositionsOnlyInDisplay(t *testing.T) {
+	// Khớp ở trường phụ (Brand) không có trong Display thì không highlight gì
+	products := NewItemSearcher(testProducts(), productDoc)
+	results := products.SearchResults("sony")
+	if len(results) == 0 || results[0].Item.SKU != "P3" {
+		t.Fatalf("SearchResults('sony') = %v", results)
+	}
+	if len(results[0].Positions) != 0 {
+		t.Errorf("Positions = %v, muốn rỗng", results[0].Positions)
+	}
+}
+
+func TestItemSearcher_Updates(t *testing.T) {
+	products := NewItemSearcher(testProducts()[:3], productDoc)
+	products.RecordSelection("tai", "P3")
+
+	products.Add(testProduct{SKU: "P4", Name: "Bàn phím cơ", Brand: "Keychron"}, testProduct{SKU: "P1", Name: "Trùng"})
+	if products.Len() != 4 {
+		t.Fatalf("Len() = %d, muốn 4", products.Len())
+	}
+	if p, ok := products.Get("P4"); !ok || p.Brand != "Keychron" {
+		t.Errorf("Get('P4') = %v, %v", p, ok)
+	}
+
+	// Update đổi Key: cache phải đi theo
+	if !products.Update("P3", testProduct{SKU: "P3-v2", Name: "Tai nghe không dây v2", Brand: "Sony"}) {
+		t.Fatal("Update P3 -> P3-v2 phải thành công")
+	}
+	if products.Contains("P3") || !products.Contains("P3-v2") {
+		t.Error("Key cũ phải biến mất, Key mới phải có")
+	}
+	if boosts := products.GetCache().GetBoostScores("tai"); boosts["P3-v2"] == 0 || boosts["P3"] != 0 {
+		t.Errorf("Cache phải chuyển sang Key mới, got %v", boosts)
+	}
+
+	// Update giữ nguyên Key chỉ đổi nội dung
+	if !products.Update("P2", testProduct{SKU: "P2", Name: "Laptop gaming", Brand: "Dell"}) {
+		t.Fatal("Update giữ Key phải thành công")
+	}
+	if !slices.ContainsFunc(products.Search("gaming"), func(p testProduct) bool { return p.SKU == "P2" }) {
+		t.Error("Search('gaming') phải thấy nội dung mới của P2")
+	}
+
+	// Key mới thuộc về item khác hoặc Key cũ không tồn tại
+	if products.Update("P2", testProduct{SKU: "P1"}) || products.Update("nope", testProduct{SKU: "P9"}) {
+		t.Error("Update phải thất bại")
+	}
+
+	products.Remove("P1", "nope")
+	if products.Len() != 3 || products.Contains("P1") {
+		t.Errorf("Remove P1 thất bại, Len() = %d", products.Len())
+	}
+	checkIndexConsistent(t, products.s.index.Load())
+
+	var skus []string
+	for _, p := range products.Items() {
+		skus = append(skus, p.SKU)
+	}
+	slices.Sort(skus)
+	if !slices.Equal(skus, []string{"P2", "P3-v2", "P4"}) {
+		t.Errorf("Items() = %v", skus)
+	}
+
+	products.Reset([]testProduct{{SKU: "Z", Name: "Zeta"}})
+	if products.Len() != 1 || products.Search("zeta")[0].SKU != "Z" {
+		t.Error("Reset thất bại")
+	}
+}
+
+func TestItemSearcher_SearchContext(t *testing.T) {
+	products := NewItemSearcher(testProducts(), productDoc)
+
+	page, err := products.SearchContext(context.Background(), "a", SearchOptions{Limit: 1})
+	if err != nil || len(page.Results) != 1 || page.NextCursor == "" {
+		t.Fatalf("page = %+v, err = %v", page, err)
+	}
+	next, err := products.SearchWithOptions("a", SearchOptions{Limit: 1, Cursor: page.NextCursor})
+	if err != nil || len(next.Results) != 1 || next.Results[0].Item.SKU == page.Results[0].Item.SKU {
+		t.Errorf("Trang 2 = %+v, err = %v", next, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := products.SearchContext(ctx, "a", SearchOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, muốn context.Canceled", err)
+	}
+}
+
+func TestItemSearcher_ConcurrentUpdates(t *testing.T) {
+	products := NewItemSearcher(testProducts()[:3], productDoc)
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := range 50 {
+				sku := string(rune('a'+w)) + strings.Repeat("x", i)
+				products.Add(testProduct{SKU: sku, Name: "Sản phẩm " + sku})
+				// Item trả về phải luôn khớp với Str của chính nó
+				for _, r := range products.SearchResults("san pham") {
+					if r.Item.Name != r.Str {
+						t.Errorf("Item %v không khớp Str %q", r.Item, r.Str)
+						return
+					}
+				}
+				products.Remove(sku)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if products.Len() != 3 {
+		t.Errorf("Len() = %d, muốn 3", products.Len())
+	}
+}
+
+func TestItemSearcher_ScorerSeesValue(t *testing.T) {
+	// Scorer đọc được item gốc qua RankState.Value để xếp hạng theo field riêng
+	cfg := DefaultConfig()
+	cfg.Scorers = []Scorer{ScorerFunc(func(st *RankState, idx int) int {
+		if p, ok := st.Value(idx).(testProduct); ok && p.Brand == "Dell" && st.Key(idx) == "P2" {
+			return 100000
+		}
+		return 0
+	})}
+	products := NewItemSearcherWithConfig(testProducts(), productDoc, cfg)
+
+	results := products.SearchResults("a")
+	if len(results) == 0 || results[0].Item.SKU != "P2" || results[0].Breakdown.Custom != 100000 {
+		t.Errorf("Scorer phải đẩy P2 lên đầu, got %v", results)
+	}
+
+	// Searcher theo path không có Value
+	var value any = "unset"
+	cfg.Scorers = []Scorer{ScorerFunc(func(st *RankState, idx int) int {
+		value = st.Value(idx)
+		return 0
+	})}
+	NewSearcherWithConfig([]string{"/a/main.go"}, cfg).Search("main")
+	if value != nil {
+		t.Errorf("Value() = %v, muốn nil với Searcher theo path", value)
+	}
+}
+
+type testDoc struct {
+	ID, Title, Path, Description string
+	Tags                         []string
+}
+
+func testDocFields(titleW, descW int) func(d testDoc) Document {
+	return func(d testDoc) Document {
+		return Document{
+			Key:     d.ID,
+			Display: d.Title,
+			Fields: []Field{
+				{Name: "title", Value: d.Title, Weight: titleW},
+				{Name: "path", Value: d.Path, Weight: 50},
+				{Name: "tags", Value: strings.Join(d.Tags, " "), Weight: 60},
+				{Name: "description", Value: d.Description, Weight: descW},
+			},
+		}
+	}
+}
+
+func TestItemSearcher_FieldWeights(t *testing.T) {
+	docs := []testDoc{
+		{ID: "a", Title: "Hướng dẫn cài đặt", Path: "/docs/setup", Description: "Báo cáo tài chính quý 1"},
+		{ID: "b", Title: "Báo cáo tài chính", Path: "/docs/finance", Description: "Tổng hợp số liệu"},
+	}
+
+	// Đặt MaxWordBonusCalc = 0 để chỉ so điểm fuzzy
+	cfg := DefaultConfig()
+	cfg.MaxWordBonusCalc = 0
+	cfg.TypoThresholdDivisor = 0
+
+	results := NewItemSearcherWithConfig(docs, testDocFields(100, 30), cfg).SearchResults("bao cao tai chinh")
+	if len(results) != 2 {
+		t.Fatalf("Phải có 2 kết quả, got %d", len(results))
+	}
+	if results[0].Item.ID != "b" || results[0].Breakdown.Field != "title" || results[1].Breakdown.Field != "description" {
+		t.Errorf("Khớp ở title phải hơn khớp ở description, got %s(%s) rồi %s(%s)",
+			results[0].Item.ID, results[0].Breakdown.Field, results[1].Item.ID, results[1].Breakdown.Field)
+	}
+
+	// Cùng 1 chuỗi khớp: điểm = điểm fuzzy của Field * trọng số / 100
+	titleScore, _, _ := fuzzyScoreGreedyPositions(&cfg, []rune("bao cao tai chinh"), []rune("bao cao tai chinh"), nil)
+	if results[0].Breakdown.Fuzzy != titleScore {
+		t.Errorf("Fuzzy = %d, muốn %d", results[0].Breakdown.Fuzzy, titleScore)
+	}
+	descScore, _, _ := fuzzyScoreGreedyPositions(&cfg, []rune("bao cao tai chinh"), []rune("bao cao tai chinh quy 1"), nil)
+	if results[1].Breakdown.Fuzzy != descScore*30/100 {
+		t.Errorf("Fuzzy = %d, muốn %d", results[1].Breakdown.Fuzzy, descScore*30/100)
+	}
+
+	// Đảo trọng số thì đảo thứ hạng
+	results = NewItemSearcherWithConfig(docs, testDocFields(10, 100), cfg).SearchResults("bao cao tai chinh")
+	if results[0].Item.ID != "a" {
+		t.Errorf("Description trọng số cao phải thắng, got %s", results[0].Item.ID)
+	}
+}
+
+func TestItemSearcher_FieldsAcrossAndPositions(t *testing.T) {
+	docs := []testDoc{
+		{ID: "a", Title: "Hợp đồng lao động", Path: "/hr/contracts", Tags: []string{"nhân sự"}},
+	}
+	cfg := DefaultConfig()
+	cfg.TypoThresholdDivisor = 0
+	products := NewItemSearcherWithConfig(docs, testDocFields(0, 0), cfg)
+
+	// Query rải qua title + tags: không Field nào khớp trọn nhưng vẫn phải tìm thấy
+	results := products.SearchResults("hop nhan")
+	if len(results) != 1 || results[0].Breakdown.Field != "" {
+		t.Fatalf("Khớp rải nhiều Field: %+v", results)
+	}
+	if results[0].Breakdown.Fuzzy <= 0 {
+		t.Errorf("Fuzzy = %d, phải > 0", results[0].Breakdown.Fuzzy)
+	}
+
+	// Khớp trọn trong title: highlight đúng trên Display, không lẫn vị trí từ path
+	results = products.SearchResults("dong")
+	if len(results) != 1 || results[0].Breakdown.Field != "title" {
+		t.Fatalf("SearchResults('dong') = %+v", results)
+	}
+	var highlighted strings.Builder
+	for _, p := range results[0].Positions {
+		r, _ := utf8.DecodeRuneInString(results[0].Str[p:])
+		highlighted.WriteRune(r)
+	}
+	if highlighted.String() != "đồng" {
+		t.Errorf("Highlight = %q, muốn \"đồng\"", highlighted.String())
+	}
+}
+
+func TestItemSearcher_PrimaryField(t *testing.T) {
+	docs := []testDoc{
+		{ID: "a", Title: "Quy trình", Tags: []string{"onboarding", "nhan su"}},
+		{ID: "b", Title: "Onboarding checklist", Tags: []string{"misc"}},
+	}
+
+	// Mặc định Field đầu tiên (title) là primary: word bonus tính trên title
+	def := NewItemSearcher(docs, testDocFields(100, 100))
+	if ix := def.s.index.Load(); ix.filenamesOnly[0] != "quy trinh" {
+		t.Errorf("Primary mặc định = %q, muốn title", ix.filenamesOnly[0])
+	}
+
+	cfg := DefaultConfig()
+	cfg.PrimaryField = "tags"
+	tags := NewItemSearcherWithConfig(docs, testDocFields(100, 100), cfg)
+	if ix := tags.s.index.Load(); ix.filenamesOnly[0] != "onboarding nhan su" {
+		t.Errorf("PrimaryField = tags: filenamesOnly = %q", ix.filenamesOnly[0])
+	}
+	results := tags.SearchResults("nhan su")
+	if len(results) == 0 || results[0].Item.ID != "a" || results[0].Breakdown.WordBonus != 2*DefaultWordMatchBonus {
+		t.Errorf("Word bonus phải tính trên tags, got %+v", results)
+	}
+
+	// Add/Update/Reset giữ nguyên PrimaryField
+	tags.Add(testDoc{ID: "c", Title: "X", Tags: []string{"ke toan"}})
+	tags.Update("b", testDoc{ID: "b", Title: "Y", Tags: []string{"it"}})
+	checkIndexConsistent(t, tags.s.index.Load())
+	tags.Reset(docs)
+	checkIndexConsistent(t, tags.s.index.Load())
+}
+
+func TestFuzzyFindFields_ParallelMatchesSequential(t *testing.T) {
+	var docs []testDoc
+	for i, path := range generateVietnameseTestFiles(300) {
+		docs = append(docs, testDoc{ID: path, Title: filepath.Base(path), Path: path, Tags: []string{"tag" + strconv.Itoa(i%7)}})
+	}
+
+	seq := NewItemSearcher(docs, testDocFields(100, 30)).s.index.Load()
+	parCfg := DefaultConfig()
+	parCfg.ParallelThreshold = 1
+	parCfg.ParallelMinTargets = 1
+
+	for _, q := range []string{"bao cao", "tag3", "hop dong 2"} {
+		a, fa := fuzzyFindFields(context.Background(), &defaultConfig, seq, q)
+		b, fb := fuzzyFindFields(context.Background(), &parCfg, seq, q)
+		if len(a) == 0 {
+			t.Fatalf("%q: không có kết quả", q)
+		}
+		slices.SortFunc(a, func(x, y FuzzyMatch) int { return x.Index - y.Index })
+		slices.SortFunc(b, func(x, y FuzzyMatch) int { return x.Index - y.Index })
+		if !slices.EqualFunc(a, b, func(x, y FuzzyMatch) bool { return x.Index == y.Index && x.Score == y.Score }) || !slices.Equal(fa, fb) {
+			t.Errorf("%q: parallel khác tuần tự", q)
+		}
+	}
+}
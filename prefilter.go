@@ -0,0 +1,134 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+prefilter.go Structure:
+├── Types
+│   └── charMask
+└── Functions
+
+	├── runeBit (private)
+	├── maskOf (private)
+	├── newCharMask (private)
+	├── typoMask (private)
+	├── runeCounts (private)
+	└── missingRunes (private)
+*/
+package fuzzyvn
+
+import "math/bits"
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- charMask: Bitmask "item có chứa ký tự nào", bật bằng Config.Prefilter, dùng để loại item trước khi chấm điểm
+- Mỗi bit là 1 nhóm ký tự (xem runeBit): a-z và 0-9 mỗi ký tự 1 bit riêng, ký tự khác dồn chung 28 bit còn lại
+- Fuzzy (FuzzyRanker): pattern khớp kiểu subsequence nên MỌI ký tự của pattern phải có trong target, thiếu thì bỏ qua luôn, khỏi chạy greedy
+- Typo (TypoRanker): mỗi ký tự của query không có trong đoạn đầu tên file tốn ít nhất 1 lỗi, thiếu quá ngưỡng thì khỏi gọi BoundedDamerauLevenshtein (xem missingRunes)
+- Cả 2 phép lọc chỉ là điều kiện cần, item bị loại chắc chắn không khớp → kết quả giống hệt lúc không lọc
+- Vì sao không dùng index n-gram (trigram): fuzzy là subsequence, "mn" khớp "main" dù "mn" không phải chuỗi con
+- Bigram/trigram của query không bắt buộc phải liền nhau trong target nên index n-gram sẽ bỏ sót kết quả
+- Đánh đổi: 32 byte mỗi item (~32MB cho 1 triệu file), vẫn phải duyệt mask của mọi item (nhưng chỉ vài ns mỗi item)
+- Query ít ký tự khác nhau (1-2 chữ cái) thì hầu như item nào cũng qua, lọc không được gì
+*/
+type charMask struct {
+	all    uint64 // Mọi ký tự của Normalized (tất cả các trường)
+	name   uint64 // Mọi ký tự của trường chính (tên file)
+	head8  uint64 // 8 ký tự đầu của trường chính
+	head16 uint64 // 16 ký tự đầu của trường chính
+}
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+// runeBit: Bit đại diện cho 1 ký tự đã chuẩn hóa
+func runeBit(r rune) uint {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return uint(r - 'a')
+	case r >= '0' && r <= '9':
+		return 26 + uint(r-'0')
+	default:
+		return 36 + uint(r)%28
+	}
+}
+
+// maskOf: Mask của tối đa limit ký tự đầu của s, limit < 0 = cả chuỗi
+func maskOf(s string, limit int) uint64 {
+	var m uint64
+	for _, r := range s {
+		if limit == 0 {
+			break
+		}
+		m |= 1 << runeBit(r)
+		limit--
+	}
+	return m
+}
+
+/*
+- newCharMask: Mask của 1 item từ Normalized và FilenamesOnly
+- head8/head16 tính trên tên file đã bỏ dấu ngăn cách, giống đoạn đầu mà typoDistance đem so
+*/
+func newCharMask(normStr, normPrimary string) charMask {
+	m := charMask{
+		all:  maskOf(normStr, -1),
+		name: maskOf(normPrimary, -1),
+	}
+	n := 0
+	for _, r := range normPrimary {
+		if n == 16 {
+			break
+		}
+		if isWordBoundary(r) {
+			continue
+		}
+		if n < 8 {
+			m.head8 |= 1 << runeBit(r)
+		}
+		m.head16 |= 1 << runeBit(r)
+		n++
+	}
+	return m
+}
+
+/*
+- typoMask: Mask chứa mọi ký tự trong n ký tự đầu của tên file
+- Lấy mask nhỏ nhất vẫn bao trọn n ký tự đầu, càng nhỏ càng lọc được nhiều
+*/
+func (m *charMask) typoMask(n int) uint64 {
+	switch {
+	case n <= 8:
+		return m.head8
+	case n <= 16:
+		return m.head16
+	default:
+		return m.name
+	}
+}
+
+// runeCounts: Số ký tự của s rơi vào từng bit, dùng cho missingRunes
+func runeCounts(s string) (counts [64]int) {
+	for _, r := range s {
+		counts[runeBit(r)]++
+	}
+	return counts
+}
+
+/*
+- missingRunes: Số ký tự của query (tính cả lặp) chắc chắn không có trong đoạn có mask target
+- Là cận dưới của DamerauLevenshtein(query, đoạn đó): ký tự không có trong đoạn kia thì phải bị xóa hoặc thay
+- Đổi chỗ 2 ký tự liền nhau không làm mất ký tự nào nên không ảnh hưởng cận dưới này
+*/
+func missingRunes(queryMask, target uint64, counts *[64]int) int {
+	missing := 0
+	for m := queryMask &^ target; m != 0; m &= m - 1 {
+		missing += counts[bits.TrailingZeros64(m)]
+	}
+	return missing
+}
@@ -0,0 +1,128 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// generateMonorepoFiles: Đường dẫn kiểu monorepo, ghép ngẫu nhiên từ nhiều thư mục/tên file khác nhau
+func generateMonorepoFiles(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	dirs := []string{"services", "packages", "internal", "cmd", "web", "tools", "docs", "infra", "libs", "apps"}
+	parts := []string{"auth", "billing", "user", "order", "payment", "search", "index", "worker", "queue", "cache",
+		"gateway", "notify", "report", "export", "import", "storage", "metrics", "Báo_cáo", "Hợp_đồng", "Kế_hoạch"}
+	exts := []string{".go", ".ts", ".tsx", ".py", ".yaml", ".json", ".md", ".sql", ".proto", ".pdf"}
+
+	files := make([]string, n)
+	for i := range files {
+		files[i] = fmt.Sprintf("/%s/%s/%s/%s_%s_%d%s",
+			dirs[rng.Intn(len(dirs))], parts[rng.Intn(len(parts))], parts[rng.Intn(len(parts))],
+			parts[rng.Intn(len(parts))], parts[rng.Intn(len(parts))], i, exts[rng.Intn(len(exts))])
+	}
+	return files
+}
+
+func prefilterConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Prefilter = true
+	return cfg
+}
+
+func TestSearcher_Prefilter_SameResults(t *testing.T) {
+	files := generateMonorepoFiles(3000, 1)
+	plain := NewSearcher(files)
+	filtered := NewSearcherWithConfig(files, prefilterConfig())
+
+	// Sửa index sau khi tạo để kiểm tra mask được cập nhật theo
+	for _, s := range []*Searcher{plain, filtered} {
+		s.Remove(files[:100]...)
+		s.Add("/services/auth/zzz_quokka.go", "/docs/Đặc_tả_hệ_thống.md")
+		s.Rename(files[200], "/tools/renamed_xylophone.py")
+	}
+	checkIndexConsistent(t, filtered.index.Load())
+
+	queries := []string{"auth", "billng", "paymnt", "conifg", "bao cao", "hop dong", "quokka", "xylophne",
+		"dac ta", "qqqq", "s", "worker queue", "idx", "reprot expotr", "zzz"}
+	for _, q := range queries {
+		want := plain.SearchResults(q)
+		got := filtered.SearchResults(q)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchResults(%q) khác nhau khi bật Prefilter:\ngot  %v\nwant %v", q, got, want)
+		}
+	}
+}
+
+func TestItemSearcher_Prefilter_SameResults(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	words := []string{"điện", "thoại", "iphone", "samsung", "ốp", "lưng", "sạc", "nhanh", "tai", "nghe", "bluetooth"}
+	pick := func() string {
+		n := 1 + rng.Intn(3)
+		out := make([]string, n)
+		for i := range out {
+			out[i] = words[rng.Intn(len(words))]
+		}
+		return strings.Join(out, " ")
+	}
+	products := make([]testProduct, 500)
+	for i := range products {
+		products[i] = testProduct{SKU: fmt.Sprint(i), Name: pick(), Brand: pick()}
+	}
+	toDoc := func(p testProduct) Document {
+		return Document{Key: p.SKU, Display: p.Name, Fields: []Field{
+			{Name: "name", Value: p.Name, Weight: 100},
+			{Name: "brand", Value: p.Brand, Weight: 40},
+		}}
+	}
+
+	plain := NewItemSearcher(products, toDoc)
+	filtered := NewItemSearcherWithConfig(products, toDoc, prefilterConfig())
+	for _, q := range []string{"iphone", "op lung", "samsng", "tai nghe blue", "xyz"} {
+		want, _ := plain.SearchWithOptions(q, SearchOptions{Limit: 100})
+		got, _ := filtered.SearchWithOptions(q, SearchOptions{Limit: 100})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SearchWithOptions(%q) khác nhau khi bật Prefilter", q)
+		}
+	}
+}
+
+func TestMissingRunes_LowerBound(t *testing.T) {
+	// Số ký tự thiếu không bao giờ vượt khoảng cách Levenshtein thật
+	rng := rand.New(rand.NewSource(3))
+	alphabet := []rune("abcdeáđơ_ 9")
+	random := func() string {
+		runes := make([]rune, rng.Intn(12))
+		for i := range runes {
+			runes[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(runes)
+	}
+	for range 5000 {
+		q, target := random(), random()
+		counts := runeCounts(q)
+		if missing, dist := missingRunes(maskOf(q, -1), maskOf(target, -1), &counts), DamerauLevenshtein(q, target); missing > dist {
+			t.Fatalf("missingRunes(%q, %q) = %d > Damerau-Levenshtein %d", q, target, missing, dist)
+		}
+	}
+}
+
+func BenchmarkSearch_Prefilter(b *testing.B) {
+	for _, n := range []int{100000, 1000000} {
+		files := generateMonorepoFiles(n, 1)
+		for _, mode := range []struct {
+			name string
+			cfg  Config
+		}{{"linear", DefaultConfig()}, {"prefilter", prefilterConfig()}} {
+			searcher := NewSearcherWithConfig(files, mode.cfg)
+			for _, q := range []string{"billing", "paymnet", "bao cao"} {
+				b.Run(fmt.Sprintf("%dk/%s/%s", n/1000, q, mode.name), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						searcher.Search(q)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,649 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+ranker.go Structure:
+├── Types
+│   ├── RankState
+│   ├── Ranker
+│   ├── RankerFunc
+│   ├── Scorer
+│   └── ScorerFunc
+├── RankState Methods
+│   ├── Context
+│   ├── Len
+│   ├── Original
+│   ├── Key
+│   ├── Value
+│   ├── Normalized
+│   ├── Primary
+│   ├── Accented
+│   └── Initials
+└── Built-in Rankers
+
+	├── DefaultRankers
+	├── FuzzyRanker
+	├── rerankConfig (private)
+	├── sortMatches (private)
+	├── WordBonusRanker
+	├── TypoRanker
+	├── typoDistance (private)
+	├── DiacriticRanker
+	├── KeystrokeRanker
+	├── SynonymRanker
+	├── alternateRankers (private)
+	├── fork (private)
+	├── rankAlternate (private)
+	├── CacheBoostRanker
+	├── cacheSignals (private)
+	└── scorerRanker (private)
+*/
+package fuzzyvn
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- RankState: Trạng thái dùng chung của 1 lần Search, được truyền qua từng Ranker trong chuỗi
+- Các Ranker đọc query + snapshot, rồi ghi điểm vào Candidates
+- Candidates: Key là Index của item, value là điểm thành phần hiện tại
+- Matches: Kết quả fuzzy pass (do FuzzyRanker điền), đã sắp xếp theo Score giảm dần
+- Config: Chỉ đọc, không được sửa
+- Ranker chạy lâu nên kiểm tra st.Context() định kỳ và return sớm khi bị hủy
+- Snapshot chỉ có hiệu lực trong lần Search hiện tại, đừng giữ RankState lại sau khi Rank trả về
+*/
+type RankState struct {
+	Query      string   // Query gốc user gõ
+	QueryNorm  string   // Query đã Normalize
+	QueryWords []string // strings.Fields(QueryNorm)
+	QueryLen   int      // Số rune của QueryNorm (không phải byte)
+
+	Config *Config
+	Cache  *QueryCache // Cache chung, có thể nil
+	UserID string      // User đang search (SearchOptions.UserID), rỗng nếu không có
+
+	Matches    []FuzzyMatch
+	Candidates map[int]ScoreBreakdown
+
+	ix    *searchIndex
+	ctx   context.Context
+	users *CacheRegistry
+}
+
+/*
+- Ranker: 1 bước trong chuỗi xếp hạng
+- Searcher gọi lần lượt từng Ranker trong Config.Rankers, Ranker sau thấy kết quả của Ranker trước
+- Có thể thêm candidate mới, sửa điểm hoặc xóa candidate khỏi st.Candidates
+*/
+type Ranker interface {
+	Rank(st *RankState)
+}
+
+// RankerFunc: Biến 1 hàm thường thành Ranker
+type RankerFunc func(st *RankState)
+
+func (f RankerFunc) Rank(st *RankState) { f(st) }
+
+/*
+- Scorer: Tín hiệu xếp hạng riêng của bạn (kích thước file, thời gian sửa, project sở hữu...)
+- Được gọi cho MỖI candidate sau khi chạy xong các Ranker, điểm trả về cộng vào Breakdown.Custom
+- Chạy trên toàn bộ candidates nên cần nhanh, tránh I/O trong Score (hãy tính trước rồi tra map)
+- Ví dụ ưu tiên file trong thư mục của team mình:
+
+	cfg.Scorers = append(cfg.Scorers, fuzzyvn.ScorerFunc(func(st *fuzzyvn.RankState, idx int) int {
+		if strings.HasPrefix(st.Original(idx), "/src/payments/") {
+			return 500
+		}
+		return 0
+	}))
+*/
+type Scorer interface {
+	Score(st *RankState, idx int) int
+}
+
+// ScorerFunc: Biến 1 hàm thường thành Scorer
+type ScorerFunc func(st *RankState, idx int) int
+
+func (f ScorerFunc) Score(st *RankState, idx int) int { return f(st, idx) }
+
+// =============================================================================
+// RankState Methods
+// =============================================================================
+
+// Context: ctx của lần Search hiện tại (context.Background() nếu gọi qua Search/SearchWithOptions)
+func (st *RankState) Context() context.Context {
+	return st.ctx
+}
+
+// Len: Tổng số item trong snapshot
+func (st *RankState) Len() int {
+	return len(st.ix.originals)
+}
+
+// Original: Chuỗi gốc (Document.Display) của item thứ idx
+func (st *RankState) Original(idx int) string {
+	return st.ix.originals[idx]
+}
+
+// Key: Document.Key của item thứ idx (với path là đường dẫn)
+func (st *RankState) Key(idx int) string {
+	return st.ix.doc(idx).Key
+}
+
+// Value: Item gốc của ItemSearcher (cần ép kiểu về T), nil với Searcher theo path
+func (st *RankState) Value(idx int) any {
+	if !st.ix.typed {
+		return nil
+	}
+	return st.ix.values[idx]
+}
+
+// Normalized: Chuỗi đã chuẩn hóa dùng cho fuzzy (Primary + " " + Secondary, với path là tên file + " " + đường dẫn)
+func (st *RankState) Normalized(idx int) string {
+	return st.ix.normalized[idx]
+}
+
+// Primary: Trường Primary đã chuẩn hóa (với path là tên file), dùng cho word bonus và Levenshtein
+func (st *RankState) Primary(idx int) string {
+	return st.ix.filenamesOnly[idx]
+}
+
+// Accented: Như Normalized nhưng giữ dấu (lowercase + NFC), rỗng nếu item toàn ASCII
+func (st *RankState) Accented(idx int) string {
+	return st.ix.accented[idx]
+}
+
+// Initials: Chữ cái đầu (đã chuẩn hóa) của từng từ/âm tiết trong Primary, "Báo_cáo_tài_chính.xlsx" → "bctcx"
+func (st *RankState) Initials(idx int) string {
+	return st.ix.initials[idx]
+}
+
+// =============================================================================
+// Built-in Rankers
+// =============================================================================
+
+/*
+- DefaultRankers: Chuỗi xếp hạng mặc định, đúng thứ tự Search vẫn chạy từ trước tới giờ
+- Fuzzy -> Word bonus -> Typo (Levenshtein) -> Acronym -> Diacritic -> Telex/VNI -> Từ đồng nghĩa -> Cache boost
+- Muốn chèn thêm bước thì copy slice này rồi chèn vào vị trí mong muốn
+- Các bước cho cách hiểu khác của query (KeystrokeRanker, SynonymRanker) phải đứng trước CacheBoostRanker, để cache và ghim tính cho cả item chỉ khớp qua cách hiểu đó
+*/
+func DefaultRankers() []Ranker {
+	return []Ranker{FuzzyRanker{}, WordBonusRanker{}, TypoRanker{}, AcronymRanker{}, DiacriticRanker{}, KeystrokeRanker{}, SynonymRanker{}, CacheBoostRanker{}}
+}
+
+/*
+- FuzzyRanker: Chạy fuzzy matcher trên toàn bộ Normalized, điền st.Matches và Breakdown.Fuzzy
+- Greedy hoặc optimal tùy Config.Algorithm, dùng parallel version nếu có nhiều files
+- Chấm trên normalized đã decode sẵn lúc tạo Searcher (targetArena), không decode lại mỗi lần search
+- Bật Config.Prefilter thì item thiếu ký tự của query bị bỏ qua, không chấm
+- Greedy có thể bỏ lỡ cách khớp tốt hơn, nên nếu Config.OptimalRerank > 0 thì chấm lại top N bằng optimal
+- Rerank không phạt khoảng trống (rerankConfig): cùng thang điểm với greedy và luôn >= điểm greedy, nên top N sau khi chấm lại vẫn đứng trên phần còn lại
+- Item dùng Document.Fields được chấm theo từng Field nhân trọng số, Field thắng ghi vào Breakdown.Field
+*/
+type FuzzyRanker struct{}
+
+func (FuzzyRanker) Rank(st *RankState) {
+	cfg := st.Config
+	ix := st.ix
+
+	// fieldOf chỉ có với ItemSearcher: Field thắng của từng item (-1 nếu không có)
+	var matches []FuzzyMatch
+	var fieldOf []int
+	switch {
+	case ix.typed:
+		matches, fieldOf = fuzzyFindFields(st.ctx, cfg, ix, st.QueryNorm)
+	case len(ix.normalized) >= cfg.ParallelThreshold:
+		matches = fuzzyFindParallel(st.ctx, cfg, st.QueryNorm, ix.normalized, ix, false)
+	default:
+		matches = fuzzyFind(st.ctx, cfg, st.QueryNorm, ix.normalized, ix, false)
+	}
+
+	// Chỉ làm cho top N vì optimal tốn O(len(query) * len(target)) mỗi lần
+	// Bị hủy rồi thì bỏ qua bước này, giữ nguyên điểm greedy của phần đã chấm
+	reranked := 0
+	if cfg.Algorithm == AlgorithmGreedy && cfg.OptimalRerank > 0 && len(matches) > 0 && !isDone(st.ctx.Done()) {
+		rcfg := rerankConfig(cfg)
+		p := newFuzzyPattern(st.QueryNorm)
+		reranked = min(cfg.OptimalRerank, len(matches))
+		for i := range reranked {
+			idx := matches[i].Index
+			score, _, ok := ix.targets.score(rcfg, AlgorithmOptimal, &p, idx, nil)
+			if !ok {
+				continue
+			}
+			if ix.typed && ix.fieldNorms[idx] != nil {
+				score, fieldOf[idx] = ix.scoreFields(rcfg, AlgorithmOptimal, &p, idx, score)
+			}
+			matches[i].Score = score
+		}
+		sortMatches(matches[:reranked])
+	}
+
+	st.Matches = matches
+	for i, m := range matches {
+		bd := st.Candidates[m.Index]
+		bd.Fuzzy = m.Score
+		bd.Reranked = i < reranked
+		if fieldOf != nil && fieldOf[m.Index] >= 0 {
+			bd.Field = ix.docs[m.Index].Fields[fieldOf[m.Index]].Name
+		}
+		st.Candidates[m.Index] = bd
+	}
+}
+
+/*
+- rerankConfig: cfg cho bước rerank của Config.OptimalRerank, bỏ phạt khoảng trống
+- Khi đó fuzzyScoreOptimal tối ưu đúng hàm điểm của greedy, chỉ khác là tìm được cách đặt tốt nhất
+*/
+func rerankConfig(cfg *Config) *Config {
+	rcfg := *cfg
+	rcfg.GapStartPenalty, rcfg.GapExtendPenalty = 0, 0
+	return &rcfg
+}
+
+// sortMatches: Sắp xếp lại theo Score giảm dần, giữ thứ tự cũ khi bằng điểm
+func sortMatches(matches []FuzzyMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+}
+
+/*
+- WordBonusRanker: Cộng Config.WordMatchBonus cho mỗi từ của query khớp với 1 từ trong tên file
+- OPTIMIZATION: Chỉ tính cho top 30 fuzzy matches (Config.MaxWordBonusCalc)
+- countWordMatches rất chậm (gọi BoundedDamerauLevenshtein), không nên chạy cho tất cả
+*/
+type WordBonusRanker struct{}
+
+func (WordBonusRanker) Rank(st *RankState) {
+	n := min(st.Config.MaxWordBonusCalc, len(st.Matches))
+	for _, m := range st.Matches[:n] {
+		bd, ok := st.Candidates[m.Index]
+		if !ok {
+			continue
+		}
+		// Word bonus tính trên tên file (không phải full path)
+		bd.WordBonus = countWordMatches(st.QueryWords, st.ix.filenamesOnly[m.Index]) * st.Config.WordMatchBonus
+		st.Candidates[m.Index] = bd
+	}
+}
+
+/*
+- TypoRanker: Tính điểm sai chính tả dựa trên Damerau-Levenshtein (theo ký tự, đổi chỗ 2 ký tự liền nhau tính 1 lỗi)
+- Tức là nếu user gõ "maain" hay "mian" thì ta vẫn tính điểm cho "main"
+- Query và tên file đều bỏ dấu ngăn cách trước khi so (compactName), queryLen tính trên query đã bỏ
+- Threshold = (queryLen / 3) + 1: cho phép khoảng 1 lỗi mỗi 3 ký tự + 1 lỗi bonus
+- Minimum threshold = 3: query ngắn (2-5 ký tự) vẫn cần đủ độ linh hoạt để match
+- (3 ở đây là Config.TypoThresholdDivisor và Config.MinTypoThreshold, divisor = 0 thì tắt hẳn)
+- Quét toàn bộ tên file nên kiểm tra ctx mỗi ctxCheckInterval file, bị hủy thì dừng
+- Bật Config.Prefilter thì tên file thiếu quá nhiều ký tự của query được bỏ qua trước khi tính Levenshtein
+- Bật Config.TypoIndex (mặc định) thì không quét nữa mà duyệt trie tên file (typo_index.go), cùng khoảng cách và điểm
+- Nếu item đã có điểm fuzzy thì giữ nhánh nào cho tổng điểm cao hơn (fuzzy + word hay levenshtein + word)
+*/
+type TypoRanker struct{}
+
+func (TypoRanker) Rank(st *RankState) {
+	cfg := st.Config
+	// So query và tên file đều đã bỏ dấu ngăn cách, "ho chi minh" gõ liền hay cách đều như nhau
+	queryNorm := compactName(st.QueryNorm)
+	queryLen := utf8.RuneCountInString(queryNorm)
+	if queryLen <= 1 || cfg.TypoThresholdDivisor <= 0 {
+		return
+	}
+
+	baseThreshold := (queryLen / cfg.TypoThresholdDivisor) + 1
+	if baseThreshold < cfg.MinTypoThreshold {
+		baseThreshold = cfg.MinTypoThreshold
+	}
+
+	// Nếu điểm sai chính tả nhỏ hơn ngưỡng cho phép thì tính điểm
+	// Robust solution khi sai chính tả đi quá xa (hoặc nếu không thì mong bạn có thể mở PR hỗ trợ mình)
+	apply := func(i, dist int) {
+		nameNorm := st.ix.filenamesOnly[i]
+		levScore := cfg.TypoBaseScore - (dist * cfg.TypoEditPenalty)
+		lenDiff := compactLen(nameNorm) - queryLen
+		if lenDiff > 0 {
+			levScore -= (lenDiff * cfg.TypoLengthPenalty)
+		}
+
+		// Thêm word bonus cho Levenshtein matches
+		// Dùng tên file để tính word matches (không phải full path)
+		wordBonus := 0
+		if dist < 2 {
+			wordMatches := countWordMatches(st.QueryWords, nameNorm)
+			wordBonus = wordMatches * cfg.WordMatchBonus
+		}
+
+		old, exists := st.Candidates[i]
+		if !exists || levScore+wordBonus > old.Fuzzy+old.WordBonus+old.Levenshtein {
+			// Giữ lại điểm của các Ranker tùy chỉnh chạy trước (nếu có)
+			old.Fuzzy = 0
+			old.Field = ""
+			old.WordBonus = wordBonus
+			old.Levenshtein = levScore
+			st.Candidates[i] = old
+		}
+	}
+	done := st.ctx.Done()
+
+	// Config.TypoIndex: duyệt trie thay vì tính Levenshtein cho từng file, chỉ còn quét các file thêm/sửa sau lần dựng gần nhất
+	if typo := st.ix.typo; typo != nil && typo.trie != nil && queryLen+1 <= typoTrieDepth {
+		if !typo.search(done, queryNorm, queryLen, baseThreshold, apply) {
+			return
+		}
+		for _, i := range typo.pending {
+			if dist, ok := typoDistance(queryNorm, queryLen, st.ix.filenamesOnly[i], baseThreshold); ok && dist <= baseThreshold {
+				apply(int(i), dist)
+			}
+		}
+		return
+	}
+
+	// Config.Prefilter: ký tự của query thiếu trong đoạn đầu tên file, mỗi ký tự thiếu tốn ít nhất 1 lỗi
+	masks := st.ix.masks
+	var queryMask uint64
+	var queryCounts [64]int
+	if masks != nil {
+		queryMask = maskOf(queryNorm, -1)
+		queryCounts = runeCounts(queryNorm)
+	}
+
+	for i, nameNorm := range st.ix.filenamesOnly {
+		if i%ctxCheckInterval == 0 && isDone(done) {
+			return
+		}
+		// Thay vì: runesName := []rune(nameNorm)
+		// Ta kiểm tra độ dài bằng len() byte trước cho nhanh (sơ loại)
+		if len(nameNorm) < queryLen {
+			continue
+		}
+		// 2 đoạn đem so (queryLen và queryLen+1 ký tự đầu) đều nằm trong queryLen+1 ký tự đầu
+		if masks != nil && missingRunes(queryMask, masks[i].typoMask(queryLen+1), &queryCounts) > baseThreshold {
+			continue
+		}
+		if dist, ok := typoDistance(queryNorm, queryLen, nameNorm, baseThreshold); ok && dist <= baseThreshold {
+			apply(i, dist)
+		}
+	}
+}
+
+/*
+- typoDistance: Khoảng cách Damerau-Levenshtein giữa query và phần đầu tên file mà TypoRanker dùng
+- Cả 2 đều bỏ dấu ngăn cách (compactName), nên "hochiminh" và "ho_chi_minh.pdf" cách nhau 0 lỗi
+- So với queryLen ký tự đầu và queryLen+1 ký tự đầu (phòng trường hợp typo thêm ký tự), lấy cái nhỏ hơn
+- ok = false nếu tên file ngắn hơn query (tính theo ký tự)
+- Vượt maxDist thì trả về maxDist + 1 (xem BoundedDamerauLevenshtein)
+- typoIndex.search trả về đúng khoảng cách này, chỉ là không phải tính cho từng file
+*/
+func typoDistance(queryCompact string, queryLen int, nameNorm string, maxDist int) (int, bool) {
+	// Mượn buffer giống editDistance, phần đầu tên file được bỏ dấu ngăn cách thẳng vào buffer
+	buf := distancePool.Get().(*distanceBuf)
+	var dist int
+	var ok bool
+	if isASCII(queryCompact) && isASCII(nameNorm) {
+		buf.bytes1 = append(buf.bytes1[:0], queryCompact...)
+		buf.bytes2 = appendCompact(buf.bytes2[:0], nameNorm, queryLen+1)
+		dist, ok = prefixDistance(buf, buf.bytes1, buf.bytes2, queryLen, maxDist)
+	} else {
+		buf.runes1 = appendCompact(buf.runes1[:0], queryCompact, -1)
+		buf.runes2 = appendCompact(buf.runes2[:0], nameNorm, queryLen+1)
+		dist, ok = prefixDistance(buf, buf.runes1, buf.runes2, queryLen, maxDist)
+	}
+	distancePool.Put(buf)
+	return dist, ok
+}
+
+// prefixDistance: Phần chung của typoDistance, name là tối đa m+1 ký tự đầu của tên file
+func prefixDistance[T fuzzyChar](buf *distanceBuf, query, name []T, m, maxDist int) (int, bool) {
+	// Nếu tên file ít ký tự hơn query thì bỏ
+	if len(name) < m {
+		return 0, false
+	}
+	dist := editDistanceOf(buf, query, name[:m], maxDist, true)
+
+	// So sánh thêm 1 ký tự (phòng trường hợp typo thêm ký tự)
+	// Chỉ cần biết d2 có nhỏ hơn dist không nên giới hạn ở dist - 1
+	if dist > 0 && len(name) > m {
+		if d2 := editDistanceOf(buf, query, name, dist-1, true); d2 < dist {
+			dist = d2
+		}
+	}
+	/*
+		Ở phần trên ví dụ như "mian", target 1 là "main" target 2 là "mainp" (dấu chấm của "main.py" đã bị bỏ)
+		Ta tính điểm ở target 1, dist = d1 = 1 (đổi chỗ i và a), nhưng ở target 2, dist = d2 = 2
+		Tức là nếu nhỏ hơn cái d1 thì lấy, còn không thì giữ nguyên
+		Kiểu như min(d1, d2)
+	*/
+	return dist, true
+}
+
+/*
+- AcronymRanker: Cộng Config.AcronymBonus cho item có chữ cái đầu của các từ liền nhau trong tên file khớp đúng query
+- "bctc": "Báo_cáo_tài_chính_2024.xlsx" (chữ cái đầu "bctc2x") được thưởng, "src/backend/tests/config.ts" thì không
+- Chữ cái đầu tính sẵn lúc tạo Searcher (RankState.Initials), mỗi lần search chỉ còn strings.Contains cho từng item
+- Chỉ xét query 1 từ, 3-12 chữ cái/số (isAcronymQuery). Query 2 chữ cái khớp quá nhiều file
+- Item chưa có trong Candidates (fuzzy/typo bỏ lỡ) được thêm vào
+*/
+type AcronymRanker struct{}
+
+func (AcronymRanker) Rank(st *RankState) {
+	bonus := st.Config.AcronymBonus
+	query := st.QueryNorm
+	if bonus == 0 || !isAcronymQuery(query) {
+		return
+	}
+	done := st.ctx.Done()
+	for idx, initials := range st.ix.initials {
+		if idx%ctxCheckInterval == 0 && isDone(done) {
+			return
+		}
+		if len(initials) < len(query) || !strings.Contains(initials, query) {
+			continue
+		}
+		bd := st.Candidates[idx]
+		bd.Acronym = bonus
+		st.Candidates[idx] = bd
+	}
+}
+
+/*
+- DiacriticRanker: Cộng Config.DiacriticBonus cho mỗi từ có dấu của query xuất hiện đúng dấu trong item
+- "bản đồ": "Bản_đồ.png" được 2 lần thưởng, "Bán_đồ_cũ.pdf" chỉ được 1 (từ "đồ"), "ban_do.txt" không được gì
+- Query không dấu (hoặc từ không dấu trong query) không được so, nên điểm giữ nguyên như trước
+- Chỉ chấm các candidate đã có (fuzzy/typo), không thêm candidate mới
+- Query Telex đã giải mã (Config.DecodeKeystrokes) cũng được chấm, vì bản giải mã là query có dấu
+*/
+type DiacriticRanker struct{}
+
+func (DiacriticRanker) Rank(st *RankState) {
+	if st.Config.DiacriticBonus == 0 {
+		return
+	}
+	words := accentedWords(st.Query)
+	if len(words) == 0 {
+		return
+	}
+	done := st.ctx.Done()
+	n := 0
+	for idx, bd := range st.Candidates {
+		if n++; n%ctxCheckInterval == 0 && isDone(done) {
+			return
+		}
+		if count := countAccentMatches(words, st.ix.accented[idx]); count > 0 {
+			bd.Diacritic = count * st.Config.DiacriticBonus
+			st.Candidates[idx] = bd
+		}
+	}
+}
+
+/*
+- KeystrokeRanker: Query gõ Telex/VNI khi tắt bộ gõ (Config.DecodeKeystrokes), chấm thêm bản đã giải mã rồi giữ điểm khớp cao hơn cho mỗi item
+- Bản giải mã chỉ chạy alternateRankers (các tín hiệu khớp chữ), không chạy lại CacheBoostRanker hay Scorers
+- Tốn thêm gần bằng 1 lượt chấm cho mỗi bản giải mã nên mặc định tắt
+- Breakdown.Query ghi lại bản giải mã nếu nó thắng, highlight theo bản đó
+*/
+type KeystrokeRanker struct{}
+
+func (KeystrokeRanker) Rank(st *RankState) {
+	if !st.Config.DecodeKeystrokes {
+		return
+	}
+	for _, alt := range keystrokeAlternates(st.Query) {
+		st.rankAlternate(alt, 100, alternateRankers)
+	}
+}
+
+/*
+- SynonymRanker: Chấm thêm các query thay thế của Config.Synonyms (Synonyms.Expand), mỗi item giữ điểm khớp cao hơn
+- Chỉ giữ Weight% điểm khớp (phần bị trừ nằm ở Breakdown.Synonym), để khớp đúng chữ user gõ đứng trên
+- Query thay thế chỉ thêm tín hiệu khớp chữ (alternateRankers), cache boost và ghim vẫn theo query gốc
+- Lượt chọn hay ghim lưu dưới "contract" không áp cho lần search "hop dong"
+- Số query thay thế bị giới hạn bởi Synonyms.SetMaxExpansions
+*/
+type SynonymRanker struct{}
+
+func (SynonymRanker) Rank(st *RankState) {
+	if st.Config.Synonyms == nil {
+		return
+	}
+	for _, exp := range st.Config.Synonyms.Expand(st.Query) {
+		st.rankAlternate(exp.Query, exp.Weight, alternateRankers)
+	}
+}
+
+/*
+  - alternateRankers: Chuỗi Ranker cho 1 cách hiểu khác của query (rankAlternate)
+  - Chỉ các tín hiệu khớp chữ: fuzzy, word bonus, sai chính tả, dấu. Cần TypoRanker vì điểm Levenshtein lớn hơn fuzzy nhiều,
+    thiếu nó thì bản giải mã "baos caos" → "báo cáo" không bao giờ thắng được query gốc khớp sai chính tả
+  - Cache boost, ghim và Scorers không nằm ở đây: chúng luôn tính theo query gốc, chạy 1 lần sau khi gộp
+*/
+var alternateRankers = []Ranker{FuzzyRanker{}, WordBonusRanker{}, TypoRanker{}, DiacriticRanker{}}
+
+// fork: RankState mới cho 1 cách hiểu khác của query, dùng chung snapshot, Config, ctx với st
+func (st *RankState) fork(query, queryNorm string) *RankState {
+	alt := *st
+	alt.Query = query
+	alt.QueryNorm = queryNorm
+	alt.QueryWords = strings.Fields(queryNorm)
+	alt.QueryLen = utf8.RuneCountInString(queryNorm)
+	alt.Matches = nil
+	alt.Candidates = make(map[int]ScoreBreakdown, 50)
+	return &alt
+}
+
+/*
+  - rankAlternate: Chấm alt (1 cách hiểu khác của query) bằng rankers rồi gộp vào st.Candidates
+  - Mỗi item giữ cách hiểu có điểm khớp cao hơn. Chỉ điểm khớp chữ được so và thay
+    (Fuzzy, WordBonus, Levenshtein, Acronym, Diacritic, Synonym), CacheBoost, Custom và Pin của st giữ nguyên
+  - weight < 100: Chỉ giữ weight% điểm khớp, phần bị trừ ghi vào Breakdown.Synonym
+  - Breakdown.Query = alt nếu alt thắng
+*/
+func (st *RankState) rankAlternate(alt string, weight int, rankers []Ranker) {
+	altNorm := Normalize(alt)
+	if altNorm == st.QueryNorm || st.ctx.Err() != nil {
+		return
+	}
+	ast := st.fork(alt, altNorm)
+	for _, r := range rankers {
+		r.Rank(ast)
+		if st.ctx.Err() != nil {
+			return
+		}
+	}
+	for idx, bd := range ast.Candidates {
+		bd.Query = alt
+		if total := bd.Total(); weight < 100 && total > 0 {
+			bd.Synonym = -total * (100 - weight) / 100
+		}
+		old, exists := st.Candidates[idx]
+		if exists && bd.Total() <= old.Total()-old.CacheBoost-old.Custom {
+			continue
+		}
+		bd.CacheBoost, bd.Custom, bd.Pin = old.CacheBoost, old.Custom, old.Pin
+		st.Candidates[idx] = bd
+	}
+}
+
+/*
+- CacheBoostRanker: Cộng điểm boost từ lịch sử chọn file (st.Cache)
+- Đảm bảo file đã cache luôn xuất hiện trong kết quả, kể cả khi fuzzy/Levenshtein không match
+- Thì ví dụ như:
+User search "tiền lương", xong họ chả chọn cái gì liên quan tới tiền lương
+nhưng chọn "bao_cao_tai_chinh_2024.xlsx"
+Hệ thống lưu lại: Query: "tiền lương" -> File: "bao_cao..."
+Xong giờ search lại "tien luong" một lần nữa
+Lúc này cả fuzzy và levenshtein đều không match
+Nhưng nó vẫn in ra "bao_cao_tai_chinh_2024.xlsx", vì trước đây từng có hành vi này
+- Đây chỉ là một cơ chế phòng bị cho trường hợp user quên tên file, vì nó cũng không có độ chính xác quá cao
+- File bị từ chối (QueryCache.RecordRejection) nhận CacheBoost âm, file được ghim (QueryCache.Pin) nhận Breakdown.Pin
+*/
+type CacheBoostRanker struct{}
+
+func (CacheBoostRanker) Rank(st *RankState) {
+	boosts, pins := st.cacheSignals()
+	// Ví dụ: User từng search "main" và chọn main.go nhiều lần:
+	// cacheBoosts = {"/a/main.go": 5000}
+	for cachedPath, boost := range boosts {
+		// Tra cứu trực tiếp từ map đã pre-compute
+		idx, exists := st.ix.keyToIdx[cachedPath]
+		if !exists {
+			continue
+		}
+		bd, isCandidate := st.Candidates[idx]
+		// Điểm âm (file bị từ chối) chỉ hạ file đã khớp, không kéo file đó vào kết quả
+		if boost <= 0 && !isCandidate {
+			continue
+		}
+		bd.CacheBoost = boost
+		st.Candidates[idx] = bd
+	}
+
+	// File được ghim luôn có mặt, vị trí do Breakdown.Pin quyết định (xem rankBefore)
+	for i, pinnedPath := range pins {
+		if idx, exists := st.ix.keyToIdx[pinnedPath]; exists {
+			bd := st.Candidates[idx]
+			bd.Pin = i + 1
+			st.Candidates[idx] = bd
+		}
+	}
+}
+
+/*
+- cacheSignals: Boost và danh sách ghim cho query hiện tại
+- Có UserID thì trộn cache của user với cache chung (CacheRegistry.blend), không thì chỉ dùng cache chung
+*/
+func (st *RankState) cacheSignals() (map[string]int, []string) {
+	if st.UserID != "" && st.users != nil {
+		return st.users.blend(st.Cache, st.UserID, st.Query)
+	}
+	if st.Cache == nil {
+		return nil, nil
+	}
+	return st.Cache.GetBoostScores(st.Query), st.Cache.GetPins(st.Query)
+}
+
+/*
+- scorerRanker: Gom các Scorer của Config.Scorers thành 1 bước cuối trong chuỗi
+- Mỗi Scorer được gọi cho mọi candidate, tổng điểm cộng vào Breakdown.Custom
+*/
+type scorerRanker []Scorer
+
+func (sr scorerRanker) Rank(st *RankState) {
+	for idx, bd := range st.Candidates {
+		for _, sc := range sr {
+			bd.Custom += sc.Score(st, idx)
+		}
+		st.Candidates[idx] = bd
+	}
+}
@@ -0,0 +1,141 @@
+package fuzzyvn
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func sortedResults(results []MatchResult) []MatchResult {
+	out := append([]MatchResult(nil), results...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
+
+func TestDefaultRankers_SameAsNil(t *testing.T) {
+	files := append(generateTestFiles(300), generateVietnameseTestFiles(50)...)
+
+	explicit := DefaultConfig()
+	explicit.Rankers = DefaultRankers()
+
+	a := NewSearcher(files)
+	b := NewSearcherWithConfig(files, explicit)
+	a.RecordSelection("main", files[7])
+	b.RecordSelection("main", files[7])
+
+	for _, q := range []string{"main", "mian", "bao cao", "test_1", "x"} {
+		ra, _ := a.rank(context.Background(), a.index.Load(), q, Normalize(q), "")
+		rb, _ := b.rank(context.Background(), b.index.Load(), q, Normalize(q), "")
+		ra, rb = sortedResults(ra), sortedResults(rb)
+		if !reflect.DeepEqual(ra, rb) {
+			t.Errorf("%q: Config.Rankers = DefaultRankers() phải cho kết quả giống nil", q)
+		}
+	}
+}
+
+func TestScorer_Custom(t *testing.T) {
+	files := []string{
+		"/other/config.go",
+		"/team/config.go",
+	}
+
+	cfg := DefaultConfig()
+	cfg.Scorers = []Scorer{
+		ScorerFunc(func(st *RankState, idx int) int {
+			if strings.HasPrefix(st.Original(idx), "/team/") {
+				return 500
+			}
+			return 0
+		}),
+		ScorerFunc(func(st *RankState, idx int) int { return 1 }),
+	}
+
+	results := NewSearcherWithConfig(files, cfg).SearchResults("config")
+	if len(results) != 2 {
+		t.Fatalf("Phải có 2 kết quả, got %d", len(results))
+	}
+	if results[0].Str != "/team/config.go" {
+		t.Errorf("Scorer phải đẩy /team/config.go lên đầu, got %v", results[0].Str)
+	}
+	if results[0].Breakdown.Custom != 501 || results[1].Breakdown.Custom != 1 {
+		t.Errorf("Custom = %d và %d, muốn 501 và 1", results[0].Breakdown.Custom, results[1].Breakdown.Custom)
+	}
+	for _, r := range results {
+		if r.Score != r.Breakdown.Total() {
+			t.Errorf("%s: Score = %d nhưng Breakdown.Total() = %d", r.Str, r.Score, r.Breakdown.Total())
+		}
+	}
+}
+
+func TestRanker_CustomChain(t *testing.T) {
+	files := []string{
+		"/src/main.go",
+		"/src/main_test.go",
+		"/docs/mian.md",
+	}
+
+	// Chỉ fuzzy, không typo: "mian" không còn khớp "main" qua Levenshtein
+	cfg := DefaultConfig()
+	cfg.Rankers = []Ranker{FuzzyRanker{}, WordBonusRanker{}}
+	results := NewSearcherWithConfig(files, cfg).SearchResults("mian")
+	for _, r := range results {
+		if r.Breakdown.Levenshtein != 0 {
+			t.Errorf("Không có TypoRanker thì Levenshtein phải bằng 0, got %v", r)
+		}
+	}
+
+	// Chèn bước lọc bỏ file test vào giữa chuỗi, trước CacheBoostRanker
+	dropTests := RankerFunc(func(st *RankState) {
+		for idx := range st.Candidates {
+			if strings.HasSuffix(st.Original(idx), "_test.go") {
+				delete(st.Candidates, idx)
+			}
+		}
+	})
+	cfg = DefaultConfig()
+	cfg.Rankers = append(DefaultRankers()[:3:3], dropTests, CacheBoostRanker{})
+	searcher := NewSearcherWithConfig(files, cfg)
+	searcher.RecordSelection("main", "/docs/mian.md")
+
+	results = searcher.SearchResults("main")
+	var got []string
+	for _, r := range results {
+		if strings.HasSuffix(r.Str, "_test.go") {
+			t.Errorf("File test phải bị loại, got %v", r.Str)
+		}
+		got = append(got, r.Str)
+	}
+	if len(got) != 2 || got[0] != "/docs/mian.md" {
+		t.Errorf("Cache boost vẫn phải chạy sau bước lọc, got %v", got)
+	}
+}
+
+func TestRankState_Accessors(t *testing.T) {
+	files := []string{"/a/Báo_cáo.pdf", "/b/main.go"}
+
+	var seen *RankState
+	cfg := DefaultConfig()
+	cfg.Rankers = append(DefaultRankers(), RankerFunc(func(st *RankState) { seen = st }))
+	NewSearcherWithConfig(files, cfg).SearchResults("Báo cáo")
+
+	if seen == nil {
+		t.Fatal("Ranker tùy chỉnh không được gọi")
+	}
+	if seen.Query != "Báo cáo" || seen.QueryNorm != "bao cao" || seen.QueryLen != 7 {
+		t.Errorf("Query = %q, QueryNorm = %q, QueryLen = %d", seen.Query, seen.QueryNorm, seen.QueryLen)
+	}
+	if !reflect.DeepEqual(seen.QueryWords, []string{"bao", "cao"}) {
+		t.Errorf("QueryWords = %v", seen.QueryWords)
+	}
+	if seen.Len() != 2 || seen.Original(0) != files[0] || seen.Primary(0) != "bao_cao.pdf" {
+		t.Errorf("Len = %d, Original(0) = %q, Primary(0) = %q", seen.Len(), seen.Original(0), seen.Primary(0))
+	}
+	if seen.Normalized(1) != "main.go /b/main.go" {
+		t.Errorf("Normalized(1) = %q", seen.Normalized(1))
+	}
+	if _, ok := seen.Candidates[0]; !ok {
+		t.Error("Báo_cáo.pdf phải nằm trong Candidates")
+	}
+}
@@ -0,0 +1,227 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+segment.go Structure:
+├── Types
+│   └── wordTarget
+├── wordTarget Methods
+│   ├── newWordTarget (private)
+│   ├── contains (private)
+│   ├── isBound (private)
+│   └── matchSyllables (private)
+└── Functions
+
+	├── compactName (private)
+	├── appendCompact (private)
+	├── compactLen (private)
+	├── setBit (private)
+	└── hasBit (private)
+*/
+package fuzzyvn
+
+import (
+	"math/bits"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+- Tên file tiếng Việt hay viết liền ("hochiminh", "baocaotaichinh") trong khi query có dấu cách ("ho chi minh"), hoặc ngược lại
+- Word bonus (countWordMatches) so từ trên tên file đã bỏ dấu ngăn cách, chỉ cần từ của query bắt đầu và kết thúc đúng ranh giới
+- Ranh giới gồm cả ranh giới âm tiết trong từ ghép tiếng Việt (compoundBounds), nên "ho chi minh" khớp 3 từ với "hochiminh.pdf"
+- Levenshtein (TypoRanker) so query và tên file đều đã bỏ dấu ngăn cách (compactName), nên "hochiminh" và "ho_chi_minh" cách nhau 0 lỗi
+*/
+
+// maxWordTarget: Số byte đầu của tên file được so từ, đủ cho tên file (giới hạn 255 byte của hầu hết filesystem)
+const maxWordTarget = 256
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- wordTarget: Tên file (đã chuẩn hóa) bỏ hết dấu ngăn cách, kèm các vị trí ranh giới từ
+- Ranh giới: 2 đầu của mỗi từ (tách bằng isWordBoundary), chỗ chuyển chữ ↔ số, ranh giới âm tiết trong từ ghép tiếng Việt
+- "ho_chi_minh_2024.pdf" → "hochiminh2024pdf", ranh giới {0, 2, 5, 9, 13, 16}
+- "hochiminh.pdf" → "hochiminhpdf", ranh giới {0, 2, 3, 5, 9, 12} (3 là của cách tách hoc|hi|minh)
+- Tách âm tiết (compoundBounds) tốn nhất nên chỉ làm khi cần, cho đúng đoạn có chuỗi con khớp rơi vào giữa
+*/
+type wordTarget struct {
+	compact  string
+	bounds   [maxWordTarget/64 + 1]uint64 // bit i bật = có dấu ngăn cách (hoặc chữ ↔ số) trước compact[i]
+	syllable [maxWordTarget/64 + 1]uint64 // Ranh giới âm tiết bên trong các đoạn đã tách
+	split    [maxWordTarget/64 + 1]uint64 // bit i bật = đoạn bắt đầu tại compact[i] đã được tách âm tiết
+}
+
+// =============================================================================
+// wordTarget Methods
+// =============================================================================
+
+// newWordTarget: Dựng wordTarget từ tên file đã chuẩn hóa, không cấp phát nếu tên file không có dấu ngăn cách
+func newWordTarget(target string) wordTarget {
+	var wt wordTarget
+	wt.compact = compactName(target)
+	if len(wt.compact) > maxWordTarget {
+		wt.compact = wt.compact[:maxWordTarget]
+	}
+
+	// Lần theo tên gốc để biết dấu ngăn cách nằm ở đâu trong chuỗi đã bỏ
+	pos, start := 0, 0 // start: đầu đoạn chữ (hoặc số) hiện tại trong compact
+	prevDigit := false
+	for _, r := range target {
+		if pos >= len(wt.compact) {
+			break
+		}
+		if isWordBoundary(r) {
+			setBit(&wt.bounds, pos)
+			start = pos
+			continue
+		}
+		digit := unicode.IsDigit(r)
+		if pos > start && digit != prevDigit {
+			setBit(&wt.bounds, pos)
+			start = pos
+		}
+		prevDigit = digit
+		pos += utf8.RuneLen(r)
+	}
+	setBit(&wt.bounds, 0)
+	setBit(&wt.bounds, len(wt.compact))
+	return wt
+}
+
+// contains: word (đã bỏ dấu ngăn cách) có xuất hiện trong tên file, bắt đầu và kết thúc đúng ranh giới không
+func (wt *wordTarget) contains(word string) bool {
+	if word == "" {
+		return false
+	}
+	for from := 0; ; {
+		i := strings.Index(wt.compact[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if wt.isBound(start) && wt.isBound(end) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+// isBound: Có ranh giới trước compact[i] không, i nằm giữa 1 đoạn thì tách âm tiết đoạn đó (1 lần) rồi xem
+func (wt *wordTarget) isBound(i int) bool {
+	if hasBit(&wt.bounds, i) {
+		return true
+	}
+	start, end := i-1, i+1
+	for !hasBit(&wt.bounds, start) {
+		start--
+	}
+	for !hasBit(&wt.bounds, end) {
+		end++
+	}
+	if !hasBit(&wt.split, start) {
+		setBit(&wt.split, start)
+		if end-start <= maxCompoundLen {
+			for b := compoundBounds(wt.compact[start:end]); b != 0; b &= b - 1 {
+				setBit(&wt.syllable, start+bits.TrailingZeros32(b))
+			}
+		}
+	}
+	return hasBit(&wt.syllable, i)
+}
+
+/*
+- matchSyllables: word là từ ghép tiếng Việt của query ("baocaotaichinh"), đếm số âm tiết của nó có trong tên file (contains)
+- Thử mọi cách tách word thành âm tiết (cùng luật với compoundBounds), lấy cách có nhiều âm tiết khớp nhất, nên tên file quyết định cách tách khi còn mơ hồ
+- "baocaotaichinh" với "bao_cao_2024.xlsx" → 2, với "baocaotaichinh.xlsx" → 4
+*/
+func (wt *wordTarget) matchSyllables(word string) int {
+	n := len(word)
+	if n > maxCompoundLen {
+		return 0
+	}
+	// best[i]: Nhiều âm tiết khớp nhất khi tách word[:i], -1 = không tách được
+	var best [maxCompoundLen + 1]int
+	for i := 1; i <= n; i++ {
+		best[i] = -1
+	}
+	for i := 0; i < n; i++ {
+		if best[i] < 0 || (i > 0 && isVowel(rune(word[i]))) {
+			continue
+		}
+		for j, v := i+1, 0; j <= min(n, i+7); j++ {
+			if v = syllableStep(v, word[j-1]); v == 0 {
+				break
+			}
+			if !syllableTrie[v].full {
+				continue
+			}
+			gain := best[i]
+			if wt.contains(word[i:j]) {
+				gain++
+			}
+			best[j] = max(best[j], gain)
+		}
+	}
+	return max(best[n], 0)
+}
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+// compactName: s bỏ hết dấu ngăn cách (isWordBoundary), trả về chính s nếu không có (không cấp phát)
+func compactName(s string) string {
+	if strings.IndexFunc(s, isWordBoundary) < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if !isWordBoundary(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+/*
+- appendCompact: Nối tối đa n ký tự đầu của s (bỏ dấu ngăn cách) vào dst, n < 0 = cả chuỗi
+- Dùng được cho cả []byte (chỉ khi s toàn ASCII) lẫn []rune, cho typoDistance khỏi cấp phát chuỗi mới
+*/
+func appendCompact[T fuzzyChar](dst []T, s string, n int) []T {
+	for _, r := range s {
+		if n == 0 {
+			break
+		}
+		if !isWordBoundary(r) {
+			dst = append(dst, T(r))
+			n--
+		}
+	}
+	return dst
+}
+
+// compactLen: Số ký tự (rune) của s sau khi bỏ dấu ngăn cách
+func compactLen(s string) int {
+	n := 0
+	for _, r := range s {
+		if !isWordBoundary(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func setBit(set *[maxWordTarget/64 + 1]uint64, i int) {
+	set[i/64] |= 1 << (i % 64)
+}
+
+func hasBit(set *[maxWordTarget/64 + 1]uint64, i int) bool {
+	return set[i/64]&(1<<(i%64)) != 0
+}
@@ -0,0 +1,156 @@
+package fuzzyvn
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewWordTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		compact string
+		bounds  []int
+	}{
+		{"ho_chi_minh_2024.pdf", "hochiminh2024pdf", []int{0, 2, 5, 9, 13, 16}},
+		{"hochiminh.pdf", "hochiminhpdf", []int{0, 2, 3, 5, 9, 12}},
+		{"main.go", "maingo", []int{0, 4, 6}},
+		{"report2024", "report2024", []int{0, 6, 10}},
+	}
+	for _, tt := range tests {
+		wt := newWordTarget(tt.target)
+		if wt.compact != tt.compact {
+			t.Errorf("newWordTarget(%q).compact = %q, muốn %q", tt.target, wt.compact, tt.compact)
+		}
+		var bounds []int
+		for i := 0; i <= len(wt.compact); i++ {
+			if wt.isBound(i) {
+				bounds = append(bounds, i)
+			}
+		}
+		if !slices.Equal(bounds, tt.bounds) {
+			t.Errorf("newWordTarget(%q) ranh giới = %v, muốn %v", tt.target, bounds, tt.bounds)
+		}
+	}
+}
+
+func TestWordTarget_Contains(t *testing.T) {
+	wt := newWordTarget("hochiminh.pdf")
+	for _, word := range []string{"ho", "chi", "minh", "hochiminh", "chiminh", "pdf"} {
+		if !wt.contains(word) {
+			t.Errorf("hochiminh.pdf phải chứa từ %q", word)
+		}
+	}
+	// "min" không kết thúc ở ranh giới, "inh" không bắt đầu ở ranh giới
+	for _, word := range []string{"min", "inh", "hoch", ""} {
+		if wt.contains(word) {
+			t.Errorf("hochiminh.pdf không được chứa từ %q", word)
+		}
+	}
+	// Từ tiếng Anh viết liền không bị tách
+	if wt := newWordTarget("datacode.go"); wt.contains("code") {
+		t.Error("datacode.go không được chứa từ code")
+	}
+}
+
+func TestWordTarget_MatchSyllables(t *testing.T) {
+	tests := []struct {
+		target, word string
+		want         int
+	}{
+		{"bao_cao_tai_chinh.xlsx", "baocaotaichinh", 4},
+		{"baocaotaichinh.xlsx", "baocaotaichinh", 4},
+		{"bao_cao_2024.xlsx", "baocaotaichinh", 2},
+		{"main.go", "baocao", 0},
+	}
+	for _, tt := range tests {
+		wt := newWordTarget(tt.target)
+		if got := wt.matchSyllables(tt.word); got != tt.want {
+			t.Errorf("matchSyllables(%q, %q) = %d, muốn %d", tt.target, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestCountWordMatches_Spacing(t *testing.T) {
+	tests := []struct {
+		query  []string
+		target string
+		want   int
+	}{
+		{[]string{"ho", "chi", "minh"}, "hochiminh.pdf", 3},
+		{[]string{"hochiminh"}, "ho_chi_minh.pdf", 3},
+		{[]string{"baocaotaichinh"}, "bao_cao_tai_chinh.xlsx", 4},
+		{[]string{"bao", "cao"}, "baocao.docx", 2},
+		{[]string{"bao", "cao"}, "bao_cao.docx", 2},
+		{[]string{"maain"}, "main.go", 1}, // 1 lỗi vẫn tính
+		{[]string{"code"}, "datacode.go", 0},
+	}
+	for _, tt := range tests {
+		if got := countWordMatches(tt.query, tt.target); got != tt.want {
+			t.Errorf("countWordMatches(%v, %q) = %d, muốn %d", tt.query, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSearcher_SpaceInsensitive(t *testing.T) {
+	files := []string{
+		"/docs/hochiminh.pdf",
+		"/docs/ho_chi_minh_2024.pdf",
+		"/reports/baocaotaichinh.xlsx",
+		"/reports/bao_cao_tai_chinh_q1.xlsx",
+		"/src/main.go",
+	}
+	searcher := NewSearcher(files)
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"ho chi minh", files[0:2]},
+		{"hochiminh", files[0:2]},
+		{"bao cao tai chinh", files[2:4]},
+		{"baocaotaichinh", files[2:4]},
+	} {
+		results := searcher.SearchResults(tt.query)
+		if len(results) < len(tt.want) {
+			t.Fatalf("SearchResults(%q) = %v", tt.query, results)
+		}
+		for _, r := range results[:len(tt.want)] {
+			found := false
+			for _, w := range tt.want {
+				found = found || r.Str == w
+			}
+			if !found {
+				t.Errorf("SearchResults(%q): %q lọt vào top %d", tt.query, r.Str, len(tt.want))
+			}
+			if r.Breakdown.WordBonus == 0 {
+				t.Errorf("SearchResults(%q): %q không có WordBonus", tt.query, r.Str)
+			}
+		}
+	}
+
+	// Levenshtein so tên đã bỏ dấu ngăn cách nên gõ liền hay cách đều 0 lỗi
+	for _, q := range []string{"hochiminh", "ho chi minh"} {
+		dist, ok := typoDistance(compactName(q), 9, "ho_chi_minh_2024.pdf", 3)
+		if !ok || dist != 0 {
+			t.Errorf("typoDistance(%q, ho_chi_minh_2024.pdf) = %d, %v, muốn 0", q, dist, ok)
+		}
+	}
+}
+
+func BenchmarkCountWordMatches(b *testing.B) {
+	query := []string{"bao", "cao", "tai", "chinh"}
+	for i := 0; i < b.N; i++ {
+		countWordMatches(query, "baocaotaichinh_2024_final.xlsx")
+	}
+}
+
+func BenchmarkSearch_Compound(b *testing.B) {
+	searcher := NewSearcher(generateMonorepoFiles(100000, 1))
+	for _, q := range []string{"bao cao", "baocao"} {
+		b.Run(q, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				searcher.Search(q)
+			}
+		})
+	}
+}
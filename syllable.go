@@ -0,0 +1,252 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+syllable.go Structure:
+├── Tables
+│   ├── syllableOnsets
+│   ├── openRimes
+│   ├── closedNuclei
+│   ├── syllableRimes
+│   ├── syllableNode
+│   └── syllableTrie
+└── Functions
+
+	├── buildRimes (private)
+	├── buildSyllables (private)
+	├── syllableStep (private)
+	├── isSyllable (private)
+	├── compoundSyllables (private)
+	├── compoundSplit (private)
+	└── compoundBounds (private)
+*/
+package fuzzyvn
+
+import "math/bits"
+
+// =============================================================================
+// Tables
+// =============================================================================
+
+/*
+- Bảng âm tiết tiếng Việt ở dạng đã chuẩn hóa (không dấu, chữ thường): âm tiết = phụ âm đầu + vần
+- Dùng để đoán 1 từ có phải tiếng Việt không (giải mã Telex/VNI) và tách từ viết liền ("baocao" → bao|cao), không cần chính xác tuyệt đối
+- Chấp nhận hơi rộng (ví dụ không kiểm tra "k" chỉ đi với i/e/y) vì sau khi bỏ dấu nhiều vần đã trùng nhau
+*/
+
+// syllableOnsets: Phụ âm đầu, "" = âm tiết bắt đầu bằng nguyên âm. "qu" và "gi" tính luôn u/i vào phụ âm đầu
+var syllableOnsets = []string{
+	"", "b", "c", "ch", "d", "g", "gh", "gi", "h", "k", "kh", "l", "m", "n", "ng", "ngh",
+	"nh", "p", "ph", "qu", "r", "s", "t", "th", "tr", "v", "x",
+}
+
+// openRimes: Vần không có phụ âm cuối (ă/â/ê/ô/ơ/ư đã về a/e/o/u)
+var openRimes = []string{
+	"a", "e", "i", "o", "u", "y",
+	"ai", "ao", "au", "ay", "eo", "eu", "ia", "iu", "oa", "oe", "oi", "ua", "ue", "ui", "uo", "uu", "uy",
+	"ieu", "yeu", "oai", "oay", "oeo", "uay", "uoi", "uou", "uya", "uyu",
+}
+
+// closedNuclei: Nguyên âm chính của vần có phụ âm cuối, kèm các phụ âm cuối đi được với nó
+var closedNuclei = map[string][]string{
+	"a":   {"c", "ch", "m", "n", "ng", "nh", "p", "t"},
+	"e":   {"c", "ch", "m", "n", "ng", "nh", "p", "t"},
+	"i":   {"ch", "m", "n", "nh", "p", "t"},
+	"o":   {"c", "m", "n", "ng", "p", "t"},
+	"u":   {"c", "m", "n", "ng", "p", "t"},
+	"ie":  {"c", "m", "n", "ng", "p", "t"},
+	"ye":  {"m", "n", "t"},
+	"uo":  {"c", "m", "n", "ng", "p", "t"},
+	"oa":  {"c", "ch", "m", "n", "ng", "nh", "p", "t"},
+	"oe":  {"n", "t"},
+	"ue":  {"ch", "n", "nh", "t"},
+	"uy":  {"ch", "n", "nh", "p", "t"},
+	"uye": {"n", "t"},
+	"ua":  {"n", "ng", "t"},
+	"oo":  {"c", "ng"},
+}
+
+// syllableRimes: Mọi vần hợp lệ, dựng 1 lần từ openRimes và closedNuclei
+var syllableRimes = buildRimes()
+
+// syllableNode: 1 node của syllableTrie, next[c-'a'] = node con theo chữ c (0 = không có), full = đường đi tới node là 1 âm tiết
+type syllableNode struct {
+	next [26]uint16
+	full bool
+}
+
+/*
+- syllableTrie: Trie của mọi âm tiết (phụ âm đầu + vần, tối đa 7 chữ cái), node 0 là gốc
+- Khoảng 3 nghìn node (~170KB), nhanh hơn tra map nhiều vì tách từ ghép chỉ cần đi tiếp 1 chữ (syllableStep)
+- Tách từ ghép dừng nối thêm chữ ngay khi đoạn đang xét không còn là prefix của âm tiết nào
+*/
+var syllableTrie = buildSyllables()
+
+// maxCompoundLen: Từ viết liền dài hơn thì không tách âm tiết (compoundBounds trả về bitmask 32 bit)
+const maxCompoundLen = 32
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+func buildRimes() map[string]bool {
+	rimes := make(map[string]bool, 256)
+	for _, r := range openRimes {
+		rimes[r] = true
+	}
+	for nucleus, codas := range closedNuclei {
+		for _, coda := range codas {
+			rimes[nucleus+coda] = true
+		}
+	}
+	return rimes
+}
+
+func buildSyllables() []syllableNode {
+	trie := make([]syllableNode, 1, 4096)
+	for _, onset := range syllableOnsets {
+		for rime := range syllableRimes {
+			s := onset + rime
+			if len(s) > 7 {
+				continue
+			}
+			v := 0
+			for i := 0; i < len(s); i++ {
+				c := s[i] - 'a'
+				if trie[v].next[c] == 0 {
+					trie = append(trie, syllableNode{})
+					trie[v].next[c] = uint16(len(trie) - 1)
+				}
+				v = int(trie[v].next[c])
+			}
+			trie[v].full = true
+		}
+	}
+	return trie
+}
+
+// syllableStep: Node con của v theo chữ c, 0 nếu không âm tiết nào bắt đầu bằng (đường đi tới v) + c
+func syllableStep(v int, c byte) int {
+	if c < 'a' || c > 'z' {
+		return 0
+	}
+	return int(syllableTrie[v].next[c-'a'])
+}
+
+/*
+- isSyllable: s (đã chuẩn hóa) có phải 1 âm tiết tiếng Việt không
+- Tức là s = 1 phụ âm đầu (có thể rỗng) + 1 vần, tra trong syllableTrie
+- Ví dụ: "nguoi", "viet", "gi", "quoc" → true; "test", "class", "fix" → false
+*/
+func isSyllable(s string) bool {
+	if s == "" || len(s) > 7 {
+		return false
+	}
+	v := 0
+	for i := 0; i < len(s); i++ {
+		if v = syllableStep(v, s[i]); v == 0 {
+			return false
+		}
+	}
+	return syllableTrie[v].full
+}
+
+/*
+- compoundSyllables: Số âm tiết nếu word (đã chuẩn hóa) là nhiều âm tiết tiếng Việt viết liền, 0 nếu không phải
+- Ví dụ: "baocao" → 2, "hochiminh" → 3, "hanoi" → 2; "main", "code", "action", "bao" → 0
+*/
+func compoundSyllables(word string) int {
+	split := compoundSplit(word)
+	if split == 0 {
+		return 0
+	}
+	return bits.OnesCount32(split) + 1
+}
+
+/*
+- compoundSplit: Cách tách word thành âm tiết, bit i bật = cắt ngay trước word[i]. 0 nếu word không phải từ ghép
+- Lấy cách tách ít âm tiết nhất trong đó mọi âm tiết sau âm tiết đầu bắt đầu bằng phụ âm, bằng nhau thì cắt sớm hơn ("hochiminh" → ho|chi|minh chứ không phải hoc|hi|minh)
+- Trung bình mỗi âm tiết phải từ 2.5 chữ cái, vì rất nhiều từ tiếng Anh tình cờ tách được ("code" = co|de, "data" = da|ta)
+*/
+func compoundSplit(word string) uint32 {
+	n := len(word)
+	// Cả word là 1 âm tiết ("chinh", "nguyen") thì cách tách ít âm tiết nhất là không tách
+	if n < 4 || n > maxCompoundLen || isSyllable(word) {
+		return 0
+	}
+	// pieces[i]: Số âm tiết ít nhất để tách word[:i], 0 = không tách được. from[i]: Đầu âm tiết cuối của cách tách đó
+	var pieces, from [maxCompoundLen + 1]int
+	for i := 0; i < n; i++ {
+		if i > 0 && (pieces[i] == 0 || isVowel(rune(word[i]))) {
+			continue
+		}
+		// Âm tiết word[i:j]
+		for j, v := i+1, 0; j <= min(n, i+7); j++ {
+			if v = syllableStep(v, word[j-1]); v == 0 {
+				break
+			}
+			if syllableTrie[v].full && (pieces[j] == 0 || pieces[i]+1 < pieces[j]) {
+				pieces[j], from[j] = pieces[i]+1, i
+			}
+		}
+	}
+	if pieces[n] < 2 || 2*n < 5*pieces[n] {
+		return 0
+	}
+	var split uint32
+	for i := from[n]; i > 0; i = from[i] {
+		split |= 1 << i
+	}
+	return split
+}
+
+/*
+- compoundBounds: Các ranh giới âm tiết bên trong word có thể có, bit i bật = có cách tách word thành âm tiết cắt ngay trước word[i]
+- 0 nếu word không phải từ ghép (compoundSplit = 0)
+- Xét mọi cách tách (cùng luật với compoundSyllables) vì không biết cách nào đúng: "hochiminh" → {2, 3, 5} (ho|chi|minh, hoc|hi|minh)
+- Âm tiết bắt đầu bằng nguyên âm chỉ được đứng đầu, không thì "hochiminh" có thêm hoc|him|inh, "baocao" có ba|o|ca|o
+- Bên so khớp (countWordMatches) tự chọn ranh giới phù hợp với query
+*/
+func compoundBounds(word string) uint32 {
+	if compoundSplit(word) == 0 {
+		return 0
+	}
+	n := len(word)
+	// prefix[i]: word[:i] tách được; suffix[i]: word[i:] tách được
+	var prefix, suffix [maxCompoundLen + 1]bool
+	prefix[0], suffix[n] = true, true
+	for i := 0; i < n; i++ {
+		if !prefix[i] || (i > 0 && isVowel(rune(word[i]))) {
+			continue
+		}
+		for j, v := i+1, 0; j <= min(n, i+7); j++ {
+			if v = syllableStep(v, word[j-1]); v == 0 {
+				break
+			}
+			prefix[j] = prefix[j] || syllableTrie[v].full
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 && isVowel(rune(word[i])) {
+			continue
+		}
+		for j, v := i+1, 0; j <= min(n, i+7); j++ {
+			if v = syllableStep(v, word[j-1]); v == 0 {
+				break
+			}
+			if syllableTrie[v].full && suffix[j] {
+				suffix[i] = true
+				break
+			}
+		}
+	}
+	var bounds uint32
+	for i := 1; i < n; i++ {
+		if prefix[i] && suffix[i] {
+			bounds |= 1 << i
+		}
+	}
+	return bounds
+}
@@ -0,0 +1,50 @@
+package fuzzyvn
+
+import "testing"
+
+func TestIsSyllable(t *testing.T) {
+	valid := []string{"a", "bao", "cao", "viet", "nam", "nguoi", "nghieng", "quoc", "gi", "gia", "thuy", "khuyen", "duong", "hoa", "xoong", "oai"}
+	for _, s := range valid {
+		if !isSyllable(s) {
+			t.Errorf("isSyllable(%q) = false, muốn true", s)
+		}
+	}
+	invalid := []string{"", "test", "class", "fix", "main2", "baos", "vieejt", "go.mod", "str", "aoe", "nguoiz"}
+	for _, s := range invalid {
+		if isSyllable(s) {
+			t.Errorf("isSyllable(%q) = true, muốn false", s)
+		}
+	}
+}
+
+func TestCompoundSyllables(t *testing.T) {
+	tests := []struct {
+		word string
+		want int
+	}{
+		{"baocao", 2},
+		{"hochiminh", 3},
+		{"baocaotaichinh", 4},
+		{"hanoi", 2},
+		{"bao", 0},    // 1 âm tiết, không phải từ ghép
+		{"code", 0},   // co|de tách được nhưng quá ngắn so với số âm tiết
+		{"data", 0},   // da|ta
+		{"action", 0}, // ac|ti|on: âm tiết sau bắt đầu bằng nguyên âm
+		{"config", 0},
+	}
+	for _, tt := range tests {
+		if got := compoundSyllables(tt.word); got != tt.want {
+			t.Errorf("compoundSyllables(%q) = %d, muốn %d", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestCompoundBounds(t *testing.T) {
+	// ho|chi|minh và hoc|hi|minh đều tách được, ranh giới của cả 2 cách đều được giữ
+	if got, want := compoundBounds("hochiminh"), uint32(1<<2|1<<3|1<<5); got != want {
+		t.Errorf("compoundBounds(hochiminh) = %b, muốn %b", got, want)
+	}
+	if got := compoundBounds("code"); got != 0 {
+		t.Errorf("compoundBounds(code) = %b, muốn 0", got)
+	}
+}
@@ -0,0 +1,406 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+synonyms.go Structure:
+├── Types
+│   ├── Errors
+│   ├── Synonyms
+│   ├── Expansion
+│   ├── synonym (private)
+│   └── synonymEntry (private)
+├── Synonyms Methods
+│   ├── NewSynonyms
+│   ├── SetMaxExpansions
+│   ├── Add
+│   ├── AddAlias
+│   ├── Len
+│   ├── Expand
+│   └── addAlt (private)
+├── Load
+│   ├── LoadSynonyms
+│   ├── LoadSynonymsFile
+│   ├── isJSONArray (private)
+│   ├── parseSynonymText (private)
+│   └── parseSynonymJSON (private)
+└── Functions
+
+	├── synonymWords (private)
+	└── synonymWeight (private)
+*/
+package fuzzyvn
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+- Dữ liệu hay trộn nhiều cách gọi cho cùng 1 thứ: "hop dong"/"contract", "bao cao"/"report", "HCM"/"Sài Gòn"/"TP.HCM"
+- Config.Synonyms: Từ điển đồng nghĩa, Search mở rộng query thành các query thay thế (Expand) rồi chấm từng query như 1 cách hiểu khác (giống Telex/VNI)
+- Điểm của query thay thế chỉ giữ Weight% (Breakdown.Synonym là phần bị trừ), mỗi item giữ cách hiểu cho điểm cao hơn
+- Nên khớp đúng chữ user gõ luôn hơn khớp qua từ đồng nghĩa cùng mức, nhưng khớp tốt qua từ đồng nghĩa vẫn hơn khớp rải rác
+*/
+
+// DefaultSynonymWeight: Phần trăm điểm giữ lại khi khớp qua từ đồng nghĩa, dùng khi không ghi trọng số
+const DefaultSynonymWeight = 80
+
+// DefaultMaxSynonymExpansions: Mỗi query thay thế tốn thêm 1 lượt chấm điểm khớp chữ, nên chỉ lấy vài query có trọng số cao nhất (xem SetMaxExpansions)
+const DefaultMaxSynonymExpansions = 4
+
+// =============================================================================
+// Types
+// =============================================================================
+
+var ErrSynonymFormat = errors.New("fuzzyvn: invalid synonym data")
+
+/*
+- Synonyms: Từ điển đồng nghĩa/bí danh, an toàn khi dùng đồng thời (Add trong lúc Search)
+- Mỗi cụm từ được chuẩn hóa (Normalize) và tách từ theo dấu ngăn cách, nên "TP.HCM", "tp hcm", "TP_HCM" là 1
+- Add: Nhóm các cụm từ thay được cho nhau. AddAlias: Chỉ 1 chiều (gõ "sg" thì tìm cả "sai gon", không có chiều ngược lại)
+- Ví dụ:
+syn := fuzzyvn.NewSynonyms()
+syn.Add(0, "hợp đồng", "contract")
+syn.AddAlias(90, "sg", "sài gòn", "tp hcm")
+cfg := fuzzyvn.DefaultConfig()
+cfg.Synonyms = syn
+*/
+type Synonyms struct {
+	mu            sync.RWMutex
+	alts          map[string][]synonym // Cụm từ đã chuẩn hóa (các từ nối bằng 1 dấu cách) → các cụm thay thế
+	maxWords      int                  // Số từ của cụm dài nhất, giới hạn độ dài đoạn query phải tra
+	maxExpansions int                  // Số query thay thế tối đa mỗi lần Expand
+}
+
+/*
+- Expansion: 1 query thay thế do Synonyms.Expand tạo ra
+- Query: Query đã chuẩn hóa, đoạn khớp từ điển được thay bằng cụm đồng nghĩa ("hop dong 2024" → "contract 2024")
+- Weight: Phần trăm điểm được giữ lại (1-100)
+*/
+type Expansion struct {
+	Query  string
+	Weight int
+}
+
+// synonym: 1 cụm thay thế kèm trọng số
+type synonym struct {
+	phrase string
+	weight int
+}
+
+/*
+- synonymEntry: 1 phần tử của file JSON
+- Không có From: Terms là 1 nhóm thay được cho nhau (như Add)
+- Có From: From thay được bằng từng Terms, 1 chiều (như AddAlias)
+*/
+type synonymEntry struct {
+	From   string   `json:"from,omitempty"`
+	Terms  []string `json:"terms"`
+	Weight int      `json:"weight,omitempty"`
+}
+
+// =============================================================================
+// Synonyms Methods
+// =============================================================================
+
+// NewSynonyms: Từ điển rỗng
+func NewSynonyms() *Synonyms {
+	return &Synonyms{alts: make(map[string][]synonym), maxExpansions: DefaultMaxSynonymExpansions}
+}
+
+/*
+- SetMaxExpansions: Đặt số query thay thế tối đa mỗi lần Expand (mặc định DefaultMaxSynonymExpansions)
+- n <= 0 = DefaultMaxSynonymExpansions
+*/
+func (s *Synonyms) SetMaxExpansions(n int) {
+	if n <= 0 {
+		n = DefaultMaxSynonymExpansions
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxExpansions = n
+}
+
+/*
+- Add: Thêm 1 nhóm cụm từ đồng nghĩa, cụm nào trong query cũng thay được bằng các cụm còn lại
+- weight: Phần trăm điểm giữ lại (1-100), <= 0 = DefaultSynonymWeight
+*/
+func (s *Synonyms) Add(weight int, terms ...string) {
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if p := synonymWords(term); p != "" {
+			phrases = append(phrases, p)
+		}
+	}
+	weight = synonymWeight(weight)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, from := range phrases {
+		for _, to := range phrases {
+			s.addAlt(from, to, weight)
+		}
+	}
+}
+
+/*
+- AddAlias: from thay được bằng từng cụm trong to, nhưng không có chiều ngược lại
+- Dùng cho viết tắt/bí danh: "sg" → "sai gon" thì được, nhưng gõ "sai gon" không nên ra mọi file có chữ "sg"
+*/
+func (s *Synonyms) AddAlias(weight int, from string, to ...string) {
+	fromPhrase := synonymWords(from)
+	if fromPhrase == "" {
+		return
+	}
+	weight = synonymWeight(weight)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, term := range to {
+		s.addAlt(fromPhrase, synonymWords(term), weight)
+	}
+}
+
+// Len: Số cụm từ có ít nhất 1 cụm thay thế
+func (s *Synonyms) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.alts)
+}
+
+/*
+- Expand: Các query thay thế của query, không gồm query gốc
+- Mỗi query thay thế chỉ thay 1 đoạn, đoạn khớp dài nhất tại mỗi vị trí được ưu tiên ("tp hcm" chứ không phải "hcm")
+- Sắp xếp theo Weight giảm dần, tối đa SetMaxExpansions query
+*/
+func (s *Synonyms) Expand(query string) []Expansion {
+	words := strings.FieldsFunc(Normalize(query), isWordBoundary)
+	if len(words) == 0 {
+		return nil
+	}
+	literal := strings.Join(words, " ")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.alts) == 0 {
+		return nil
+	}
+	seen := make(map[string]int)
+	for i := 0; i < len(words); {
+		n := 0
+		var alts []synonym
+		for l := min(s.maxWords, len(words)-i); l > 0 && n == 0; l-- {
+			if found, ok := s.alts[strings.Join(words[i:i+l], " ")]; ok {
+				n, alts = l, found
+			}
+		}
+		if n == 0 {
+			i++
+			continue
+		}
+		for _, alt := range alts {
+			parts := append(append(append([]string{}, words[:i]...), alt.phrase), words[i+n:]...)
+			expanded := strings.Join(parts, " ")
+			if expanded != literal && alt.weight > seen[expanded] {
+				seen[expanded] = alt.weight
+			}
+		}
+		i += n
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+	expansions := make([]Expansion, 0, len(seen))
+	for q, w := range seen {
+		expansions = append(expansions, Expansion{Query: q, Weight: w})
+	}
+	sort.Slice(expansions, func(a, b int) bool {
+		if expansions[a].Weight != expansions[b].Weight {
+			return expansions[a].Weight > expansions[b].Weight
+		}
+		return expansions[a].Query < expansions[b].Query
+	})
+	if len(expansions) > s.maxExpansions {
+		expansions = expansions[:s.maxExpansions]
+	}
+	return expansions
+}
+
+// addAlt: from → to, thêm lại thì giữ trọng số cao hơn. Gọi khi đang giữ s.mu
+func (s *Synonyms) addAlt(from, to string, weight int) {
+	if from == to || to == "" {
+		return
+	}
+	alts := s.alts[from]
+	for i := range alts {
+		if alts[i].phrase == to {
+			alts[i].weight = max(alts[i].weight, weight)
+			return
+		}
+	}
+	s.alts[from] = append(alts, synonym{phrase: to, weight: weight})
+	s.maxWords = max(s.maxWords, strings.Count(from, " ")+1)
+}
+
+// =============================================================================
+// Load
+// =============================================================================
+
+/*
+- LoadSynonyms: Đọc từ điển, tự nhận ra định dạng: bắt đầu bằng mảng JSON (isJSONArray) thì là JSON, còn lại là text
+- Text: mỗi dòng 1 nhóm, '#' là comment, "[90]" ở đầu dòng là trọng số
+hợp đồng, contract, hđ
+[90] báo cáo, report
+sg => sài gòn, tp hcm
+- "=>" là bí danh 1 chiều (AddAlias), còn lại là nhóm (Add)
+- JSON: mảng synonymEntry
+[{"terms": ["hợp đồng", "contract"]}, {"from": "sg", "terms": ["sài gòn"], "weight": 90}]
+- Dữ liệu sai định dạng trả về lỗi bọc ErrSynonymFormat (dùng errors.Is)
+*/
+func LoadSynonyms(r io.Reader) (*Synonyms, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return NewSynonyms(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != ' ' && b[0] != '\t' && b[0] != '\n' && b[0] != '\r' {
+			if b[0] == '[' && isJSONArray(br) {
+				return parseSynonymJSON(br)
+			}
+			return parseSynonymText(br)
+		}
+		br.ReadByte()
+	}
+}
+
+/*
+- LoadSynonymsFile: Đọc từ điển từ file (text hoặc JSON, xem LoadSynonyms)
+- File chưa tồn tại thì trả lỗi bọc os.ErrNotExist
+*/
+func LoadSynonymsFile(path string) (*Synonyms, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadSynonyms(file)
+}
+
+/*
+- isJSONArray: '[' ở đầu có phải mở mảng JSON không
+- Dòng text cũng có thể bắt đầu bằng "[90]" (trọng số), nên nhìn ký tự kế tiếp (bỏ khoảng trắng): JSON thì là '{' hoặc ']'
+*/
+func isJSONArray(br *bufio.Reader) bool {
+	head, _ := br.Peek(64)
+	for _, c := range head[1:] {
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '{', ']':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// parseSynonymText: Định dạng text, xem LoadSynonyms
+func parseSynonymText(r io.Reader) (*Synonyms, error) {
+	syn := NewSynonyms()
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		weight := 0
+		if strings.HasPrefix(text, "[") {
+			end := strings.IndexByte(text, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: line %d: missing ']'", ErrSynonymFormat, line)
+			}
+			w, err := strconv.Atoi(strings.TrimSpace(text[1:end]))
+			if err != nil || w <= 0 || w > 100 {
+				return nil, fmt.Errorf("%w: line %d: weight %q", ErrSynonymFormat, line, text[1:end])
+			}
+			weight, text = w, text[end+1:]
+		}
+
+		if from, to, ok := strings.Cut(text, "=>"); ok {
+			if strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+				return nil, fmt.Errorf("%w: line %d: empty alias", ErrSynonymFormat, line)
+			}
+			syn.AddAlias(weight, from, strings.Split(to, ",")...)
+			continue
+		}
+		terms := strings.Split(text, ",")
+		if len(terms) < 2 {
+			return nil, fmt.Errorf("%w: line %d: need at least 2 terms", ErrSynonymFormat, line)
+		}
+		syn.Add(weight, terms...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return syn, nil
+}
+
+// parseSynonymJSON: Định dạng JSON, xem LoadSynonyms
+func parseSynonymJSON(r io.Reader) (*Synonyms, error) {
+	var entries []synonymEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSynonymFormat, err)
+	}
+	syn := NewSynonyms()
+	for i, e := range entries {
+		if e.Weight < 0 || e.Weight > 100 {
+			return nil, fmt.Errorf("%w: entry %d: weight %d", ErrSynonymFormat, i, e.Weight)
+		}
+		switch {
+		case e.From != "" && len(e.Terms) > 0:
+			syn.AddAlias(e.Weight, e.From, e.Terms...)
+		case e.From == "" && len(e.Terms) >= 2:
+			syn.Add(e.Weight, e.Terms...)
+		default:
+			return nil, fmt.Errorf("%w: entry %d: need at least 2 terms", ErrSynonymFormat, i)
+		}
+	}
+	return syn, nil
+}
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+// synonymWords: Cụm từ đã chuẩn hóa, các từ (tách theo dấu ngăn cách) nối bằng 1 dấu cách: "TP.HCM" → "tp hcm"
+func synonymWords(term string) string {
+	return strings.Join(strings.FieldsFunc(Normalize(term), isWordBoundary), " ")
+}
+
+// synonymWeight: Trọng số hợp lệ (1-100), <= 0 = DefaultSynonymWeight
+func synonymWeight(weight int) int {
+	if weight <= 0 {
+		return DefaultSynonymWeight
+	}
+	return min(weight, 100)
+}
@@ -0,0 +1,264 @@
+package fuzzyvn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSynonyms_Expand(t *testing.T) {
+	syn := NewSynonyms()
+	syn.Add(0, "Hợp đồng", "contract")
+	syn.Add(90, "báo cáo", "report")
+	syn.AddAlias(70, "hcm", "Sài Gòn")
+	syn.Add(0, "TP.HCM", "Hồ Chí Minh")
+
+	tests := []struct {
+		query string
+		want  []Expansion
+	}{
+		{"hop dong 2024", []Expansion{{"contract 2024", DefaultSynonymWeight}}},
+		{"Contract", []Expansion{{"hop dong", DefaultSynonymWeight}}},
+		{"report", []Expansion{{"bao cao", 90}}},
+		{"bao cao hop dong", []Expansion{{"report hop dong", 90}, {"bao cao contract", DefaultSynonymWeight}}},
+		{"hcm", []Expansion{{"sai gon", 70}}},
+		{"sai gon", nil},                                               // Bí danh chỉ 1 chiều
+		{"tp_hcm", []Expansion{{"ho chi minh", DefaultSynonymWeight}}}, // Cụm dài nhất thắng, không thay riêng "hcm"
+		{"ho chi minh", []Expansion{{"tp hcm", DefaultSynonymWeight}}},
+		{"main.go", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := syn.Expand(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Expand(%q) = %v, muốn %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Thêm lại thì giữ trọng số cao hơn
+	syn.Add(95, "contract", "hợp đồng")
+	if got := syn.Expand("contract"); len(got) != 1 || got[0].Weight != 95 {
+		t.Errorf("Sau khi thêm lại: Expand(contract) = %v", got)
+	}
+
+	// Nhiều cụm thay thế: chỉ lấy DefaultMaxSynonymExpansions cụm có trọng số cao nhất
+	syn.Add(0, "a", "b", "c", "d", "e", "f")
+	if got := syn.Expand("a"); len(got) != DefaultMaxSynonymExpansions {
+		t.Errorf("Expand(a) có %d query, muốn %d", len(got), DefaultMaxSynonymExpansions)
+	}
+	syn.AddAlias(90, "a", "z")
+	syn.SetMaxExpansions(2)
+	if got := syn.Expand("a"); len(got) != 2 || got[0] != (Expansion{"z", 90}) {
+		t.Errorf("SetMaxExpansions(2): Expand(a) = %v", got)
+	}
+	syn.SetMaxExpansions(0)
+	if got := syn.Expand("a"); len(got) != DefaultMaxSynonymExpansions {
+		t.Errorf("SetMaxExpansions(0): Expand(a) có %d query, muốn %d", len(got), DefaultMaxSynonymExpansions)
+	}
+}
+
+func TestLoadSynonyms(t *testing.T) {
+	text := `
+# Từ điển của phòng kế toán
+hợp đồng, contract, hđ
+[90] báo cáo, report   # trọng số 90
+hcm => Sài Gòn, TP.HCM
+`
+	jsonData := ` [
+		{"terms": ["hợp đồng", "contract", "hđ"]},
+		{"terms": ["báo cáo", "report"], "weight": 90},
+		{"from": "hcm", "terms": ["Sài Gòn", "TP.HCM"]}
+	]`
+	for name, data := range map[string]string{"text": text, "json": jsonData} {
+		syn, err := LoadSynonyms(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: LoadSynonyms: %v", name, err)
+		}
+		if got := syn.Expand("hd 2024"); !slices.Equal(got, []Expansion{{"contract 2024", 80}, {"hop dong 2024", 80}}) {
+			t.Errorf("%s: Expand(hd 2024) = %v", name, got)
+		}
+		if got := syn.Expand("report"); !slices.Equal(got, []Expansion{{"bao cao", 90}}) {
+			t.Errorf("%s: Expand(report) = %v", name, got)
+		}
+		if got := syn.Expand("hcm"); len(got) != 2 || syn.Expand("sai gon") != nil {
+			t.Errorf("%s: Expand(hcm) = %v, Expand(sai gon) = %v", name, got, syn.Expand("sai gon"))
+		}
+	}
+
+	if syn, err := LoadSynonyms(strings.NewReader("  \n")); err != nil || syn.Len() != 0 {
+		t.Errorf("File rỗng: %v, %v", syn, err)
+	}
+
+	for _, bad := range []string{
+		"hợp đồng",               // 1 cụm
+		"[abc] a, b",             // Trọng số không phải số
+		"[150] a, b",             // Trọng số > 100
+		"[90 a, b",               // Thiếu ']'
+		"=> a",                   // Bí danh không có from
+		`[{"terms": ["a"]}]`,     // JSON 1 cụm
+		`[{"terms": ["a", "b"]}`, // JSON hỏng
+		`[{"terms": ["a", "b"], "weight": -1}]`,
+	} {
+		if _, err := LoadSynonyms(strings.NewReader(bad)); !errors.Is(err, ErrSynonymFormat) {
+			t.Errorf("LoadSynonyms(%q) = %v, muốn ErrSynonymFormat", bad, err)
+		}
+	}
+}
+
+func TestLoadSynonymsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "synonyms.txt")
+	if _, err := LoadSynonymsFile(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("File chưa có: err = %v, muốn os.ErrNotExist", err)
+	}
+	if err := os.WriteFile(path, []byte("hop dong, contract\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	syn, err := LoadSynonymsFile(path)
+	if err != nil || syn.Len() != 2 {
+		t.Errorf("LoadSynonymsFile = %v, %v", syn, err)
+	}
+}
+
+func TestSearcher_Synonyms(t *testing.T) {
+	files := []string{
+		"/legal/contract_2024.pdf",
+		"/legal/Hợp_đồng_2024.docx",
+		"/misc/hop_thu_dong_goi.txt",
+	}
+	syn := NewSynonyms()
+	syn.Add(0, "hợp đồng", "contract")
+	cfg := DefaultConfig()
+	cfg.Synonyms = syn
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	// Khớp đúng chữ user gõ đứng đầu, file khớp qua từ đồng nghĩa đứng ngay sau
+	results := searcher.SearchResults("hop dong 2024")
+	if len(results) < 2 || results[0].Str != files[1] || results[1].Str != files[0] {
+		t.Fatalf("SearchResults(hop dong 2024) = %v", results)
+	}
+	if bd := results[0].Breakdown; bd.Query != "" || bd.Synonym != 0 {
+		t.Errorf("Khớp đúng chữ không được tính là từ đồng nghĩa: %+v", bd)
+	}
+	bd := results[1].Breakdown
+	if bd.Query != "contract 2024" || bd.Synonym >= 0 {
+		t.Errorf("%s: Breakdown = %+v, muốn Query = contract 2024 và Synonym < 0", results[1].Str, bd)
+	}
+	if undemoted := bd.Total() - bd.Synonym; bd.Synonym != -undemoted*(100-DefaultSynonymWeight)/100 {
+		t.Errorf("Synonym = %d, muốn -%d%% của %d", bd.Synonym, 100-DefaultSynonymWeight, undemoted)
+	}
+	if results[1].Score != bd.Total() {
+		t.Errorf("Score = %d, Total() = %d", results[1].Score, bd.Total())
+	}
+	// Highlight theo query đã mở rộng
+	results = searcher.SearchResults("hop dong")
+	if len(results) < 2 || results[1].Breakdown.Query != "contract" || len(results[1].Positions) == 0 {
+		t.Errorf("SearchResults(hop dong) = %v, muốn highlight chữ contract", results)
+	}
+
+	// Chiều ngược lại
+	if results := searcher.SearchResults("contract"); len(results) < 2 || results[0].Str != files[0] || results[1].Str != files[1] {
+		t.Errorf("SearchResults(contract) = %v", results)
+	}
+
+	// Trọng số 100: điểm giữ nguyên
+	syn.Add(100, "hợp đồng", "contract")
+	for _, r := range searcher.SearchResults("contract") {
+		if r.Breakdown.Synonym != 0 {
+			t.Errorf("Weight = 100 nhưng %s có Synonym = %d", r.Str, r.Breakdown.Synonym)
+		}
+	}
+
+	// Không có Synonyms thì không đổi gì
+	for _, r := range NewSearcher(files).SearchResults("hop dong 2024") {
+		if r.Breakdown.Query != "" || r.Breakdown.Synonym != 0 {
+			t.Errorf("Không có Synonyms nhưng %s có Breakdown %+v", r.Str, r.Breakdown)
+		}
+	}
+}
+
+func TestSearcher_SynonymsCacheFollowsLiteral(t *testing.T) {
+	files := []string{
+		"/legal/contract_2024.pdf",
+		"/legal/contract_draft.pdf",
+		"/legal/Hợp_đồng_2024.docx",
+	}
+	syn := NewSynonyms()
+	syn.Add(0, "hợp đồng", "contract")
+	cfg := DefaultConfig()
+	cfg.Synonyms = syn
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	// Lượt chọn và ghim dưới "contract" không áp cho lần search "hop dong"
+	for i := 0; i < 5; i++ {
+		searcher.RecordSelection("contract", files[1])
+	}
+	searcher.Cache.Pin("contract", files[0])
+	if r := searcher.SearchResults("contract"); len(r) == 0 || r[0].Str != files[0] || r[0].Breakdown.Pin != 1 {
+		t.Fatalf("SearchResults(contract) = %v, muốn %s được ghim", r, files[0])
+	}
+	results := searcher.SearchResults("hop dong")
+	if len(results) != len(files) || results[0].Str != files[2] {
+		t.Fatalf("SearchResults(hop dong) = %v, muốn %s đứng đầu", results, files[2])
+	}
+	for _, r := range results {
+		if r.Breakdown.Pin != 0 || r.Breakdown.CacheBoost != 0 {
+			t.Errorf("%s khớp qua từ đồng nghĩa nhưng có Breakdown %+v", r.Str, r.Breakdown)
+		}
+	}
+
+	// Ghim và lượt chọn dưới chính query gốc thì vẫn áp cho file khớp qua từ đồng nghĩa
+	searcher.RecordSelection("hop dong", files[1])
+	searcher.Cache.Pin("hop dong", files[0])
+	results = searcher.SearchResults("hop dong")
+	if len(results) != len(files) || results[0].Str != files[0] || results[0].Breakdown.Pin != 1 || results[0].Breakdown.Query != "contract" {
+		t.Fatalf("SearchResults(hop dong) sau khi ghim = %v", results)
+	}
+	for _, r := range results {
+		if r.Str == files[1] && r.Breakdown.CacheBoost <= 0 {
+			t.Errorf("%s được chọn dưới hop dong nhưng CacheBoost = %d", r.Str, r.Breakdown.CacheBoost)
+		}
+	}
+}
+
+func TestSearcher_SynonymsConcurrent(t *testing.T) {
+	syn := NewSynonyms()
+	cfg := DefaultConfig()
+	cfg.Synonyms = syn
+	searcher := NewSearcherWithConfig([]string{"/a/contract.pdf", "/a/hop_dong.pdf"}, cfg)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			syn.Add(0, "hợp đồng", fmt.Sprintf("contract%d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			searcher.Search("hop dong")
+		}
+	}()
+	wg.Wait()
+}
+
+func BenchmarkSearch_Synonyms(b *testing.B) {
+	files := generateMonorepoFiles(100000, 1)
+	syn := NewSynonyms()
+	syn.Add(0, "báo cáo", "report")
+	for _, s := range []*Synonyms{nil, syn} {
+		cfg := DefaultConfig()
+		cfg.Synonyms = s
+		searcher := NewSearcherWithConfig(files, cfg)
+		b.Run(fmt.Sprintf("bao cao/synonyms=%v", s != nil), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				searcher.Search("bao cao")
+			}
+		})
+	}
+}
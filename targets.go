@@ -0,0 +1,218 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+targets.go Structure:
+├── Types
+│   ├── fuzzyChar
+│   ├── fuzzyPattern
+│   ├── span
+│   └── targetArena
+└── Functions
+
+	├── scoreWith (private)
+	├── isASCII (private)
+	├── newFuzzyPattern (private)
+	├── newTargetArena (private)
+	├── clone (private)
+	├── add (private)
+	├── extend (private)
+	├── set (private)
+	├── removeAt (private)
+	├── score (private)
+	└── scoreRange (private)
+*/
+package fuzzyvn
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// =============================================================================
+// Types
+// =============================================================================
+
+// fuzzyChar: Kiểu ký tự mà fuzzy matcher chấm được, byte cho target thuần ASCII, rune cho phần còn lại
+type fuzzyChar interface {
+	byte | rune
+}
+
+// fuzzyPattern: Pattern đã decode sẵn cả 2 dạng, tạo 1 lần cho mỗi lần search
+type fuzzyPattern struct {
+	runes []rune
+	bytes []byte // Chỉ có khi ascii
+	ascii bool
+}
+
+// span: Vị trí target thứ i trong arena
+type span struct {
+	off  uint32 // Offset trong ascii hoặc wide
+	n    uint32 // Số ký tự
+	wide bool   // true = nằm trong wide ([]rune), false = nằm trong ascii ([]byte)
+}
+
+/*
+- targetArena: Normalized của mọi item đã decode sẵn, để vòng lặp fuzzy không phải đổi string sang []rune mỗi lần search
+- Trước đây mỗi lần FuzzyFind mượn buffer từ targetRunePool rồi decode lại từng target, dù Normalized không hề đổi giữa các lần search
+- Sau khi chuẩn hóa, đa số item (kể cả tiếng Việt đã bỏ dấu) là ASCII: lưu thẳng byte, 1 byte/ký tự, chấm bằng bản byte của matcher
+- Item còn ký tự ngoài ASCII (chữ Hán, emoji...) lưu []rune, 4 byte/ký tự
+- Tất cả nối liền trong 2 mảng lớn, mỗi item chỉ tốn thêm 1 span 12 byte thay vì 1 slice header riêng
+- Pattern có ký tự ngoài ASCII thì chắc chắn không khớp target ASCII, bỏ qua luôn không cần chấm
+- Copy-on-write giống searchIndex: clone copy spans, còn ascii/wide dùng chung nhưng cắt capacity nên append trên bản clone luôn cấp phát mảng mới, không ghi đè vùng snapshot cũ đang đọc
+- set/removeAt không xóa dữ liệu cũ, chỉ cộng vào garbage, lần clone sau thấy rác quá nửa thì dồn lại
+- Offset uint32: tối đa ~4 tỷ ký tự mỗi mảng
+*/
+type targetArena struct {
+	ascii   []byte // Target thuần ASCII, nối liền
+	wide    []rune // Target có ký tự ngoài ASCII, đã decode, nối liền
+	spans   []span // Target thứ i
+	garbage int    // Số ký tự trong ascii + wide không còn span nào trỏ tới
+}
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+/*
+- scoreWith: Giống fuzzyScore nhưng chọn thuật toán qua algo thay vì cfg.Algorithm
+- Dùng được cho cả []byte lẫn []rune (generic), bản byte không phải decode UTF-8
+*/
+func scoreWith[T fuzzyChar](cfg *Config, algo Algorithm, pattern, target []T, positions []int) (int, []int, bool) {
+	if algo == AlgorithmOptimal {
+		return fuzzyScoreOptimal(cfg, pattern, target, positions)
+	}
+	return fuzzyScoreGreedyPositions(cfg, pattern, target, positions)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// newFuzzyPattern: Decode pattern (đã chuẩn hóa) sang cả []rune lẫn []byte
+func newFuzzyPattern(pattern string) fuzzyPattern {
+	p := fuzzyPattern{runes: []rune(pattern), ascii: isASCII(pattern)}
+	if p.ascii {
+		p.bytes = []byte(pattern)
+	}
+	return p
+}
+
+func newTargetArena(capacity int) targetArena {
+	return targetArena{spans: make([]span, 0, capacity)}
+}
+
+/*
+- clone: Copy arena cho bản clone của searchIndex
+- Rác quá nửa thì dồn lại luôn (đằng nào append đầu tiên cũng phải copy cả mảng)
+*/
+func (a *targetArena) clone(extra int) targetArena {
+	if live := len(a.ascii) + len(a.wide) - a.garbage; a.garbage > live {
+		c := newTargetArena(len(a.spans) + extra)
+		ascii := make([]byte, 0, len(a.ascii))
+		wide := make([]rune, 0, len(a.wide))
+		for _, sp := range a.spans {
+			next := sp
+			if sp.wide {
+				next.off = uint32(len(wide))
+				wide = append(wide, a.wide[sp.off:sp.off+sp.n]...)
+			} else {
+				next.off = uint32(len(ascii))
+				ascii = append(ascii, a.ascii[sp.off:sp.off+sp.n]...)
+			}
+			c.spans = append(c.spans, next)
+		}
+		c.ascii, c.wide = ascii, wide
+		return c
+	}
+
+	c := newTargetArena(len(a.spans) + extra)
+	c.spans = append(c.spans, a.spans...)
+	c.ascii = a.ascii[:len(a.ascii):len(a.ascii)]
+	c.wide = a.wide[:len(a.wide):len(a.wide)]
+	c.garbage = a.garbage
+	return c
+}
+
+// add: Decode 1 target rồi nối vào cuối arena, trả về span của nó
+func (a *targetArena) add(s string) span {
+	if isASCII(s) {
+		sp := span{off: uint32(len(a.ascii)), n: uint32(len(s))}
+		a.ascii = append(a.ascii, s...)
+		return sp
+	}
+	sp := span{off: uint32(len(a.wide)), wide: true}
+	for _, r := range s {
+		a.wide = append(a.wide, r)
+	}
+	sp.n = uint32(len(a.wide)) - sp.off
+	return sp
+}
+
+/*
+- extend: Thêm target cho các normalized[len(spans):] chưa có trong arena
+- Đếm trước tổng số ký tự để cấp phát đúng 1 lần, thay vì để append tăng dần (tốn gấp mấy lần bộ nhớ lúc build)
+- Gọi 1 lần trong searchIndex.seal, không gọi trong appendDoc
+*/
+func (a *targetArena) extend(normalized []string) {
+	pending := normalized[len(a.spans):]
+	asciiLen, wideLen := 0, 0
+	for _, s := range pending {
+		if isASCII(s) {
+			asciiLen += len(s)
+		} else {
+			wideLen += utf8.RuneCountInString(s)
+		}
+	}
+	a.ascii = slices.Grow(a.ascii, asciiLen)
+	a.wide = slices.Grow(a.wide, wideLen)
+	a.spans = slices.Grow(a.spans, len(pending))
+	for _, s := range pending {
+		a.spans = append(a.spans, a.add(s))
+	}
+}
+
+// set: Ghi đè target ở vị trí idx, chỗ cũ thành rác
+func (a *targetArena) set(idx int, s string) {
+	a.garbage += int(a.spans[idx].n)
+	a.spans[idx] = a.add(s)
+}
+
+// removeAt: Swap-remove giống searchIndex.removeAt
+func (a *targetArena) removeAt(idx int) {
+	last := len(a.spans) - 1
+	a.garbage += int(a.spans[idx].n)
+	a.spans[idx] = a.spans[last]
+	a.spans = a.spans[:last]
+}
+
+/*
+- score: Chấm pattern với target thứ idx, không decode, không mượn pool
+- algo: Thường là cfg.Algorithm, riêng OptimalRerank truyền AlgorithmOptimal
+*/
+func (a *targetArena) score(cfg *Config, algo Algorithm, p *fuzzyPattern, idx int, positions []int) (int, []int, bool) {
+	return a.scoreRange(cfg, algo, p, idx, 0, a.spans[idx].n, positions)
+}
+
+/*
+- scoreRange: Như score nhưng chỉ chấm đoạn [from, to) (tính theo ký tự) của target thứ idx
+- Dùng cho Document.Fields: các Field của 1 item nằm liền nhau trong 1 span, mỗi Field là 1 đoạn
+- positions (nếu có) tính từ đầu đoạn, không phải đầu span
+*/
+func (a *targetArena) scoreRange(cfg *Config, algo Algorithm, p *fuzzyPattern, idx int, from, to uint32, positions []int) (int, []int, bool) {
+	sp := a.spans[idx]
+	if sp.wide {
+		return scoreWith(cfg, algo, p.runes, a.wide[sp.off+from:sp.off+to], positions)
+	}
+	if !p.ascii {
+		return 0, positions, false
+	}
+	return scoreWith(cfg, algo, p.bytes, a.ascii[sp.off+from:sp.off+to], positions)
+}
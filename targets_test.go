@@ -0,0 +1,116 @@
+package fuzzyvn
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// arenaString: Target thứ idx của arena dưới dạng string, để so với normalized
+func arenaString(a *targetArena, idx int) string {
+	sp := a.spans[idx]
+	if sp.wide {
+		return string(a.wide[sp.off : sp.off+sp.n])
+	}
+	return string(a.ascii[sp.off : sp.off+sp.n])
+}
+
+func TestTargetArena_MatchesDecoded(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	alphabet := []rune("abcdeg_/ .9中ñ")
+	random := func(n int) string {
+		runes := make([]rune, n)
+		for i := range runes {
+			// Phần lớn là ASCII như Normalized thật
+			if rng.Intn(10) == 0 {
+				runes[i] = alphabet[len(alphabet)-2+rng.Intn(2)]
+			} else {
+				runes[i] = alphabet[rng.Intn(len(alphabet)-2)]
+			}
+		}
+		return string(runes)
+	}
+
+	arena := newTargetArena(0)
+	var want []string
+	var snapshots []targetArena
+	var snapshotWant [][]string
+	for round := range 30 {
+		// Clone như searchIndex.clone, sửa trên bản clone, giữ lại bản cũ để kiểm tra không bị ghi đè
+		snapshots = append(snapshots, arena)
+		snapshotWant = append(snapshotWant, append([]string(nil), want...))
+		arena = arena.clone(10)
+		for range 20 {
+			switch op := rng.Intn(4); {
+			case op == 0 && len(want) > 0:
+				idx := rng.Intn(len(want))
+				arena.removeAt(idx)
+				want[idx] = want[len(want)-1]
+				want = want[:len(want)-1]
+			case op == 1 && len(want) > 0:
+				idx := rng.Intn(len(want))
+				want[idx] = random(rng.Intn(20))
+				arena.set(idx, want[idx])
+			default:
+				want = append(want, random(rng.Intn(20)))
+				arena.extend(want)
+			}
+		}
+
+		for _, algo := range []Algorithm{AlgorithmGreedy, AlgorithmOptimal} {
+			for range 20 {
+				pattern := random(1 + rng.Intn(3))
+				p := newFuzzyPattern(pattern)
+				for idx, target := range want {
+					gotScore, gotPos, gotOK := arena.score(&defaultConfig, algo, &p, idx, []int{})
+					wantScore, wantPos, wantOK := scoreWith(&defaultConfig, algo, []rune(pattern), []rune(target), []int{})
+					if gotScore != wantScore || gotOK != wantOK || (wantOK && !reflect.DeepEqual(gotPos, wantPos)) {
+						t.Fatalf("round %d, algo %d, score(%q, %q) = (%d, %v, %v), muốn (%d, %v, %v)",
+							round, algo, pattern, target, gotScore, gotPos, gotOK, wantScore, wantPos, wantOK)
+					}
+					// Đoạn con [from, to) như 1 Field trong searchIndex.fields
+					runes := []rune(target)
+					from := rng.Intn(len(runes) + 1)
+					to := from + rng.Intn(len(runes)-from+1)
+					gotScore, _, gotOK = arena.scoreRange(&defaultConfig, algo, &p, idx, uint32(from), uint32(to), nil)
+					wantScore, _, wantOK = scoreWith(&defaultConfig, algo, []rune(pattern), runes[from:to], nil)
+					if gotScore != wantScore || gotOK != wantOK {
+						t.Fatalf("round %d, algo %d, scoreRange(%q, %q[%d:%d]) = (%d, %v), muốn (%d, %v)",
+							round, algo, pattern, target, from, to, gotScore, gotOK, wantScore, wantOK)
+					}
+				}
+			}
+		}
+	}
+
+	for i := range snapshots {
+		for idx, s := range snapshotWant[i] {
+			if got := arenaString(&snapshots[i], idx); got != s {
+				t.Fatalf("Snapshot %d bị sửa: target %d = %q, muốn %q", i, idx, got, s)
+			}
+		}
+	}
+	if arena.garbage == 0 {
+		t.Errorf("Không có set/removeAt nào tạo rác, test không kiểm tra được compact")
+	}
+}
+
+func BenchmarkFuzzyFind_Arena(b *testing.B) {
+	const n = 100000
+	ix := buildIndex(generateMonorepoFiles(n, 1), &defaultConfig)
+	b.ReportMetric(float64(len(ix.targets.ascii)+4*len(ix.targets.wide)+12*len(ix.targets.spans))/n, "arena-B/item")
+	for _, q := range []string{"billing", "paymnet", "bao cao"} {
+		b.Run(fmt.Sprintf("%s/decode", q), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fuzzyFind(context.Background(), &defaultConfig, q, ix.normalized, nil, false)
+			}
+		})
+		b.Run(fmt.Sprintf("%s/arena", q), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fuzzyFind(context.Background(), &defaultConfig, q, ix.normalized, ix, false)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+telex.go Structure:
+├── Tables
+│   ├── toneMarks
+│   ├── telexTones
+│   └── vniTones
+└── Functions
+
+	├── DecodeTelex
+	├── DecodeVNI
+	├── decodeKeystrokes (private)
+	├── decodeSyllable (private)
+	├── isVowel (private)
+	├── hasVowel (private)
+	├── addCircumflex (private)
+	├── addHorn (private)
+	├── addBreve (private)
+	├── placeTone (private)
+	├── tonePosition (private)
+	└── keystrokeAlternates (private)
+*/
+package fuzzyvn
+
+import (
+	"slices"
+	"strings"
+)
+
+// =============================================================================
+// Tables
+// =============================================================================
+
+/*
+- Người dùng tắt bộ gõ (hoặc máy không có bộ gõ) thì query tới dạng phím thô: Telex "vieejt nam", "baos caos", VNI "vie65t"
+- Normalize chỉ bỏ dấu thật nên "vieejt" không bao giờ khớp "Việt"
+- Giải mã từng từ thành tiếng Việt có dấu, rồi Search chấm thêm cách hiểu này bên cạnh query gốc
+- Chỉ giải mã từ không phải âm tiết tiếng Việt sẵn, và kết quả giải mã phải là âm tiết hợp lệ (isSyllable), nên "bao", "test.go", "2024" giữ nguyên
+*/
+
+// toneMarks: Dấu thanh dạng combining, ghép với nguyên âm bằng NFC. 0 = không dấu
+var toneMarks = [...]rune{0, '\u0301', '\u0300', '\u0309', '\u0303', '\u0323'}
+
+// telexTones: Phím Telex → dấu thanh (index trong toneMarks), z = xóa dấu
+var telexTones = map[rune]int{'s': 1, 'f': 2, 'r': 3, 'x': 4, 'j': 5, 'z': 0}
+
+// vniTones: Phím VNI → dấu thanh, 0 = xóa dấu
+var vniTones = map[rune]int{'1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '0': 0}
+
+// =============================================================================
+// Functions
+// =============================================================================
+
+/*
+- DecodeTelex: Giải mã các từ gõ kiểu Telex khi tắt bộ gõ thành tiếng Việt có dấu
+- aa/ee/oo → â/ê/ô, aw/ow/uw → ă/ơ/ư (uow → ươ), dd → đ, s/f/r/x/j → sắc/huyền/hỏi/ngã/nặng, z → xóa dấu
+- Từ nào không giải mã ra âm tiết tiếng Việt thì giữ nguyên
+- Ví dụ: DecodeTelex("vieejt nam") = "việt nam", DecodeTelex("baos caos 2024") = "báo cáo 2024"
+*/
+func DecodeTelex(s string) string {
+	return decodeKeystrokes(s, false)
+}
+
+/*
+- DecodeVNI: Giống DecodeTelex nhưng theo kiểu gõ VNI (số sau chữ)
+- 1-5 → sắc/huyền/hỏi/ngã/nặng, 6 → â/ê/ô, 7 → ơ/ư (uo7 → ươ), 8 → ă, 9 → đ, 0 → xóa dấu
+- Ví dụ: DecodeVNI("vie65t nam") = "việt nam"
+*/
+func DecodeVNI(s string) string {
+	return decodeKeystrokes(s, true)
+}
+
+/*
+- decodeKeystrokes: Tách s thành các từ chữ cái ASCII (VNI thì tính cả số), giải mã từng từ
+- Ký tự khác (dấu cách, dấu chấm, chữ có dấu sẵn...) giữ nguyên
+*/
+func decodeKeystrokes(s string, vni bool) string {
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	isKey := func(c byte) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (vni && c >= '0' && c <= '9')
+	}
+	for i := 0; i < len(s); {
+		if !isKey(s[i]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && isKey(s[j]) {
+			j++
+		}
+		word := s[i:j]
+		// Dính liền với chữ có dấu (ví dụ "vi" và "t" của "việt" đã gõ bằng bộ gõ) thì không phải phím thô
+		if (i > 0 && s[i-1] >= 0x80) || (j < len(s) && s[j] >= 0x80) {
+			b.WriteString(word)
+		} else if lower := strings.ToLower(word); isSyllable(lower) {
+			b.WriteString(word)
+		} else if decoded, ok := decodeSyllable(lower, vni); ok {
+			b.WriteString(decoded)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+/*
+- decodeSyllable: Giải mã 1 từ (chữ thường) gõ Telex/VNI
+- ok = false nếu không có phím nào được dùng để bỏ dấu, hoặc kết quả không phải âm tiết tiếng Việt
+*/
+func decodeSyllable(word string, vni bool) (string, bool) {
+	out := make([]rune, 0, len(word))
+	tone := -1 // -1 = chưa gõ dấu thanh
+	changed := false
+	for _, r := range word {
+		last := len(out) - 1
+		if vni {
+			if t, isTone := vniTones[r]; isTone {
+				if !hasVowel(out) {
+					return "", false
+				}
+				tone, changed = t, true
+				continue
+			}
+			switch r {
+			case '6':
+				if !addCircumflex(out) {
+					return "", false
+				}
+			case '7':
+				if !addHorn(out) {
+					return "", false
+				}
+			case '8':
+				if !addBreve(out) {
+					return "", false
+				}
+			case '9':
+				i := slices.Index(out, 'd')
+				if i < 0 {
+					return "", false
+				}
+				out[i] = 'đ'
+			default:
+				out = append(out, r)
+				continue
+			}
+			changed = true
+			continue
+		}
+
+		if t, isTone := telexTones[r]; isTone && hasVowel(out) {
+			tone, changed = t, true
+			continue
+		}
+		switch {
+		case r == 'd' && last >= 0 && out[last] == 'd':
+			out[last] = 'đ'
+		case (r == 'a' || r == 'e' || r == 'o') && last >= 0 && out[last] == r:
+			addCircumflex(out[last:])
+		case r == 'w':
+			if !addHorn(out) {
+				// "w" đứng riêng (hoặc sau phụ âm) là ư
+				out = append(out, 'ư')
+			}
+		default:
+			out = append(out, r)
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return "", false
+	}
+	if tone > 0 {
+		out = placeTone(out, tone)
+	}
+	decoded := string(out)
+	if !isSyllable(Normalize(decoded)) {
+		return "", false
+	}
+	return decoded, true
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aăâeêioôơuưy", r)
+}
+
+func hasVowel(out []rune) bool {
+	for _, r := range out {
+		if isVowel(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// addCircumflex: a/e/o gần cuối nhất → â/ê/ô
+func addCircumflex(out []rune) bool {
+	for i := len(out) - 1; i >= 0; i-- {
+		switch out[i] {
+		case 'a':
+			out[i] = 'â'
+			return true
+		case 'e':
+			out[i] = 'ê'
+			return true
+		case 'o':
+			out[i] = 'ô'
+			return true
+		}
+	}
+	return false
+}
+
+// addHorn: "uo" → "ươ", không thì o/u gần cuối nhất → ơ/ư (Telex còn a → ă)
+func addHorn(out []rune) bool {
+	for i := 0; i+1 < len(out); i++ {
+		if out[i] == 'u' && (out[i+1] == 'o' || out[i+1] == 'ơ') && !(i == 1 && out[0] == 'q') {
+			out[i], out[i+1] = 'ư', 'ơ'
+			return true
+		}
+	}
+	for i := len(out) - 1; i >= 0; i-- {
+		switch out[i] {
+		case 'o':
+			out[i] = 'ơ'
+			return true
+		case 'u':
+			// "qu" là phụ âm đầu, u của nó không thành ư
+			if i == 1 && out[0] == 'q' {
+				continue
+			}
+			out[i] = 'ư'
+			return true
+		case 'a':
+			out[i] = 'ă'
+			return true
+		}
+	}
+	return false
+}
+
+// addBreve: a gần cuối nhất → ă (VNI 8)
+func addBreve(out []rune) bool {
+	for i := len(out) - 1; i >= 0; i-- {
+		if out[i] == 'a' {
+			out[i] = 'ă'
+			return true
+		}
+	}
+	return false
+}
+
+/*
+- placeTone: Đặt dấu thanh lên đúng nguyên âm theo chính tả (kiểu cũ: "hòa", "mùa", "thủy"), vị trí lấy từ tonePosition
+*/
+func placeTone(out []rune, tone int) []rune {
+	if pos := tonePosition(out); pos >= 0 {
+		if composed, ok := vowelTones[tonedVowel{out[pos], tone}]; ok {
+			out[pos] = composed
+		}
+	}
+	return out
+}
+
+/*
+- tonePosition: Vị trí nguyên âm nhận dấu thanh trong âm tiết out (chưa có dấu thanh), -1 nếu không có nguyên âm
+- Nguyên âm có mũ/móc/trăng (â ê ô ơ ư ă) được ưu tiên, "ươ" đặt trên ơ
+- Không có thì: vần có phụ âm cuối → nguyên âm cuối, 3 nguyên âm → nguyên âm giữa, 2 nguyên âm → nguyên âm đầu
+- u của "qu" và i của "gi" (khi theo sau là nguyên âm khác) thuộc phụ âm đầu, không nhận dấu
+*/
+func tonePosition(out []rune) int {
+	start := 0
+	if len(out) > 2 && (out[0] == 'q' && out[1] == 'u' || out[0] == 'g' && out[1] == 'i') && isVowel(out[2]) {
+		start = 2
+	}
+	for start < len(out) && !isVowel(out[start]) {
+		start++
+	}
+	end := start
+	for end < len(out) && isVowel(out[end]) {
+		end++
+	}
+	if start == end {
+		return -1
+	}
+
+	pos := -1
+	for i := start; i < end; i++ {
+		if strings.ContainsRune("ăâêôơư", out[i]) {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		switch n := end - start; {
+		case end < len(out):
+			pos = end - 1
+		case n >= 3:
+			pos = start + 1
+		default:
+			pos = start
+		}
+	}
+	return pos
+}
+
+/*
+- keystrokeAlternates: Các cách hiểu khác của query nếu được gõ Telex/VNI khi tắt bộ gõ (không gồm query gốc)
+- Rỗng nếu không có từ nào giải mã được
+*/
+func keystrokeAlternates(query string) []string {
+	var alts []string
+	for _, decoded := range [...]string{DecodeTelex(query), DecodeVNI(query)} {
+		if decoded != query && (len(alts) == 0 || alts[0] != decoded) {
+			alts = append(alts, decoded)
+		}
+	}
+	return alts
+}
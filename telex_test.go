@@ -0,0 +1,167 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDecodeTelex(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"vieejt nam", "việt nam"},
+		{"baos caos 2024", "báo cáo 2024"},
+		{"Baos Caos", "báo cáo"},
+		{"nguowif", "người"},
+		{"dduowngf", "đường"},
+		{"tieengs vieetj", "tiếng việt"},
+		{"quoocs", "quốc"},
+		{"gias", "giá"},
+		{"hoaf", "hòa"},
+		{"thuyr", "thủy"},
+		{"khuyeens", "khuyến"},
+		{"tuw", "tư"},
+		{"hopwj ddoongf", "hợp đồng"},
+		// Đã là âm tiết hoặc không giải mã ra âm tiết: giữ nguyên
+		{"bao cao", "bao cao"},
+		{"main.go", "main.go"},
+		{"class", "class"},
+		{"việt nam", "việt nam"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := DecodeTelex(tt.input); got != tt.want {
+			t.Errorf("DecodeTelex(%q) = %q, muốn %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeVNI(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"vie65t nam", "việt nam"},
+		{"ba1o ca1o", "báo cáo"},
+		{"nguo72i", "người"},
+		{"d9uo7ng2", "đường"},
+		{"thu3y", "thủy"},
+		{"a8n", "ăn"},
+		{"file2", "file2"},
+		{"v2 2024", "v2 2024"},
+	}
+	for _, tt := range tests {
+		if got := DecodeVNI(tt.input); got != tt.want {
+			t.Errorf("DecodeVNI(%q) = %q, muốn %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKeystrokeAlternates(t *testing.T) {
+	if alts := keystrokeAlternates("vieejt nam"); !slices.Equal(alts, []string{"việt nam"}) {
+		t.Errorf("keystrokeAlternates(vieejt nam) = %v", alts)
+	}
+	if alts := keystrokeAlternates("vie65t nam"); !slices.Equal(alts, []string{"việt nam"}) {
+		t.Errorf("keystrokeAlternates(vie65t nam) = %v", alts)
+	}
+	if alts := keystrokeAlternates("main.go"); len(alts) != 0 {
+		t.Errorf("keystrokeAlternates(main.go) = %v, muốn rỗng", alts)
+	}
+}
+
+func TestSearcher_DecodeKeystrokes(t *testing.T) {
+	files := []string{
+		"/docs/Báo_cáo_tài_chính_2024.xlsx",
+		"/docs/Hợp_đồng_lao_động.docx",
+		"/src/baos_client.go",
+		"/src/main.go",
+	}
+	cfg := DefaultConfig()
+	cfg.DecodeKeystrokes = true
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	for _, q := range []string{"baos caos", "ba1o ca1o"} {
+		results := searcher.SearchResults(q)
+		if len(results) == 0 || results[0].Str != files[0] {
+			t.Fatalf("SearchResults(%q) phải trả về báo cáo đầu tiên, got %v", q, results)
+		}
+		if results[0].Breakdown.Query != "báo cáo" {
+			t.Errorf("SearchResults(%q): Breakdown.Query = %q, muốn %q", q, results[0].Breakdown.Query, "báo cáo")
+		}
+		if len(results[0].Positions) == 0 {
+			t.Errorf("SearchResults(%q): kết quả từ bản giải mã cũng phải có vị trí highlight", q)
+		}
+	}
+
+	// Query gốc vẫn được chấm: "baos" khớp thẳng tên file baos_client.go, điểm cao hơn bản giải mã "báo"
+	results := searcher.SearchResults("baos")
+	var literal *MatchResult
+	for i := range results {
+		if results[i].Str == "/src/baos_client.go" {
+			literal = &results[i]
+		}
+	}
+	if literal == nil || literal.Breakdown.Query != "" {
+		t.Errorf("SearchResults(baos) phải có /src/baos_client.go từ query gốc, got %v", results)
+	}
+
+	if results := searcher.SearchResults("hopwj ddoongf"); len(results) == 0 || results[0].Str != files[1] {
+		t.Errorf("SearchResults(hopwj ddoongf) phải trả về hợp đồng đầu tiên, got %v", results)
+	}
+
+	// Mặc định tắt
+	for _, r := range NewSearcher(files).SearchResults("baos caos") {
+		if r.Breakdown.Query != "" {
+			t.Errorf("DecodeKeystrokes = false nhưng vẫn có kết quả từ bản giải mã: %v", r)
+		}
+	}
+}
+
+func TestSearcher_DecodeKeystrokes_CacheFollowsLiteral(t *testing.T) {
+	files := []string{
+		"/docs/Báo_cáo_tài_chính_2024.xlsx",
+		"/docs/Báo_cáo_tuần.docx",
+		"/src/main.go",
+	}
+	cfg := DefaultConfig()
+	cfg.DecodeKeystrokes = true
+	searcher := NewSearcherWithConfig(files, cfg)
+
+	// Ghim lưu dưới bản giải mã không áp cho query gõ phím thô
+	searcher.Cache.Pin("báo cáo", files[1])
+	// Từ chối lưu dưới query gốc áp cho cả item chỉ khớp qua bản giải mã
+	searcher.Cache.RecordRejection("baos caos", files[0])
+
+	byPath := make(map[string]ScoreBreakdown)
+	for _, r := range searcher.SearchResults("baos caos") {
+		byPath[r.Str] = r.Breakdown
+	}
+	if bd := byPath[files[1]]; bd.Pin != 0 || bd.Query != "báo cáo" {
+		t.Errorf("%s: Breakdown = %+v, muốn khớp qua bản giải mã và không bị ghim", files[1], bd)
+	}
+	if bd := byPath[files[0]]; bd.CacheBoost >= 0 || bd.Query != "báo cáo" {
+		t.Errorf("%s: Breakdown = %+v, muốn bị trừ điểm theo query gốc", files[0], bd)
+	}
+}
+
+func BenchmarkSearch_DecodeKeystrokes(b *testing.B) {
+	files := generateMonorepoFiles(100000, 1)
+	for _, decode := range []bool{false, true} {
+		cfg := DefaultConfig()
+		cfg.DecodeKeystrokes = decode
+		searcher := NewSearcherWithConfig(files, cfg)
+		for _, q := range []string{"bao cao", "baos caos", "payment"} {
+			b.Run(fmt.Sprintf("%s/decode=%v", q, decode), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					searcher.Search(q)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecodeTelex(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		keystrokeAlternates("tieengs vieetj bao caos main.go")
+	}
+}
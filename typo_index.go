@@ -0,0 +1,369 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+typo_index.go Structure:
+├── Types
+│   ├── typoTrie
+│   ├── typoIndex
+│   └── typoSearch
+├── typoIndex Methods
+│   ├── newTypoIndex
+│   ├── rebuild (private)
+│   ├── clone (private)
+│   ├── stale (private)
+│   ├── appendItem (private)
+│   ├── detach (private)
+│   ├── setItem (private)
+│   ├── removeAt (private)
+│   └── search (private)
+└── typoSearch Methods
+
+	├── row (private)
+	├── step (private)
+	├── emit (private)
+	└── visit (private)
+*/
+package fuzzyvn
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// typoTrieDepth: Số ký tự đầu tối đa của tên file được đưa vào trie, query dài hơn (queryLen + 1 > depth) quét toàn bộ như cũ
+const typoTrieDepth = 32
+
+// typoRebuildMin: Số item chưa có trong trie (thêm/sửa/xóa sau khi dựng) tối thiểu trước khi dựng lại
+const typoRebuildMin = 1024
+
+// =============================================================================
+// Types
+// =============================================================================
+
+/*
+- typoTrie: Trie theo ký tự (rune) trên typoTrieDepth ký tự đầu của mọi tên file (đã bỏ dấu ngăn cách), bất biến sau khi dựng
+- Node đánh số theo preorder, nên cây con của node v là đoạn liền [v, end[v]), con đầu là v+1, em kế tiếp của con c là end[c]
+- Item sắp theo tên file nên item thuộc cây con của v cũng là đoạn liền [first[v], first[end[v]]) trong typoIndex.slots
+- Item kết thúc đúng tại v (tên file là đường đi tới v) là [first[v], first[v+1])
+- 12 byte mỗi node, không có con trỏ hay map
+*/
+type typoTrie struct {
+	label []rune  // node → ký tự trên cạnh từ node cha, node 0 là gốc
+	end   []int32 // node → node đầu tiên sau cây con của nó (preorder)
+	first []int32 // node → slot đầu tiên của item kết thúc tại node đó hoặc sau nó (preorder), thêm 1 phần tử chốt cuối
+}
+
+/*
+- typoIndex: Index cho TypoRanker, tìm mọi tên file trong ngưỡng typo mà không phải tính BoundedDamerauLevenshtein cho từng file
+- Bật bằng Config.TypoIndex (mặc định bật)
+- TypoRanker so query (m ký tự) với m và m+1 ký tự đầu của tên file. Đó đều là đường đi trong trie tới độ sâu m và m+1
+- Duyệt trie kiểu Levenshtein automaton: mỗi node giữ 1 hàng DP của query với đường đi tới node, tên file chung prefix dùng chung hàng
+- Giá trị nhỏ nhất của hàng > ngưỡng thì nối thêm ký tự nào cũng không xuống được nữa, bỏ cả cây con
+- Tới độ sâu m thì không cần đi tiếp: mọi item trong cây con của mỗi node con có cùng khoảng cách, lấy nguyên đoạn slot
+- Khoảng cách tính theo ký tự, có đổi chỗ 2 ký tự liền nhau, giống hệt typoDistance, nên điểm giống hệt quét toàn bộ
+- Đổi chỗ cần hàng DP cách 2 bậc và ký tự của node cha, đều có sẵn trên đường đi (rows, path)
+- Trie dùng chung giữa các snapshot. Mỗi snapshot chỉ copy slots/ref/pending (8 byte mỗi item) khi clone
+- Item thêm/sửa sau khi dựng nằm trong pending và được quét tuần tự. Item xóa để lại slot -1. Khi số này vượt max(typoRebuildMin, N/4) thì dựng lại (seal)
+*/
+type typoIndex struct {
+	trie    *typoTrie
+	slots   []int32 // slot → index item, -1 = đã xóa hoặc đã sửa
+	ref     []int32 // index item → slot nếu >= 0, ^(vị trí trong pending) nếu < 0
+	pending []int32 // Item chưa có trong trie
+	dead    int     // Số slot = -1
+}
+
+// typoSearch: Trạng thái của 1 lần duyệt trie
+type typoSearch struct {
+	ix        *typoIndex
+	q         []rune
+	m         int
+	threshold int
+	rows      []int  // Hàng DP tại độ sâu k: rows[k*(m+1) : (k+1)*(m+1)]
+	path      []rune // Ký tự thứ k của đường đi đang duyệt
+	fn        func(idx, dist int)
+	done      <-chan struct{}
+	visited   int
+	stopped   bool
+}
+
+// =============================================================================
+// typoIndex Methods
+// =============================================================================
+
+// newTypoIndex: typoIndex rỗng, item được thêm qua appendItem rồi dựng trie ở lần seal đầu tiên
+func newTypoIndex(capacity int) *typoIndex {
+	return &typoIndex{
+		ref:     make([]int32, 0, capacity),
+		pending: make([]int32, 0, capacity),
+	}
+}
+
+/*
+- rebuild: Dựng lại trie từ toàn bộ tên file, xóa pending và slot chết
+- Trie theo tên file đã bỏ dấu ngăn cách (compactName), giống chuỗi mà typoDistance đem so
+- O(N log N) do phải sort tên file
+- Duyệt danh sách đã sort 2 lần: lần đầu đếm số node để cấp phát đúng 1 lần (trie lớn, append tăng dần tốn gấp mấy lần bộ nhớ)
+*/
+func (ti *typoIndex) rebuild(names []string) {
+	type entry struct {
+		name string
+		idx  int32
+	}
+	// Tên đã bỏ dấu ngăn cách nằm liền nhau trong 1 chuỗi, khỏi cấp phát cho từng tên
+	size := 0
+	for _, name := range names {
+		size += min(len(name), typoTrieDepth*utf8.UTFMax)
+	}
+	arena := make([]byte, 0, size)
+	ends := make([]int, len(names))
+	for i, name := range names {
+		n := 0
+		for _, r := range name {
+			if n == typoTrieDepth {
+				break
+			}
+			if !isWordBoundary(r) {
+				arena = utf8.AppendRune(arena, r)
+				n++
+			}
+		}
+		ends[i] = len(arena)
+	}
+	compact := string(arena)
+	sorted := make([]entry, len(names))
+	for i := range names {
+		start := 0
+		if i > 0 {
+			start = ends[i-1]
+		}
+		sorted[i] = entry{compact[start:ends[i]], int32(i)}
+	}
+	slices.SortFunc(sorted, func(a, b entry) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	// walk: Gọi fn cho từng tên file theo thứ tự đã sort, kèm typoTrieDepth ký tự đầu (đã cắt sẵn trong arena) và độ dài prefix chung với tên trước đó
+	walk := func(fn func(idx int32, cur []rune, lcp int)) {
+		var prev, cur []rune
+		for _, e := range sorted {
+			cur = cur[:0]
+			for _, r := range e.name {
+				cur = append(cur, r)
+			}
+			lcp := 0
+			for lcp < len(prev) && lcp < len(cur) && prev[lcp] == cur[lcp] {
+				lcp++
+			}
+			fn(e.idx, cur, lcp)
+			prev, cur = cur, prev
+		}
+	}
+
+	nodes := 1
+	walk(func(_ int32, cur []rune, lcp int) { nodes += len(cur) - lcp })
+
+	trie := &typoTrie{
+		label: make([]rune, 1, nodes),
+		end:   make([]int32, 1, nodes),
+		first: make([]int32, 1, nodes+1),
+	}
+	slots := make([]int32, 0, len(names))
+	ref := make([]int32, len(names))
+	stack := []int32{0} // stack[k] = node ở độ sâu k trên đường đi hiện tại
+	walk(func(idx int32, cur []rune, lcp int) {
+		// Đóng các node không còn nằm trên đường đi
+		for len(stack) > lcp+1 {
+			trie.end[stack[len(stack)-1]] = int32(len(trie.label))
+			stack = stack[:len(stack)-1]
+		}
+		for _, r := range cur[lcp:] {
+			stack = append(stack, int32(len(trie.label)))
+			trie.label = append(trie.label, r)
+			trie.end = append(trie.end, 0)
+			trie.first = append(trie.first, int32(len(slots)))
+		}
+		ref[idx] = int32(len(slots))
+		slots = append(slots, idx)
+	})
+	for _, v := range stack {
+		trie.end[v] = int32(len(trie.label))
+	}
+	trie.first = append(trie.first, int32(len(slots)))
+
+	ti.trie = trie
+	ti.slots = slots
+	ti.ref = ref
+	ti.pending = ti.pending[:0]
+	ti.dead = 0
+}
+
+// clone: Copy phần thay đổi theo snapshot, trie dùng chung
+func (ti *typoIndex) clone(extra int) *typoIndex {
+	return &typoIndex{
+		trie:    ti.trie,
+		slots:   slices.Clone(ti.slots),
+		ref:     slices.Grow(slices.Clone(ti.ref), extra),
+		pending: slices.Grow(slices.Clone(ti.pending), extra),
+		dead:    ti.dead,
+	}
+}
+
+// stale: Chưa dựng trie, hoặc quá nhiều item nằm ngoài trie (phải quét tuần tự)
+func (ti *typoIndex) stale() bool {
+	outside := len(ti.pending) + ti.dead
+	return (ti.trie == nil && len(ti.ref) > 0) || outside > max(typoRebuildMin, len(ti.ref)/4)
+}
+
+// appendItem: Item mới ở cuối, chưa có trong trie
+func (ti *typoIndex) appendItem() {
+	idx := int32(len(ti.ref))
+	ti.ref = append(ti.ref, ^int32(len(ti.pending)))
+	ti.pending = append(ti.pending, idx)
+}
+
+// detach: Gỡ item idx khỏi trie (đánh dấu slot chết) hoặc khỏi pending (swap-remove)
+func (ti *typoIndex) detach(idx int) {
+	if r := ti.ref[idx]; r >= 0 {
+		ti.slots[r] = -1
+		ti.dead++
+	} else {
+		p := ^r
+		last := ti.pending[len(ti.pending)-1]
+		ti.pending[p] = last
+		ti.ref[last] = ^p
+		ti.pending = ti.pending[:len(ti.pending)-1]
+	}
+}
+
+// setItem: Tên file của idx đã đổi, chuyển sang pending
+func (ti *typoIndex) setItem(idx int) {
+	ti.detach(idx)
+	ti.ref[idx] = ^int32(len(ti.pending))
+	ti.pending = append(ti.pending, int32(idx))
+}
+
+// removeAt: Swap-remove giống searchIndex.removeAt, cập nhật slot/pending đang trỏ tới item cuối
+func (ti *typoIndex) removeAt(idx int) {
+	ti.detach(idx)
+	last := len(ti.ref) - 1
+	if idx != last {
+		r := ti.ref[last]
+		ti.ref[idx] = r
+		if r >= 0 {
+			ti.slots[r] = int32(idx)
+		} else {
+			ti.pending[^r] = int32(idx)
+		}
+	}
+	ti.ref = ti.ref[:last]
+}
+
+/*
+- search: Gọi fn(idx, dist) cho mọi item trong trie có khoảng cách typo <= threshold, theo đúng định nghĩa của typoDistance
+- q đã chuẩn hóa, m = số ký tự của q, cần m + 1 <= typoTrieDepth
+- Không gồm item trong pending, caller tự quét
+- Trả về false nếu bị dừng giữa chừng vì done
+*/
+func (ti *typoIndex) search(done <-chan struct{}, q string, m, threshold int, fn func(idx, dist int)) bool {
+	s := &typoSearch{
+		ix:        ti,
+		q:         []rune(q),
+		m:         m,
+		threshold: threshold,
+		rows:      make([]int, (m+2)*(m+1)),
+		path:      make([]rune, m+1),
+		fn:        fn,
+		done:      done,
+	}
+	root := s.row(0)
+	for j := range root {
+		root[j] = j
+	}
+	s.visit(0, 0)
+	return !s.stopped
+}
+
+// =============================================================================
+// typoSearch Methods
+// =============================================================================
+
+func (s *typoSearch) row(k int) []int {
+	width := s.m + 1
+	return s.rows[k*width : (k+1)*width]
+}
+
+/*
+- step: Tính hàng ở độ sâu k+1 từ hàng ở độ sâu k khi nối thêm ký tự r, giống 1 vòng của editDistanceOf
+- Trả về giá trị nhỏ nhất của hàng mới
+*/
+func (s *typoSearch) step(k int, r rune) int {
+	s.path[k] = r
+	prev, next := s.row(k), s.row(k+1)
+	next[0] = prev[0] + 1
+	best := next[0]
+	for j := 1; j <= s.m; j++ {
+		v := min(prev[j], next[j-1]) + 1
+		if diag := prev[j-1]; s.q[j-1] == r {
+			v = min(v, diag)
+		} else {
+			v = min(v, diag+1)
+		}
+		// Đổi chỗ: ký tự của node cha và r là q[j-1], q[j-2] theo thứ tự ngược lại
+		if k > 0 && j > 1 && s.q[j-2] == r && s.q[j-1] == s.path[k-1] {
+			v = min(v, s.row(k - 1)[j-2]+1)
+		}
+		next[j] = v
+		best = min(best, v)
+	}
+	return best
+}
+
+// emit: Gọi fn cho các item trong đoạn slot [from, to), bỏ qua slot chết
+func (s *typoSearch) emit(from, to int32, dist int) {
+	for _, idx := range s.ix.slots[from:to] {
+		if idx >= 0 {
+			s.fn(int(idx), dist)
+		}
+	}
+}
+
+/*
+- visit: Duyệt node v ở độ sâu k (đã có hàng DP ở độ sâu k)
+- k < m: item kết thúc tại v có tên file ngắn hơn query, typoDistance bỏ qua. Đi tiếp vào con nếu hàng của con còn <= ngưỡng
+- k == m: đường đi là m ký tự đầu (d1). Mỗi con c cho m+1 ký tự đầu (d2), mọi item trong cây con của c có khoảng cách min(d1, d2)
+*/
+func (s *typoSearch) visit(v int32, k int) {
+	if s.visited%ctxCheckInterval == 0 && isDone(s.done) {
+		s.stopped = true
+	}
+	s.visited++
+	if s.stopped {
+		return
+	}
+	t := s.ix.trie
+
+	if k < s.m {
+		for c := v + 1; c < t.end[v]; c = t.end[c] {
+			if s.step(k, t.label[c]) <= s.threshold {
+				s.visit(c, k+1)
+			}
+		}
+		return
+	}
+
+	d := s.row(k)[s.m]
+	if d <= s.threshold {
+		s.emit(t.first[v], t.first[v+1], d)
+	}
+	for c := v + 1; c < t.end[v]; c = t.end[c] {
+		s.step(k, t.label[c])
+		if dist := min(d, s.row(k + 1)[s.m]); dist <= s.threshold {
+			s.emit(t.first[c], t.first[t.end[c]], dist)
+		}
+	}
+}
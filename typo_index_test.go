@@ -0,0 +1,164 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// typoScan: Kết quả của typoIndex.search + pending bằng cách tính typoDistance cho từng tên file
+func typoScan(names []string, q string, threshold int) map[int]int {
+	m := utf8.RuneCountInString(q)
+	out := make(map[int]int)
+	for i, name := range names {
+		if dist, ok := typoDistance(q, m, name, threshold); ok && dist <= threshold {
+			out[i] = dist
+		}
+	}
+	return out
+}
+
+func typoLookup(ti *typoIndex, names []string, q string, threshold int) map[int]int {
+	m := utf8.RuneCountInString(q)
+	out := make(map[int]int)
+	ti.search(nil, q, m, threshold, func(idx, dist int) {
+		if _, dup := out[idx]; dup {
+			panic(fmt.Sprintf("item %d được trả về 2 lần", idx))
+		}
+		out[idx] = dist
+	})
+	for _, i := range ti.pending {
+		if dist, ok := typoDistance(q, m, names[i], threshold); ok && dist <= threshold {
+			out[int(i)] = dist
+		}
+	}
+	return out
+}
+
+func TestTypoIndex_MatchesScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	// Ít chữ cái để nhiều tên file chung prefix (hay có đổi chỗ), có ký tự nhiều byte để kiểm tra khoảng cách theo ký tự
+	alphabet := []rune("abcde_.中ß")
+	random := func(maxLen int) string {
+		runes := make([]rune, rng.Intn(maxLen+1))
+		for i := range runes {
+			runes[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(runes)
+	}
+
+	ti := newTypoIndex(0)
+	var names []string
+	for range 2000 {
+		names = append(names, random(12))
+		ti.appendItem()
+	}
+	ti.rebuild(names)
+
+	check := func(round int) {
+		t.Helper()
+		for range 100 {
+			// TypoRanker luôn đưa query đã bỏ dấu ngăn cách
+			q := compactName(random(6))
+			if utf8.RuneCountInString(q) < 2 {
+				continue
+			}
+			threshold := 1 + rng.Intn(3)
+			got, want := typoLookup(ti, names, q, threshold), typoScan(names, q, threshold)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("round %d, q = %q, ngưỡng %d: index trả về %d item, quét toàn bộ %d item", round, q, threshold, len(got), len(want))
+			}
+		}
+	}
+	check(0)
+
+	// Sửa sau khi dựng: item mới và item đổi tên nằm trong pending, item xóa để lại slot chết
+	for round := 1; round <= 5; round++ {
+		for range 300 {
+			switch op := rng.Intn(3); {
+			case op == 0 && len(names) > 0:
+				idx := rng.Intn(len(names))
+				ti.removeAt(idx)
+				names[idx] = names[len(names)-1]
+				names = names[:len(names)-1]
+			case op == 1 && len(names) > 0:
+				idx := rng.Intn(len(names))
+				names[idx] = random(12)
+				ti.setItem(idx)
+			default:
+				names = append(names, random(12))
+				ti.appendItem()
+			}
+		}
+		check(round)
+		if round == 3 {
+			ti.rebuild(names)
+			check(round)
+		}
+	}
+}
+
+func TestSearcher_TypoIndex_SameResults(t *testing.T) {
+	files := generateMonorepoFiles(3000, 6)
+	scanCfg := DefaultConfig()
+	scanCfg.TypoIndex = false
+	scan := NewSearcherWithConfig(files, scanCfg)
+	indexed := NewSearcher(files)
+
+	queries := []string{"auth", "biling", "paymnt", "wroker", "bao cao", "hpo dong", "quokak", "xylophne", "ke hoahc", "ab"}
+	compare := func(stage string) {
+		t.Helper()
+		checkIndexConsistent(t, indexed.index.Load())
+		for _, q := range queries {
+			if got, want := indexed.SearchResults(q), scan.SearchResults(q); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: SearchResults(%q) khác nhau khi dùng TypoIndex:\ngot  %v\nwant %v", stage, q, got, want)
+			}
+		}
+	}
+	compare("sau khi tạo")
+
+	// Ít thay đổi: nằm trong pending, chưa dựng lại
+	for _, s := range []*Searcher{scan, indexed} {
+		s.Remove(files[:50]...)
+		s.Add("/services/auth/quokka.go", "/docs/Kế_hoạch_2025.md")
+		s.Rename(files[100], "/tools/xylophone.py")
+	}
+	if ti := indexed.index.Load().typo; len(ti.pending) == 0 || ti.dead == 0 {
+		t.Fatalf("pending = %d, dead = %d: thay đổi nhỏ lẽ ra chưa dựng lại trie", len(ti.pending), ti.dead)
+	}
+	compare("sau khi sửa")
+
+	// Nhiều thay đổi: dựng lại trie
+	more := generateMonorepoFiles(2000, 7)
+	for _, s := range []*Searcher{scan, indexed} {
+		s.Add(more...)
+	}
+	if ti := indexed.index.Load().typo; len(ti.pending) != 0 {
+		t.Fatalf("pending = %d sau khi thêm 2000 file, lẽ ra phải dựng lại trie", len(ti.pending))
+	}
+	compare("sau khi dựng lại")
+}
+
+func BenchmarkTypoRanker(b *testing.B) {
+	for _, n := range []int{100000, 1000000} {
+		files := generateMonorepoFiles(n, 1)
+		for _, mode := range []struct {
+			name  string
+			index bool
+		}{{"scan", false}, {"index", true}} {
+			cfg := DefaultConfig()
+			cfg.TypoIndex = mode.index
+			searcher := NewSearcherWithConfig(files, cfg)
+			for _, q := range []string{"paymnet", "wroker queue", "bao cao"} {
+				b.Run(fmt.Sprintf("%dk/%s/%s", n/1000, q, mode.name), func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						st := searcher.newRankState(b.Context(), searcher.index.Load(), q, Normalize(q), "")
+						TypoRanker{}.Rank(st)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,293 @@
+/*
+----------------
+Author: verse91
+License: 0BSD
+----------------
+
+usercache.go Structure:
+├── Types
+│   └── CacheRegistry
+└── CacheRegistry Methods
+
+	├── NewCacheRegistry
+	├── SetMaxUsers
+	├── SetWeights
+	├── SetFactory
+	├── OnEvict
+	├── Get
+	├── Lookup
+	├── Remove
+	├── Len
+	├── RenamePath
+	├── touch (private)
+	├── evictedCache (private)
+	├── evictIfNeeded (private)
+	├── notifyEvicted (private)
+	└── blend (private)
+*/
+package fuzzyvn
+
+import (
+	"slices"
+	"sync"
+)
+
+// =============================================================================
+// Types
+// =============================================================================
+
+const (
+	DefaultMaxUsers     = 1000 // Số QueryCache của user được giữ trong RAM
+	DefaultUserWeight   = 100  // Boost từ cache riêng của user được tính 100%
+	DefaultGlobalWeight = 50   // Boost từ cache chung được tính 50%
+)
+
+/*
+- CacheRegistry: Mỗi user (hoặc tenant) 1 QueryCache riêng, dùng cho Searcher.SearchAs / RecordSelectionAs
+- Tránh việc 1 người click làm đổi thứ hạng kết quả của tất cả mọi người
+- Cache chung (Searcher.Cache) vẫn học từ mọi user, đóng vai trò lớp dự phòng
+- boost = boost của user * userWeight / 100 + boost chung (đã trừ phần của chính user) * globalWeight / 100
+- User mới chưa có lịch sử vẫn có kết quả tốt nhờ cache chung (tính đủ 100% như Search), user cũ thì lịch sử của chính họ nặng ký hơn
+- Giữ tối đa maxUsers cache trong RAM, user lâu không dùng bị bỏ ra trước (LRU, giống queryOrder của QueryCache)
+- Muốn giữ lịch sử qua LRU/khởi động lại thì dùng SetFactory (đọc từ file) + OnEvict (ghi ra file)
+- userID là chuỗi bất kỳ, tách theo tenant thì dùng key dạng "tenant/user" hoặc mỗi tenant 1 Searcher
+*/
+type CacheRegistry struct {
+	mu           sync.Mutex
+	caches       map[string]*QueryCache                 // userID → cache riêng
+	order        []string                               // Thứ tự dùng gần đây, user gần nhất ở cuối mảng
+	maxUsers     int                                    // Giới hạn số cache giữ trong RAM
+	userWeight   int                                    // % boost lấy từ cache của user
+	globalWeight int                                    // % boost lấy từ cache chung
+	newCache     func(userID string) *QueryCache        // Tạo cache cho user mới, nil = NewQueryCache
+	onEvict      func(userID string, cache *QueryCache) // Gọi khi 1 cache bị bỏ khỏi RAM, có thể nil
+}
+
+// =============================================================================
+// CacheRegistry Methods
+// =============================================================================
+
+// NewCacheRegistry: Registry rỗng với giới hạn và trọng số mặc định
+func NewCacheRegistry() *CacheRegistry {
+	return &CacheRegistry{
+		caches:       make(map[string]*QueryCache),
+		order:        make([]string, 0),
+		maxUsers:     DefaultMaxUsers,
+		userWeight:   DefaultUserWeight,
+		globalWeight: DefaultGlobalWeight,
+	}
+}
+
+// SetMaxUsers: Đặt số cache tối đa giữ trong RAM, giảm thì bỏ bớt user cũ nhất ngay
+func (r *CacheRegistry) SetMaxUsers(n int) {
+	r.mu.Lock()
+	evicted := r.evictIfNeeded(n)
+	r.mu.Unlock()
+	r.notifyEvicted(evicted)
+}
+
+/*
+- SetWeights: Trọng số (%) khi trộn boost của user với boost chung
+- global = 0: Chỉ dùng lịch sử riêng của user, click của user khác không đổi thứ hạng của họ, ghim chung cũng không áp dụng
+- user = 0: Bỏ qua lịch sử riêng, chỉ dùng cache chung (vẫn nhân globalWeight)
+*/
+func (r *CacheRegistry) SetWeights(user, global int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.userWeight = user
+	r.globalWeight = global
+}
+
+/*
+- SetFactory: Cách tạo cache cho user chưa có trong RAM, nil = NewQueryCache
+- Ví dụ: đọc lại lịch sử đã lưu bằng LoadQueryCacheFile
+*/
+func (r *CacheRegistry) SetFactory(newCache func(userID string) *QueryCache) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.newCache = newCache
+}
+
+/*
+- OnEvict: Hàm được gọi khi cache của 1 user bị bỏ khỏi RAM (do LRU hoặc Remove)
+- Gọi ngoài lock nên có thể làm việc chậm như ghi file
+*/
+func (r *CacheRegistry) OnEvict(fn func(userID string, cache *QueryCache)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onEvict = fn
+}
+
+/*
+- Get: Lấy cache của user, chưa có thì tạo mới (qua SetFactory)
+- Dùng để ghi (RecordSelection, Pin, RecordRejection...) hoặc cấu hình riêng cho từng user
+*/
+func (r *CacheRegistry) Get(userID string) *QueryCache {
+	if cache, ok := r.Lookup(userID); ok {
+		return cache
+	}
+
+	// Tạo cache ngoài lock vì factory có thể đọc file
+	r.mu.Lock()
+	factory := r.newCache
+	r.mu.Unlock()
+	var cache *QueryCache
+	if factory != nil {
+		cache = factory(userID)
+	}
+	if cache == nil {
+		cache = NewQueryCache()
+	}
+
+	r.mu.Lock()
+	// Goroutine khác có thể đã tạo xong trong lúc ta chạy factory, dùng luôn cái đó
+	if existing, ok := r.caches[userID]; ok {
+		r.touch(userID)
+		r.mu.Unlock()
+		return existing
+	}
+	r.caches[userID] = cache
+	r.order = append(r.order, userID)
+	evicted := r.evictIfNeeded(r.maxUsers)
+	r.mu.Unlock()
+
+	r.notifyEvicted(evicted)
+	return cache
+}
+
+/*
+- Lookup: Lấy cache của user nếu đang có trong RAM, không tạo mới
+- Search dùng hàm này nên user chỉ search mà chưa chọn gì thì không tốn bộ nhớ
+*/
+func (r *CacheRegistry) Lookup(userID string) (*QueryCache, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cache, ok := r.caches[userID]
+	if ok {
+		r.touch(userID)
+	}
+	return cache, ok
+}
+
+// Remove: Bỏ cache của user khỏi registry (OnEvict vẫn được gọi)
+func (r *CacheRegistry) Remove(userID string) {
+	r.mu.Lock()
+	cache, ok := r.caches[userID]
+	if ok {
+		delete(r.caches, userID)
+		r.order = removeString(r.order, userID)
+	}
+	fn := r.onEvict
+	r.mu.Unlock()
+
+	if ok && fn != nil {
+		fn(userID, cache)
+	}
+}
+
+// Len: Số cache đang giữ trong RAM
+func (r *CacheRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.caches)
+}
+
+/*
+- RenamePath: Chuyển lượt chọn, ghim và từ chối của oldPath sang newPath trong cache của mọi user đang ở trong RAM
+- Searcher.Rename/Update gọi hàm này cùng lúc với Cache.RenamePath
+- Cache đã bị bỏ khỏi RAM (đã ghi ra qua OnEvict) không được sửa, đọc lại qua SetFactory thì vẫn giữ đường dẫn cũ
+*/
+func (r *CacheRegistry) RenamePath(oldPath, newPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cache := range r.caches {
+		cache.RenamePath(oldPath, newPath)
+	}
+}
+
+// touch: Đẩy user xuống cuối order (gần đây nhất), giống QueryCache.moveToFront
+func (r *CacheRegistry) touch(userID string) {
+	if n := len(r.order); n > 0 && r.order[n-1] == userID {
+		return
+	}
+	r.order = append(removeString(r.order, userID), userID)
+}
+
+// evictedCache: 1 cache vừa bị bỏ khỏi RAM, chờ gọi OnEvict sau khi nhả lock
+type evictedCache struct {
+	userID string
+	cache  *QueryCache
+}
+
+// evictIfNeeded: Đặt giới hạn n rồi bỏ user cũ nhất cho tới khi không vượt n
+func (r *CacheRegistry) evictIfNeeded(n int) []evictedCache {
+	r.maxUsers = n
+	var evicted []evictedCache
+	for len(r.order) > max(r.maxUsers, 0) {
+		oldest := r.order[0]
+		r.order = r.order[1:]
+		evicted = append(evicted, evictedCache{oldest, r.caches[oldest]})
+		delete(r.caches, oldest)
+	}
+	return evicted
+}
+
+// notifyEvicted: Gọi OnEvict cho các cache vừa bị bỏ, ngoài lock
+func (r *CacheRegistry) notifyEvicted(evicted []evictedCache) {
+	if len(evicted) == 0 {
+		return
+	}
+	r.mu.Lock()
+	fn := r.onEvict
+	r.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	for _, e := range evicted {
+		fn(e.userID, e.cache)
+	}
+}
+
+/*
+  - blend: Trộn boost và ghim của user với cache chung theo trọng số
+  - File bị từ chối (boost âm) cũng được trộn theo cùng trọng số
+  - RecordSelectionAs ghi lượt chọn vào cả 2 cache, nên boost chung của 1 file đã gồm phần của chính user
+    Trừ phần đó ra (không âm) trước khi nhân globalWeight, để click của user chỉ được tính 1 lần (userWeight)
+    Đây là ước lượng: boost không cộng dồn tuyệt đối (frecency lấy trung bình, lấy max qua các query tương tự)
+  - Ghim của user đứng trước, sau đó tới ghim chung chưa có trong danh sách của user
+    Ghim không nhân được theo %, nên globalWeight = 0 thì bỏ ghim chung, và file user đã từ chối thì không ghim cho user đó
+  - User chưa có cache thì không có gì để trộn: dùng cache chung đủ 100% giống Search (trừ khi globalWeight = 0)
+*/
+func (r *CacheRegistry) blend(global *QueryCache, userID, query string) (map[string]int, []string) {
+	r.mu.Lock()
+	userWeight, globalWeight := r.userWeight, r.globalWeight
+	r.mu.Unlock()
+
+	boosts := make(map[string]int)
+	var own map[string]int
+	var pins []string
+	if user, ok := r.Lookup(userID); ok {
+		own = user.GetBoostScores(query)
+		for path, boost := range own {
+			boosts[path] += boost * userWeight / 100
+		}
+		pins = user.GetPins(query)
+	} else if globalWeight > 0 {
+		globalWeight = 100
+	}
+	if global == nil || globalWeight <= 0 {
+		return boosts, pins
+	}
+	for path, boost := range global.GetBoostScores(query) {
+		if mine := own[path]; boost > 0 && mine > 0 {
+			boost = max(boost-mine, 0)
+		}
+		boosts[path] += boost * globalWeight / 100
+	}
+	for _, path := range global.GetPins(query) {
+		if own[path] >= 0 && !slices.Contains(pins, path) {
+			pins = append(pins, path)
+		}
+	}
+	return boosts, pins
+}
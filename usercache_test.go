@@ -0,0 +1,218 @@
+package fuzzyvn
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSearcher_SearchAs_Blend(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_test.go", "/src/main_server.go"}
+	searcher := NewSearcher(files)
+
+	searcher.RecordSelectionAs("alice", "main", "/src/main_server.go")
+
+	boostOf := func(results []MatchResult, path string) int {
+		for _, r := range results {
+			if r.Str == path {
+				return r.Breakdown.CacheBoost
+			}
+		}
+		return -1
+	}
+	search := func(userID string) []MatchResult {
+		page, _ := searcher.SearchWithOptions("main", SearchOptions{UserID: userID})
+		return page.Results
+	}
+
+	// alice: click của chính mình chỉ tính 1 lần (100% cache riêng, phần trong cache chung bị trừ ra)
+	if got := boostOf(search("alice"), "/src/main_server.go"); got != 5000 {
+		t.Errorf("Boost của alice = %d, muốn %d", got, 5000)
+	}
+	// bob chưa có cache: dùng cache chung đủ 100% như khi không có user
+	if got := boostOf(search("bob"), "/src/main_server.go"); got != 5000 {
+		t.Errorf("Boost của bob = %d, muốn %d", got, 5000)
+	}
+	if got := boostOf(search(""), "/src/main_server.go"); got != 5000 {
+		t.Errorf("Boost không có user = %d, muốn %d", got, 5000)
+	}
+
+	// carol cũng chọn file đó: carol thấy phần của alice nhân 50%, alice thấy phần của carol nhân 50%
+	searcher.RecordSelectionAs("carol", "main", "/src/main_server.go")
+	for _, user := range []string{"alice", "carol"} {
+		if got := boostOf(search(user), "/src/main_server.go"); got != 5000+2500 {
+			t.Errorf("Boost của %s = %d, muốn %d", user, got, 7500)
+		}
+	}
+	if searcher.Users.Len() != 2 {
+		t.Errorf("Search không được tạo cache cho user mới, Len = %d", searcher.Users.Len())
+	}
+}
+
+func TestSearcher_SearchAs_OtherUsersClicks(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_test.go", "/src/main_server.go"}
+	searcher := NewSearcher(files)
+	searcher.RecordSelectionAs("bob", "config", "/src/config.go") // bob có cache riêng nhưng chưa chọn gì cho "main"
+	baseline := searcher.SearchAs("bob", "main")
+
+	for range 3 {
+		searcher.RecordSelectionAs("alice", "main", "/src/main_server.go")
+	}
+
+	// globalWeight = 0: click của alice không đổi thứ hạng của bob, kể cả user chưa có cache
+	searcher.Users.SetWeights(100, 0)
+	if got := searcher.SearchAs("bob", "main"); !slices.Equal(got, baseline) {
+		t.Errorf("bob bị click của alice đổi thứ hạng: %v, muốn %v", got, baseline)
+	}
+	if got := searcher.SearchAs("dave", "main"); !slices.Equal(got, baseline) {
+		t.Errorf("dave bị click của alice đổi thứ hạng: %v, muốn %v", got, baseline)
+	}
+	if got := searcher.SearchAs("alice", "main"); got[0] != "/src/main_server.go" {
+		t.Errorf("alice phải thấy file đã chọn lên đầu, got %v", got)
+	}
+
+	// globalWeight > 0: bob nhận click của alice theo đúng trọng số
+	searcher.Users.SetWeights(100, 10)
+	page, _ := searcher.SearchWithOptions("main", SearchOptions{UserID: "bob"})
+	for _, r := range page.Results {
+		if r.Str == "/src/main_server.go" && r.Breakdown.CacheBoost != 15000*10/100 {
+			t.Errorf("Boost của bob = %d, muốn %d", r.Breakdown.CacheBoost, 15000*10/100)
+		}
+	}
+}
+
+func TestSearcher_SearchAs_PinsAndRejections(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_test.go", "/docs/README.md"}
+	searcher := NewSearcher(files)
+
+	searcher.Cache.Pin("main", "/docs/README.md")
+	searcher.Users.Get("alice").Pin("main", "/src/main_test.go")
+	searcher.Users.Get("alice").RecordRejection("main", "/src/main.go")
+
+	got := searcher.SearchAs("alice", "main")
+	want := []string{"/src/main_test.go", "/docs/README.md", "/src/main.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("alice = %v, muốn %v", got, want)
+	}
+	if got := searcher.SearchAs("bob", "main"); got[0] != "/docs/README.md" || got[1] != "/src/main.go" {
+		t.Errorf("bob chỉ thấy ghim chung, got %v", got)
+	}
+
+	// alice từ chối file được ghim chung: ghim đó không áp cho alice
+	searcher.Users.Get("alice").RecordRejection("main", "/docs/README.md")
+	if got := searcher.SearchAs("alice", "main"); got[0] != "/src/main_test.go" || got[1] == "/docs/README.md" {
+		t.Errorf("alice đã từ chối README.md nhưng vẫn bị ghim, got %v", got)
+	}
+	// globalWeight = 0: bỏ cả ghim chung
+	searcher.Users.SetWeights(100, 0)
+	if got := searcher.SearchAs("bob", "main"); got[0] == "/docs/README.md" {
+		t.Errorf("globalWeight = 0 nhưng bob vẫn thấy ghim chung, got %v", got)
+	}
+}
+
+func TestSearcher_SearchAs_Rename(t *testing.T) {
+	files := []string{"/src/main.go", "/src/main_test.go", "/docs/main.md"}
+	searcher := NewSearcher(files)
+	searcher.Users.SetWeights(100, 0) // Chỉ lịch sử riêng của user
+	searcher.Users.Get("alice").Pin("main", "/docs/main.md")
+	for i := 0; i < 3; i++ {
+		searcher.RecordSelectionAs("bob", "main", "/src/main_test.go")
+	}
+
+	if !searcher.Rename("/docs/main.md", "/docs/guide/main.md") || !searcher.Rename("/src/main_test.go", "/test/main_test.go") {
+		t.Fatal("Rename thất bại")
+	}
+	if got := searcher.SearchAs("alice", "main"); len(got) == 0 || got[0] != "/docs/guide/main.md" {
+		t.Errorf("Ghim của alice phải theo file mới, got %v", got)
+	}
+	if got := searcher.SearchAs("bob", "main"); len(got) == 0 || got[0] != "/test/main_test.go" {
+		t.Errorf("Boost của bob phải theo file mới, got %v", got)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		cache := searcher.Users.Get(user)
+		if pins := cache.GetPins("main"); slices.Contains(pins, "/docs/main.md") {
+			t.Errorf("%s: ghim vẫn giữ đường dẫn cũ: %v", user, pins)
+		}
+		if _, ok := cache.GetBoostScores("main")["/src/main_test.go"]; ok {
+			t.Errorf("%s: boost vẫn giữ đường dẫn cũ", user)
+		}
+	}
+}
+
+func TestCacheRegistry_LRU(t *testing.T) {
+	reg := NewCacheRegistry()
+	reg.SetMaxUsers(2)
+
+	var created, evicted []string
+	reg.SetFactory(func(userID string) *QueryCache {
+		created = append(created, userID)
+		return nil // nil = NewQueryCache
+	})
+	reg.OnEvict(func(userID string, cache *QueryCache) {
+		evicted = append(evicted, userID)
+	})
+
+	a := reg.Get("a")
+	reg.Get("b")
+	if reg.Get("a") != a {
+		t.Error("Get lần 2 phải trả về đúng cache cũ")
+	}
+	reg.Get("c") // b lâu không dùng nhất
+
+	if !slices.Equal(created, []string{"a", "b", "c"}) {
+		t.Errorf("Factory được gọi cho %v", created)
+	}
+	if !slices.Equal(evicted, []string{"b"}) {
+		t.Errorf("Bị bỏ = %v, muốn [b]", evicted)
+	}
+	if _, ok := reg.Lookup("b"); ok {
+		t.Error("b phải bị bỏ khỏi RAM")
+	}
+
+	reg.Remove("a")
+	reg.SetMaxUsers(0)
+	if reg.Len() != 0 || !slices.Equal(evicted, []string{"b", "a", "c"}) {
+		t.Errorf("Len = %d, bị bỏ = %v", reg.Len(), evicted)
+	}
+}
+
+func TestItemSearcher_SearchAs(t *testing.T) {
+	products := []testProduct{
+		{SKU: "1", Name: "iPhone 15", Brand: "Apple"},
+		{SKU: "2", Name: "iPhone 15 Pro", Brand: "Apple"},
+	}
+	is := NewItemSearcher(products, productDoc)
+	is.Users().SetWeights(100, 0)
+
+	is.RecordSelectionAs("alice", "iphone", "2")
+	if got := is.SearchAs("alice", "iphone"); got[0].SKU != "2" {
+		t.Errorf("alice phải thấy SKU 2 lên đầu, got %v", got)
+	}
+	if got := is.SearchAs("bob", "iphone"); got[0].SKU != "1" {
+		t.Errorf("bob không bị ảnh hưởng, got %v", got)
+	}
+}
+
+func TestSearcher_SearchAs_Concurrent(t *testing.T) {
+	searcher := NewSearcher(generateTestFiles(300))
+	searcher.Users.SetMaxUsers(4)
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				user := fmt.Sprintf("user%d", (w+i)%10)
+				searcher.RecordSelectionAs(user, "main", fmt.Sprintf("/project/src/file_%d.go", i))
+				searcher.SearchAs(user, "main")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if searcher.Users.Len() > 4 {
+		t.Errorf("Số cache user = %d, không được vượt 4", searcher.Users.Len())
+	}
+}